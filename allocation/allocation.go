@@ -0,0 +1,211 @@
+// Package allocation picks the parking space a vehicle is given from the
+// available spaces of a parking lot.
+package allocation
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/space"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// Names of the strategies a parking lot can be configured with.
+const (
+	FirstAvailable  = "first_available"
+	NearestEntrance = "nearest_entrance"
+	LowestLevel     = "lowest_level"
+	SpreadZones     = "spread_zones"
+	Random          = "random"
+
+	DefaultStrategy = FirstAvailable
+)
+
+// Candidate is an available parking space.
+type Candidate struct {
+	ID         int
+	SlotNumber int
+	SizeClass  vehicle.SizeClass
+	Features   space.Features
+
+	// Distance to the entrance, nil when not configured.
+	Distance *int
+	// LevelOrdinal of the space's level, nil when it is not in a zone.
+	LevelOrdinal *int
+	// ZoneID is 0 when the space is not in a zone, ZoneOccupied and ZoneSpaces
+	// count the booked and all spaces of the zone.
+	ZoneID                   int
+	ZoneOccupied, ZoneSpaces int
+}
+
+// Strategy chooses one of the candidates, which are never empty and all equally
+// suitable for the vehicle.
+type Strategy interface {
+	Choose(candidates []Candidate) Candidate
+}
+
+// Request is what the vehicle needs.
+type Request struct {
+	VehicleType vehicle.Type
+	Features    space.Features
+	// AllowOverflow allows spaces with features that were not requested.
+	AllowOverflow bool
+}
+
+// Allocate picks a space for the request. Only candidates that fit the vehicle
+// and are eligible for the requested features are considered and of those only
+// the ones with the fewest features that were not requested and then the
+// smallest size class, so specialised and large spaces are kept free. The
+// strategy decides between the rest. It returns false when no candidate fits.
+func Allocate(s Strategy, candidates []Candidate, r Request) (Candidate, bool) {
+	var best []Candidate
+	for _, c := range candidates {
+		if !c.SizeClass.Fits(r.VehicleType) || !c.Features.Eligible(r.Features, r.AllowOverflow) {
+			continue
+		}
+
+		if len(best) > 0 {
+			switch compareTier(c, best[0], r.Features) {
+			case -1:
+				best = best[:0]
+			case 1:
+				continue
+			}
+		}
+		best = append(best, c)
+	}
+
+	if len(best) == 0 {
+		return Candidate{}, false
+	}
+
+	return s.Choose(best), true
+}
+
+func compareTier(a, b Candidate, required space.Features) int {
+	ae, be := a.Features.Extra(required), b.Features.Extra(required)
+	switch {
+	case ae < be:
+		return -1
+	case ae > be:
+		return 1
+	case a.SizeClass < b.SizeClass:
+		return -1
+	case a.SizeClass > b.SizeClass:
+		return 1
+	}
+
+	return 0
+}
+
+var strategies = map[string]Strategy{
+	FirstAvailable:  firstAvailable{},
+	NearestEntrance: nearestEntrance{},
+	LowestLevel:     lowestLevel{},
+	SpreadZones:     spreadZones{},
+	Random:          NewRandom(rand.NewSource(time.Now().UnixNano())),
+}
+
+// ByName returns the strategy a parking lot is configured with.
+func ByName(name string) (Strategy, error) {
+	s, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown allocation strategy %q", name)
+	}
+
+	return s, nil
+}
+
+// Valid reports whether name is a known strategy.
+func Valid(name string) bool {
+	_, err := ByName(name)
+	return err == nil
+}
+
+// first returns the candidate less considers the smallest, ties are broken by
+// the slot number so every strategy is deterministic.
+func first(candidates []Candidate, less func(a, b Candidate) bool) Candidate {
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if less(c, best) || (!less(best, c) && c.SlotNumber < best.SlotNumber) {
+			best = c
+		}
+	}
+
+	return best
+}
+
+// firstAvailable takes the space that was created first.
+type firstAvailable struct{}
+
+func (firstAvailable) Choose(candidates []Candidate) Candidate {
+	return first(candidates, func(a, b Candidate) bool {
+		return false
+	})
+}
+
+// nearestEntrance takes the space closest to the entrance, spaces without a
+// distance are taken last.
+type nearestEntrance struct{}
+
+func (nearestEntrance) Choose(candidates []Candidate) Candidate {
+	return first(candidates, func(a, b Candidate) bool {
+		return lessOptional(a.Distance, b.Distance)
+	})
+}
+
+// lowestLevel fills the lowest level first, spaces outside of levels are taken
+// last.
+type lowestLevel struct{}
+
+func (lowestLevel) Choose(candidates []Candidate) Candidate {
+	return first(candidates, func(a, b Candidate) bool {
+		return lessOptional(a.LevelOrdinal, b.LevelOrdinal)
+	})
+}
+
+// spreadZones takes a space in the zone with the lowest share of booked spaces
+// to spread wear evenly, spaces outside of zones are taken last.
+type spreadZones struct{}
+
+func (spreadZones) Choose(candidates []Candidate) Candidate {
+	return first(candidates, func(a, b Candidate) bool {
+		if a.ZoneID == 0 || b.ZoneID == 0 {
+			return a.ZoneID != 0 && b.ZoneID == 0
+		}
+
+		// a.ZoneOccupied/a.ZoneSpaces < b.ZoneOccupied/b.ZoneSpaces
+		return a.ZoneOccupied*b.ZoneSpaces < b.ZoneOccupied*a.ZoneSpaces
+	})
+}
+
+// lessOptional orders nil after every value.
+func lessOptional(a, b *int) bool {
+	if a == nil || b == nil {
+		return a != nil && b == nil
+	}
+
+	return *a < *b
+}
+
+type random struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewRandom returns a strategy that takes any candidate, src makes it
+// deterministic.
+func NewRandom(src rand.Source) Strategy {
+	return &random{
+		rnd: rand.New(src),
+	}
+}
+
+func (r *random) Choose(candidates []Candidate) Candidate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return candidates[r.rnd.Intn(len(candidates))]
+}
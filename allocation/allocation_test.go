@@ -0,0 +1,110 @@
+package allocation
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/arifmahmudrana/parking-lot/space"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestStrategies(t *testing.T) {
+	candidates := []Candidate{
+		{ID: 1, SlotNumber: 3, Distance: intPtr(40), LevelOrdinal: intPtr(1), ZoneID: 1, ZoneOccupied: 3, ZoneSpaces: 4},
+		{ID: 2, SlotNumber: 1, Distance: nil, LevelOrdinal: nil},
+		{ID: 3, SlotNumber: 2, Distance: intPtr(10), LevelOrdinal: intPtr(2), ZoneID: 2, ZoneOccupied: 1, ZoneSpaces: 4},
+		{ID: 4, SlotNumber: 4, Distance: intPtr(10), LevelOrdinal: intPtr(0), ZoneID: 3, ZoneOccupied: 2, ZoneSpaces: 4},
+	}
+
+	tests := []struct {
+		strategy string
+		want     int
+	}{
+		// the lowest slot number
+		{FirstAvailable, 2},
+		// 3 and 4 are as close, 3 has the lower slot number
+		{NearestEntrance, 3},
+		{LowestLevel, 4},
+		// zone 2 has the lowest share of booked spaces
+		{SpreadZones, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			s, err := ByName(tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Choose(candidates); got.ID != tt.want {
+				t.Errorf("Choose() = space %d, want %d", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrategiesWithoutOptionalValues(t *testing.T) {
+	candidates := []Candidate{
+		{ID: 1, SlotNumber: 2},
+		{ID: 2, SlotNumber: 1},
+	}
+
+	for _, name := range []string{NearestEntrance, LowestLevel, SpreadZones} {
+		s, err := ByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Choose(candidates); got.ID != 2 {
+			t.Errorf("%s: Choose() = space %d, want the lowest slot number 2", name, got.ID)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	candidates := []Candidate{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+
+	a, b := NewRandom(rand.NewSource(42)), NewRandom(rand.NewSource(42))
+	chosen := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		got, want := a.Choose(candidates), b.Choose(candidates)
+		if got.ID != want.ID {
+			t.Fatalf("pick %d: Choose() = space %d, the same seed chose %d", i, got.ID, want.ID)
+		}
+		chosen[got.ID] = true
+	}
+
+	if len(chosen) != len(candidates) {
+		t.Errorf("100 picks chose %d of %d spaces", len(chosen), len(candidates))
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	candidates := []Candidate{
+		{ID: 1, SlotNumber: 1, SizeClass: vehicle.SizeLarge},
+		{ID: 2, SlotNumber: 2, SizeClass: vehicle.SizeRegular, Features: space.EVCharger},
+		{ID: 3, SlotNumber: 3, SizeClass: vehicle.SizeRegular},
+		{ID: 4, SlotNumber: 4, SizeClass: vehicle.SizeCompact},
+	}
+
+	tests := []struct {
+		name string
+		r    Request
+		want int
+		ok   bool
+	}{
+		{"smallest fitting size", Request{VehicleType: vehicle.Car}, 3, true},
+		{"requested feature", Request{VehicleType: vehicle.Car, Features: space.EVCharger}, 2, true},
+		{"large vehicle", Request{VehicleType: vehicle.Bus}, 1, true},
+		{"nothing fits", Request{VehicleType: vehicle.Bus, Features: space.EVCharger}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Allocate(firstAvailable{}, candidates, tt.r)
+			if ok != tt.ok || got.ID != tt.want {
+				t.Errorf("Allocate() = space %d, %v, want %d, %v", got.ID, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// Action is what an API key may be allowed to do.
+type Action string
+
+const (
+	ActionPark   Action = "park"
+	ActionUnpark Action = "unpark"
+)
+
+func (a Action) Valid() bool {
+	return a == ActionPark || a == ActionUnpark
+}
+
+// apiKeyPrefix starts every key so leaked keys are easy to recognise
+const apiKeyPrefix = "pl_"
+
+// NewAPIKey returns a key of the form pl_<id>_<secret>. The id is stored to look
+// the key up and the secret only as the hash, the key can not be shown again.
+func NewAPIKey() (key, id, hash string, err error) {
+	b := make([]byte, 6+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+
+	id, secret := hex.EncodeToString(b[:6]), hex.EncodeToString(b[6:])
+
+	return apiKeyPrefix + id + "_" + secret, id, HashAPIKeySecret(secret), nil
+}
+
+// ParseAPIKey splits a key into its id and secret.
+func ParseAPIKey(key string) (id, secret string, err error) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return "", "", ErrInvalidAPIKey
+	}
+
+	id, secret, ok = strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", "", ErrInvalidAPIKey
+	}
+
+	return id, secret, nil
+}
+
+// HashAPIKeySecret hashes a secret for storage. Secrets are random so unlike
+// passwords they need no salt or slow hash.
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAPIKeySecret reports whether secret matches the stored hash.
+func CheckAPIKeySecret(hash, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashAPIKeySecret(secret))) == 1
+}
@@ -0,0 +1,54 @@
+// Package auth hashes passwords and issues the signed tokens API clients
+// authenticate with.
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted for an account and
+// MaxPasswordLength the longest in bytes, bcrypt refuses longer ones.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordTooLong  = errors.New("password is too long")
+)
+
+// HashPassword returns the bcrypt hash of the password.
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	if len(password) > MaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// DummyHash is a bcrypt hash at the default cost of a password nobody knows.
+// Checking the password of an unknown account against it takes as long as
+// checking a known one, so the time of a login does not tell which emails have
+// an account.
+const DummyHash = "$2a$10$vIcgawqf39F1kZxFg4YQburJKhmxypyXCZ78v8S1zq83SiVTTx6G."
+
+// CheckPassword reports whether password matches the hash. bcrypt ignores what
+// follows the first MaxPasswordLength bytes, longer passwords never match.
+func CheckPassword(hash, password string) bool {
+	if len(password) > MaxPasswordLength {
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{"empty", "", ErrPasswordTooShort},
+		{"too short", strings.Repeat("a", MinPasswordLength-1), ErrPasswordTooShort},
+		{"shortest", strings.Repeat("a", MinPasswordLength), nil},
+		{"longest", strings.Repeat("a", MaxPasswordLength), nil},
+		{"too long", strings.Repeat("a", MaxPasswordLength+1), ErrPasswordTooLong},
+		// the limits are in bytes, é is two
+		{"too long in bytes", strings.Repeat("é", MaxPasswordLength/2+1), ErrPasswordTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := HashPassword(tt.password)
+			if err != tt.wantErr {
+				t.Fatalf("HashPassword() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !CheckPassword(hash, tt.password) {
+				t.Error("CheckPassword() rejected the hashed password")
+			}
+			if CheckPassword(hash, tt.password+"b") {
+				t.Error("CheckPassword() accepted another password")
+			}
+		})
+	}
+}
+
+func TestCheckPasswordInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "-", "secret123"} {
+		if CheckPassword(hash, "secret123") {
+			t.Errorf("CheckPassword(%q) accepted a password", hash)
+		}
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(DummyHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("DummyHash cost = %d, want the cost of HashPassword %d", cost, bcrypt.DefaultCost)
+	}
+	for _, password := range []string{"", "secret123", DummyHash} {
+		if CheckPassword(DummyHash, password) {
+			t.Errorf("DummyHash matches %q", password)
+		}
+	}
+}
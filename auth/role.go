@@ -0,0 +1,29 @@
+package auth
+
+// Role of a user, every role may do what the roles below it may do. Every
+// user is a driver, the other roles are granted globally or for a parking lot.
+type Role string
+
+const (
+	Driver    Role = "driver"
+	Attendant Role = "attendant"
+	Manager   Role = "manager"
+	Admin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	Driver:    0,
+	Attendant: 1,
+	Manager:   2,
+	Admin:     3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast reports whether r may do what other may do.
+func (r Role) AtLeast(other Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[other]
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of a token, Subject is the user ID.
+type Claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// UserID is the user the token was issued to.
+func (c Claims) UserID() (int, error) {
+	return strconv.Atoi(c.Subject)
+}
+
+var (
+	encoding = base64.RawURLEncoding
+	// header is the encoded {"alg":"HS256","typ":"JWT"}, it is the only header
+	// tokens are issued and accepted with
+	header = encoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+)
+
+// NewToken returns a JWT signed with HMAC-SHA256 for the user that expires
+// after ttl.
+func NewToken(userID int, secret []byte, now time.Time, ttl time.Duration) (string, error) {
+	payload, err := json.Marshal(Claims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := header + "." + encoding.EncodeToString(payload)
+
+	return unsigned + "." + sign(unsigned, secret), nil
+}
+
+// ParseToken verifies the signature and expiry of a token issued by NewToken
+// and returns its claims.
+func ParseToken(token string, secret []byte, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(parts[0]+"."+parts[1], secret))) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	payload, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if now.Unix() >= c.ExpiresAt {
+		return Claims{}, ErrInvalidToken
+	}
+
+	return c, nil
+}
+
+func sign(unsigned string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+
+	return encoding.EncodeToString(mac.Sum(nil))
+}
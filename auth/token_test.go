@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("test secret")
+	testNow    = time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+)
+
+func TestParseToken(t *testing.T) {
+	token, err := NewToken(42, testSecret, testNow, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseToken(token, testSecret, testNow.Add(59*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Claims{Subject: "42", IssuedAt: testNow.Unix(), ExpiresAt: testNow.Add(time.Hour).Unix()}
+	if c != want {
+		t.Errorf("ParseToken() = %+v, want %+v", c, want)
+	}
+	if id, err := c.UserID(); err != nil || id != 42 {
+		t.Errorf("UserID() = %d, %v, want 42", id, err)
+	}
+}
+
+func TestParseTokenInvalid(t *testing.T) {
+	token, err := NewToken(42, testSecret, testNow, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+
+	// the payload of another user signed with another secret
+	payload, err := json.Marshal(Claims{Subject: "1", IssuedAt: testNow.Unix(), ExpiresAt: testNow.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := parts[0] + "." + encoding.EncodeToString(payload) + "." + parts[2]
+
+	none := encoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	unsigned := none + "." + parts[1] + "."
+
+	tests := []struct {
+		name   string
+		token  string
+		secret []byte
+		now    time.Time
+	}{
+		{"expired", token, testSecret, testNow.Add(time.Hour)},
+		{"wrong secret", token, []byte("other secret"), testNow},
+		{"tampered payload", tampered, testSecret, testNow},
+		{"alg none", unsigned, testSecret, testNow},
+		{"other header", none + "." + parts[1] + "." + parts[2], testSecret, testNow},
+		{"missing signature", parts[0] + "." + parts[1], testSecret, testNow},
+		{"extra part", token + ".x", testSecret, testNow},
+		{"empty", "", testSecret, testNow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := ParseToken(tt.token, tt.secret, tt.now); err != ErrInvalidToken {
+				t.Errorf("ParseToken() = %+v, %v, want %v", c, err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestClaimsUserID(t *testing.T) {
+	if _, err := (Claims{Subject: "not a number"}).UserID(); err == nil {
+		t.Error("UserID() of a subject that is not a number did not fail")
+	}
+}
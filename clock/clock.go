@@ -0,0 +1,95 @@
+// Package clock handles windows of local time that recur on days of the week,
+// such as opening hours or tariff bands.
+package clock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ValidDay reports whether day is a weekday ("mon", "tue", ...).
+func ValidDay(day string) bool {
+	_, ok := weekdays[day]
+	return ok
+}
+
+// Parse returns the minutes since midnight of a "HH:MM" clock time.
+func Parse(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Window is a window of local time from Start to End in minutes since midnight.
+// A window with End before Start ends on the next day and one with End equal to
+// Start lasts the whole day. Days are the weekdays the window starts on.
+type Window struct {
+	Start, End int
+	Days       [7]bool
+}
+
+// NewWindow parses the "HH:MM" start and end of a window, no days means every
+// day.
+func NewWindow(days []string, start, end string) (Window, error) {
+	var (
+		w   Window
+		err error
+	)
+	if w.Start, err = Parse(start); err != nil {
+		return Window{}, fmt.Errorf("start: %v", err)
+	}
+	if w.End, err = Parse(end); err != nil {
+		return Window{}, fmt.Errorf("end: %v", err)
+	}
+
+	for d := range w.Days {
+		w.Days[d] = len(days) == 0
+	}
+	for _, day := range days {
+		d, ok := weekdays[day]
+		if !ok {
+			return Window{}, fmt.Errorf("unknown day %q", day)
+		}
+		w.Days[d] = true
+	}
+
+	return w, nil
+}
+
+// Contains reports whether the local time t is inside the window and returns
+// the local date the occurrence started on, so two occurrences can be told
+// apart.
+func (w Window) Contains(t time.Time) (bool, time.Time) {
+	minute := t.Hour()*60 + t.Minute()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	switch {
+	case w.Start < w.End:
+		if minute < w.Start || minute >= w.End {
+			return false, time.Time{}
+		}
+	case w.Start > w.End:
+		if minute < w.End {
+			// the occurrence started yesterday
+			day = day.AddDate(0, 0, -1)
+		} else if minute < w.Start {
+			return false, time.Time{}
+		}
+	}
+
+	return w.Days[day.Weekday()], day
+}
@@ -0,0 +1,120 @@
+package clock
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // the tests must not depend on the time zones of the host
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int
+		wantErr bool
+	}{
+		{"00:00", 0, false},
+		{"06:30", 390, false},
+		{" 22:00 ", 1320, false},
+		{"23:59", 1439, false},
+		{"24:00", 0, true},
+		{"6", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v, want %d, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNewWindow(t *testing.T) {
+	w, err := NewWindow([]string{"mon", "fri"}, "22:00", "06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Window{Start: 1320, End: 360}
+	want.Days[time.Monday], want.Days[time.Friday] = true, true
+	if w != want {
+		t.Errorf("NewWindow() = %+v, want %+v", w, want)
+	}
+
+	every, err := NewWindow(nil, "08:00", "18:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for d, ok := range every.Days {
+		if !ok {
+			t.Errorf("a window without days is not on %s", time.Weekday(d))
+		}
+	}
+
+	for _, days := range [][]string{{"monday"}, {"Mon"}} {
+		if _, err := NewWindow(days, "08:00", "18:00"); err == nil {
+			t.Errorf("NewWindow(%q) accepted an unknown day", days)
+		}
+	}
+	if _, err := NewWindow(nil, "8", "18:00"); err == nil {
+		t.Error("NewWindow() accepted an invalid start")
+	}
+	if _, err := NewWindow(nil, "08:00", "18"); err == nil {
+		t.Error("NewWindow() accepted an invalid end")
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(days ...string) []string { return days }
+	tests := []struct {
+		name       string
+		days       []string
+		start, end string
+		at         time.Time
+		want       bool
+		occurrence string
+	}{
+		// 2024-03-04 is a Monday
+		{"inside", nil, "08:00", "18:00", time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC), true, "2024-03-04"},
+		{"start is inside", nil, "08:00", "18:00", time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC), true, "2024-03-04"},
+		{"end is outside", nil, "08:00", "18:00", time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC), false, ""},
+		{"before", nil, "08:00", "18:00", time.Date(2024, 3, 4, 7, 59, 0, 0, time.UTC), false, ""},
+		{"other day", day("tue"), "08:00", "18:00", time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC), false, ""},
+		{"night before midnight", nil, "22:00", "06:00", time.Date(2024, 3, 4, 23, 0, 0, 0, time.UTC), true, "2024-03-04"},
+		{"night after midnight", nil, "22:00", "06:00", time.Date(2024, 3, 5, 5, 59, 0, 0, time.UTC), true, "2024-03-04"},
+		{"night end", nil, "22:00", "06:00", time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC), false, ""},
+		{"day", nil, "22:00", "06:00", time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC), false, ""},
+		// the night starting on Monday lasts into Tuesday
+		{"night of the day before", day("mon"), "22:00", "06:00", time.Date(2024, 3, 5, 3, 0, 0, 0, time.UTC), true, "2024-03-04"},
+		{"night of another day", day("mon"), "22:00", "06:00", time.Date(2024, 3, 4, 3, 0, 0, 0, time.UTC), false, ""},
+		{"whole day", day("sat", "sun"), "00:00", "00:00", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), true, "2024-03-09"},
+		{"whole day of another day", day("sat", "sun"), "00:00", "00:00", time.Date(2024, 3, 8, 23, 59, 0, 0, time.UTC), false, ""},
+		// local time decides, 23:30 UTC on Monday is 00:30 on Tuesday in Berlin
+		{"local time", day("tue"), "00:00", "01:00", time.Date(2024, 3, 4, 23, 30, 0, 0, time.UTC).In(berlin), true, "2024-03-05"},
+		// 01:30 UTC on 2024-03-31 is 03:30 in Berlin, after the clocks skipped 02:00-03:00
+		{"daylight saving starts", nil, "02:00", "03:00", time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC).In(berlin), false, ""},
+		{"after daylight saving starts", nil, "03:00", "04:00", time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC).In(berlin), true, "2024-03-31"},
+		// 00:30 and 01:30 UTC on 2024-10-27 are both 02:30 in Berlin
+		{"daylight saving ends", nil, "02:00", "03:00", time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC).In(berlin), true, "2024-10-27"},
+		{"repeated hour", nil, "02:00", "03:00", time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC).In(berlin), true, "2024-10-27"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewWindow(tt.days, tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, occurrence := w.Contains(tt.at)
+			if got != tt.want {
+				t.Fatalf("Contains(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+			if got && occurrence.Format("2006-01-02") != tt.occurrence {
+				t.Errorf("Contains(%s) occurrence = %s, want %s", tt.at, occurrence.Format("2006-01-02"), tt.occurrence)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/space"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// tokenTTL is how long a login token is valid
+	tokenTTL = 24 * time.Hour
+	// apiKeyTouchInterval limits how often the last use of an API key is written
+	apiKeyTouchInterval = time.Minute
+)
+
+type contextKey string
+
+const principalContextKey contextKey = "principal"
+
+// principal is the authenticated caller, a user or an API key.
+type principal struct {
+	user   db.User
+	grants []db.Grant
+	apiKey *db.APIKey
+}
+
+// can reports whether the caller may do what role may do in the parking lot,
+// parkingLotID 0 asks for a global grant. Every user is a driver, API keys have
+// no role.
+func (p principal) can(role auth.Role, parkingLotID int) bool {
+	if p.apiKey != nil {
+		return false
+	}
+	if role == auth.Driver {
+		return true
+	}
+
+	for _, g := range p.grants {
+		if g.Allows(role, parkingLotID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canActFor reports whether the caller may do the action in the parking lot for
+// the user: API keys within their scopes, users for themselves and attendants of
+// the parking lot for anyone.
+func (p principal) canActFor(action auth.Action, parkingLotID, userID int) bool {
+	if p.apiKey != nil {
+		return p.apiKey.Allows(action, parkingLotID)
+	}
+
+	return p.user.ID == userID || p.can(auth.Attendant, parkingLotID)
+}
+
+// LoadAuthSecret reads the key tokens are signed with from AUTH_SECRET. Without
+// a database a random key is used, tokens are lost on exit with the data anyway.
+func (app *application) LoadAuthSecret() {
+	secret := os.Getenv("AUTH_SECRET")
+	if secret != "" {
+		app.authSecret = []byte(secret)
+		return
+	}
+
+	if os.Getenv("DB_DRIVER") != "memory" {
+		app.errorLog.Fatal("AUTH_SECRET must be set")
+	}
+
+	app.authSecret = make([]byte, 32)
+	if _, err := rand.Read(app.authSecret); err != nil {
+		app.errorLog.Fatal(err)
+	}
+}
+
+// LoadBootstrapAdmin reads the email of the user made admin when signing up
+// from BOOTSTRAP_ADMIN_EMAIL, it should be unset once the admin exists.
+func (app *application) LoadBootstrapAdmin() {
+	app.bootstrapAdmin = strings.ToLower(strings.TrimSpace(os.Getenv("BOOTSTRAP_ADMIN_EMAIL")))
+	if app.bootstrapAdmin != "" {
+		app.infoLog.Printf("%s is made admin when signing up", app.bootstrapAdmin)
+	}
+}
+
+// authenticate adds the user of the bearer token to the request context,
+// requests without a token continue anonymously and requests with an invalid
+// one are rejected.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			app.unauthorized(w, r)
+			return
+		}
+
+		claims, err := auth.ParseToken(token, app.authSecret, time.Now())
+		if err != nil {
+			app.unauthorized(w, r)
+			return
+		}
+
+		userID, err := claims.UserID()
+		if err != nil {
+			app.unauthorized(w, r)
+			return
+		}
+
+		u, err := app.dbRepo.GetUserByID(userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.unauthorized(w, r)
+				return
+			}
+
+			app.serverError(w, r, err)
+			return
+		}
+
+		grants, err := app.dbRepo.GetGrantsByUser(u.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		p := principal{
+			user:   u,
+			grants: grants,
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey, p)))
+	})
+}
+
+// authenticateAPIKey adds the API key of the X-API-Key header to the request
+// context, revoked and expired keys are rejected.
+func (app *application) authenticateAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// a request is made either by a user or by an API key
+		if _, ok := principalFromContext(r.Context()); ok {
+			app.unauthorized(w, r)
+			return
+		}
+
+		keyID, secret, err := auth.ParseAPIKey(key)
+		if err != nil {
+			app.unauthorized(w, r)
+			return
+		}
+
+		k, err := app.dbRepo.GetAPIKeyByKeyID(keyID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				app.unauthorized(w, r)
+				return
+			}
+
+			app.serverError(w, r, err)
+			return
+		}
+
+		now := time.Now()
+		if !auth.CheckAPIKeySecret(k.SecretHash, secret) || !k.Active(now) {
+			app.unauthorized(w, r)
+			return
+		}
+
+		if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= apiKeyTouchInterval {
+			if err := app.dbRepo.TouchAPIKey(k.ID, now); err != nil {
+				app.errorLog.Println(err)
+			}
+		}
+
+		p := principal{
+			apiKey: &k,
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey, p)))
+	})
+}
+
+// requireUser rejects anonymous requests and requests made with an API key.
+func (app *application) requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := principalFromContext(r.Context()); !ok || p.apiKey != nil {
+			app.unauthorized(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAction rejects anonymous requests and requests made with an API key
+// that may not do the action in the parking lot of the URL. Without a parking
+// lot in the URL the handler checks the scope of the key.
+func (app *application) requireAction(action auth.Action) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := principalFromContext(r.Context())
+			if !ok {
+				app.unauthorized(w, r)
+				return
+			}
+
+			if param := chi.URLParam(r, "parkinglotID"); p.apiKey != nil && param != "" {
+				parkingLotID, err := strconv.Atoi(param)
+				if err != nil {
+					app.invalidParam(w, r, "parkinglotID")
+					return
+				}
+
+				if !p.apiKey.Allows(action, parkingLotID) {
+					app.forbiddenScope(w, r, action, parkingLotID)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireRole rejects requests of callers without a grant of at least role for
+// the parking lot of the URL, or a global grant when the URL has none.
+func (app *application) requireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := principalFromContext(r.Context())
+			if !ok {
+				app.unauthorized(w, r)
+				return
+			}
+
+			var parkingLotID int
+			if param := chi.URLParam(r, "parkinglotID"); param != "" {
+				id, err := strconv.Atoi(param)
+				if err != nil {
+					app.invalidParam(w, r, "parkinglotID")
+					return
+				}
+				parkingLotID = id
+			}
+
+			if !p.can(role, parkingLotID) {
+				app.forbidden(w, r, role, parkingLotID)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forbidden tells the caller which role it lacks, parkingLotID is 0 for
+// global roles.
+func (app *application) forbidden(w http.ResponseWriter, r *http.Request, role auth.Role, parkingLotID int) {
+	details := struct {
+		RequiredRole auth.Role `json:"required_role"`
+		ParkingLotID int       `json:"parking_lot_id,omitempty"`
+	}{
+		RequiredRole: role,
+		ParkingLotID: parkingLotID,
+	}
+	app.errorJSON(w, r, http.StatusForbidden, "insufficient_role", "the "+string(role)+" role is required", details)
+}
+
+// forbiddenScope tells an API key client which action it lacks in the parking
+// lot.
+func (app *application) forbiddenScope(w http.ResponseWriter, r *http.Request, action auth.Action, parkingLotID int) {
+	details := struct {
+		RequiredAction auth.Action `json:"required_action"`
+		ParkingLotID   int         `json:"parking_lot_id"`
+	}{
+		RequiredAction: action,
+		ParkingLotID:   parkingLotID,
+	}
+	app.errorJSON(w, r, http.StatusForbidden, "insufficient_scope", "the api key may not "+string(action)+" in the parking lot", details)
+}
+
+// forbidAction writes the 403 response for a caller canActFor denied.
+func (app *application) forbidAction(w http.ResponseWriter, r *http.Request, p principal, action auth.Action, parkingLotID int) {
+	if p.apiKey != nil {
+		app.forbiddenScope(w, r, action, parkingLotID)
+		return
+	}
+
+	app.forbidden(w, r, auth.Attendant, parkingLotID)
+}
+
+// allowFeatures reports whether the caller may ask for a space with features
+// and writes the 403 response when not, only attendants of the parking lot may
+// ask for staff only spaces.
+func (app *application) allowFeatures(w http.ResponseWriter, r *http.Request, parkingLotID int, features space.Features) bool {
+	if !features.Has(space.StaffOnly) {
+		return true
+	}
+	if p, _ := principalFromContext(r.Context()); p.can(auth.Attendant, parkingLotID) {
+		return true
+	}
+
+	app.forbidden(w, r, auth.Attendant, parkingLotID)
+	return false
+}
+
+func (app *application) unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	app.errorJSON(w, r, http.StatusUnauthorized, "unauthorized", "a valid bearer token or api key is required", nil)
+}
+
+func principalFromContext(ctx context.Context) (principal, bool) {
+	p, ok := ctx.Value(principalContextKey).(principal)
+	return p, ok
+}
+
+func userFromContext(ctx context.Context) (db.User, bool) {
+	p, ok := principalFromContext(ctx)
+	return p.user, ok
+}
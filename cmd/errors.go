@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// apiError is the body of every error response, Code is stable for clients to
+// act on and Message is meant for humans.
+type apiError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// dbErrorStatuses maps the domain errors of the db package to HTTP statuses,
+// a domain error that is not listed is a conflict with the current state.
+var dbErrorStatuses = map[*db.Error]int{
+	db.ErrAlreadyUnparked: http.StatusConflict,
+	db.ErrDuplicate:       http.StatusConflict,
+	db.ErrInUse:           http.StatusConflict,
+	db.ErrBookingConflict: http.StatusConflict,
+	db.ErrBookingNotHeld:  http.StatusConflict,
+	db.ErrBookingTooEarly: http.StatusConflict,
+	db.ErrVehicleParked:   http.StatusConflict,
+	db.ErrAPIKeyInactive:  http.StatusConflict,
+	db.ErrLotNotFound:     http.StatusNotFound,
+	db.ErrLotFull:         http.StatusConflict,
+	db.ErrLotCapacity:     http.StatusConflict,
+	db.ErrLotActive:       http.StatusConflict,
+	db.ErrLotClosed:       http.StatusConflict,
+
+	db.ErrReservationActive:  http.StatusConflict,
+	db.ErrAlreadyPaid:        http.StatusConflict,
+	db.ErrNotPaid:            http.StatusConflict,
+	db.ErrPaymentInProgress:  http.StatusConflict,
+	db.ErrAdjustmentTooLarge: http.StatusConflict,
+
+	db.ErrVehicleNotRegistered: http.StatusNotFound,
+	db.ErrVehicleNotOwned:      http.StatusForbidden,
+}
+
+// errorJSON writes the error envelope with the ID of the request so it can be
+// found in the logs.
+func (app *application) errorJSON(w http.ResponseWriter, r *http.Request, status int, code, message string, details any) {
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Error apiError `json:"error"`
+	}{
+		Error: apiError{
+			Code:      code,
+			Message:   message,
+			Details:   details,
+			RequestID: middleware.GetReqID(r.Context()),
+		},
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(status)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// serverError logs err, the client only learns that the request failed.
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorLog.Printf("request %s: %v", middleware.GetReqID(r.Context()), err)
+	app.errorJSON(w, r, http.StatusInternalServerError, "internal_error", "internal server error", nil)
+}
+
+// invalidParam is the response to a malformed URL or query parameter.
+func (app *application) invalidParam(w http.ResponseWriter, r *http.Request, name string) {
+	app.errorJSON(w, r, http.StatusBadRequest, "invalid_parameter", "invalid "+name, map[string]string{
+		"parameter": name,
+	})
+}
+
+// invalidJSON is the response to a body that can not be decoded.
+func (app *application) invalidJSON(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorJSON(w, r, http.StatusBadRequest, "invalid_json", "the request body is not valid JSON: "+err.Error(), nil)
+}
+
+// invalidField is the response to a decoded body that fails validation.
+func (app *application) invalidField(w http.ResponseWriter, r *http.Request, field, message string) {
+	app.errorJSON(w, r, http.StatusBadRequest, "validation_failed", message, map[string]string{
+		"field": field,
+	})
+}
+
+// notFound is the response when resource does not exist.
+func (app *application) notFound(w http.ResponseWriter, r *http.Request, resource string) {
+	app.errorJSON(w, r, http.StatusNotFound, "not_found", resource+" not found", map[string]string{
+		"resource": resource,
+	})
+}
+
+// dbError writes the response for an error of the repository. resource names
+// what was not found when err is sql.ErrNoRows.
+func (app *application) dbError(w http.ResponseWriter, r *http.Request, err error, resource string) {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, db.ErrNilQueryRowContext) {
+		app.notFound(w, r, resource)
+		return
+	}
+
+	var dbErr *db.Error
+	if !errors.As(err, &dbErr) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	status, ok := dbErrorStatuses[dbErr]
+	if !ok {
+		status = http.StatusConflict
+	}
+	app.errorJSON(w, r, status, dbErr.Code, dbErr.Message, nil)
+}
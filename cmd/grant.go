@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+)
+
+const grantUsage = "usage: grant <email> <role> [parkingLotID]"
+
+// grant runs the grant subcommand which gives a user a role, it is how the
+// first admin is created, e.g. `go run cmd/*.go grant admin@example.com admin`
+func (app *application) grant(args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		app.errorLog.Fatal(grantUsage)
+	}
+
+	g := db.Grant{
+		Role: auth.Role(args[1]),
+	}
+	if len(args) == 3 {
+		parkingLotID, err := strconv.Atoi(args[2])
+		if err != nil {
+			app.errorLog.Fatal(grantUsage)
+		}
+		g.ParkingLotID = &parkingLotID
+	}
+	if !validGrant(g) {
+		app.errorLog.Fatal(grantUsage)
+	}
+
+	dbRepo, err := db.NewDB(os.Getenv("MYSQL_DSN"))
+	if err != nil {
+		app.errorLog.Fatal(err)
+	}
+	defer dbRepo.Close()
+
+	u, err := dbRepo.GetUserByEmail(strings.ToLower(args[0]))
+	if err != nil {
+		app.errorLog.Fatal(err)
+	}
+	g.UserID = u.ID
+
+	id, err := dbRepo.CreateGrant(g)
+	if err != nil {
+		app.errorLog.Fatal(err)
+	}
+
+	app.infoLog.Printf("created grant %d", id)
+}
@@ -3,14 +3,26 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/mail"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/arifmahmudrana/parking-lot/allocation"
+	"github.com/arifmahmudrana/parking-lot/auth"
 	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/hours"
+	"github.com/arifmahmudrana/parking-lot/payment"
+	"github.com/arifmahmudrana/parking-lot/pricing"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
 	"github.com/go-chi/chi/v5"
 )
 
+// maxPhoneLength is the size of parking_lots.contact_phone
+const maxPhoneLength = 32
+
 func (app *application) GetParkingLots(w http.ResponseWriter, r *http.Request) {
 	var (
 		err  error
@@ -20,28 +32,25 @@ func (app *application) GetParkingLots(w http.ResponseWriter, r *http.Request) {
 	if page != "" {
 		p, err = strconv.Atoi(page)
 		if err != nil {
-			app.errorLog.Println(err)
-			w.WriteHeader(http.StatusBadRequest)
+			app.invalidParam(w, r, "page")
 			return
 		}
 	}
 
 	if p < 1 {
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidParam(w, r, "page")
 		return
 	}
 
 	count, err := app.dbRepo.GetTotalCountParkingLots()
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		app.serverError(w, r, err)
 		return
 	}
 
 	parkingLots, err := app.dbRepo.GetParkingLots(p)
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -63,24 +72,23 @@ func (app *application) GetParkingLots(w http.ResponseWriter, r *http.Request) {
 
 func (app *application) CreateParkingLots(w http.ResponseWriter, r *http.Request) {
 	var (
-		p   db.ParkingLot
+		p = db.ParkingLot{
+			BookingGracePeriod: defaultBookingGracePeriod,
+		}
 		dec = json.NewDecoder(r.Body)
 	)
 	if err := dec.Decode(&p); err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidJSON(w, r, err)
 		return
 	}
 
-	p.Name = strings.TrimSpace(p.Name)
-	if p.Name == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if !app.validParkingLot(w, r, &p) {
 		return
 	}
 
 	id, err := app.dbRepo.CreateParkingLot(p)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -97,18 +105,215 @@ func (app *application) CreateParkingLots(w http.ResponseWriter, r *http.Request
 	}
 }
 
-func (app *application) GetParkingSpaces(w http.ResponseWriter, r *http.Request) {
+// validParkingLot normalises p and writes the response when it is not valid.
+func (app *application) validParkingLot(w http.ResponseWriter, r *http.Request, p *db.ParkingLot) bool {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		app.invalidField(w, r, "name", "name is required")
+		return false
+	}
+
+	p.TimeZone = strings.TrimSpace(p.TimeZone)
+	if p.TimeZone == "" {
+		p.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(p.TimeZone); err != nil {
+		app.invalidField(w, r, "time_zone", "unknown time zone")
+		return false
+	}
+
+	p.Address = strings.TrimSpace(p.Address)
+	if (p.Latitude == nil) != (p.Longitude == nil) {
+		app.invalidField(w, r, "latitude", "latitude and longitude must be set together")
+		return false
+	}
+	if p.Latitude != nil && (*p.Latitude < -90 || *p.Latitude > 90) {
+		app.invalidField(w, r, "latitude", "latitude must be between -90 and 90")
+		return false
+	}
+	if p.Longitude != nil && (*p.Longitude < -180 || *p.Longitude > 180) {
+		app.invalidField(w, r, "longitude", "longitude must be between -180 and 180")
+		return false
+	}
+	if p.Capacity != nil && *p.Capacity <= 0 {
+		app.invalidField(w, r, "capacity", "capacity must be positive")
+		return false
+	}
+
+	p.ContactName = strings.TrimSpace(p.ContactName)
+	p.ContactPhone = strings.TrimSpace(p.ContactPhone)
+	if len(p.ContactPhone) > maxPhoneLength || strings.Trim(p.ContactPhone, "+0123456789 ()-") != "" {
+		app.invalidField(w, r, "contact_phone", "invalid phone number")
+		return false
+	}
+	p.ContactEmail = strings.TrimSpace(p.ContactEmail)
+	if p.ContactEmail != "" {
+		if _, err := mail.ParseAddress(p.ContactEmail); err != nil {
+			app.invalidField(w, r, "contact_email", "invalid email address")
+			return false
+		}
+	}
+
+	if err := p.OpeningHours.Validate(); err != nil {
+		app.invalidField(w, r, "opening_hours", err.Error())
+		return false
+	}
+	if p.ClosingPolicy == "" {
+		p.ClosingPolicy = hours.Stay
+	}
+	if !p.ClosingPolicy.Valid() {
+		app.invalidField(w, r, "closing_policy", "closing_policy must be stay, flag or unpark")
+		return false
+	}
+
+	if p.AllocationStrategy == "" {
+		p.AllocationStrategy = allocation.DefaultStrategy
+	}
+	if !allocation.Valid(p.AllocationStrategy) {
+		app.invalidField(w, r, "allocation_strategy", "unknown allocation strategy")
+		return false
+	}
+
+	if p.BookingGracePeriod < 0 {
+		app.invalidField(w, r, "booking_grace_period", "booking_grace_period must not be negative")
+		return false
+	}
+	if p.NoShowFee < 0 {
+		app.invalidField(w, r, "no_show_fee", "no_show_fee must not be negative")
+		return false
+	}
+	if p.MaxStay != nil && *p.MaxStay <= 0 {
+		app.invalidField(w, r, "max_stay", "max_stay must be positive")
+		return false
+	}
+	if p.StaleAfter != nil && *p.StaleAfter <= 0 {
+		app.invalidField(w, r, "stale_after", "stale_after must be positive")
+		return false
+	}
+
+	return true
+}
+
+// readParkingLot reads the parking lot of the URL and writes the response when
+// it does not exist.
+func (app *application) readParkingLot(w http.ResponseWriter, r *http.Request) (db.ParkingLot, bool) {
 	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
 	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return db.ParkingLot{}, false
+	}
+
+	p, err := app.dbRepo.GetParkingLotByID(parkinglotID)
+	if err != nil {
+		app.dbError(w, r, err, "parking lot")
+		return db.ParkingLot{}, false
+	}
+
+	return p, true
+}
+
+// GetParkingLot returns a parking lot with its current and upcoming closures
+// and whether it is open now.
+func (app *application) GetParkingLot(w http.ResponseWriter, r *http.Request) {
+	p, ok := app.readParkingLot(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	closures, err := app.dbRepo.GetClosuresByParkingLot(p.ID, now)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	open, err := p.OpenAt(now, closures)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data struct {
+			db.ParkingLot
+			Closures []db.Closure `json:"closures"`
+			OpenNow  bool         `json:"open_now"`
+		} `json:"data"`
+	}{}
+	resultData.Data.ParkingLot = p
+	resultData.Data.Closures = closures
+	resultData.Data.OpenNow = open
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
 		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// UpdateParkingLot changes the attributes present in the body, null clears
+// the optional ones. opening_hours replaces all opening hours.
+func (app *application) UpdateParkingLot(w http.ResponseWriter, r *http.Request) {
+	p, ok := app.readParkingLot(w, r)
+	if !ok {
+		return
+	}
+
+	id := p.ID
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&p); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+	p.ID = id
+
+	if !app.validParkingLot(w, r, &p) {
+		return
+	}
+
+	if err := app.dbRepo.UpdateParkingLot(p); err != nil {
+		app.dbError(w, r, err, "parking lot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.ParkingLot `json:"data"`
+	}{
+		Data: p,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// DeleteParkingLot archives a parking lot without parked vehicles or held
+// bookings, its reservations are kept for reports.
+func (app *application) DeleteParkingLot(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	if err := app.dbRepo.ArchiveParkingLot(parkinglotID); err != nil {
+		app.dbError(w, r, err, "parking lot")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) GetParkingSpaces(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
 		return
 	}
 
 	parkingSpaces, err := app.dbRepo.GetParkingSpacesByParkingLot(parkinglotID)
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		app.serverError(w, r, err)
 		return
 	}
 
@@ -127,26 +332,44 @@ func (app *application) GetParkingSpaces(w http.ResponseWriter, r *http.Request)
 func (app *application) CreateParkingSpaces(w http.ResponseWriter, r *http.Request) {
 	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidParam(w, r, "parkinglotID")
 		return
 	}
 
 	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.serverError(w, r, err)
 		return
 	}
 
 	if !exists {
-		w.WriteHeader(http.StatusBadRequest)
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	// the body is optional, spaces are regular sized without features by default
+	var (
+		b = db.ParkingSpace{
+			SizeClass: vehicle.SizeRegular,
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil && err != io.EOF {
+		app.invalidJSON(w, r, err)
 		return
 	}
 
-	id, err := app.dbRepo.CreateParkingSpaceFromParkingLotID(parkinglotID)
+	if !app.validParkingSpace(w, r, &b) {
+		return
+	}
+
+	id, err := app.dbRepo.CreateParkingSpaceFromParkingLotID(parkinglotID, b)
+	if err == sql.ErrNoRows {
+		app.invalidField(w, r, "zone_id", "the zone is not in the parking lot")
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		app.dbError(w, r, err, "parking space")
 		return
 	}
 
@@ -166,41 +389,64 @@ func (app *application) CreateParkingSpaces(w http.ResponseWriter, r *http.Reque
 func (app *application) ParkParkingSpaces(w http.ResponseWriter, r *http.Request) {
 	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidParam(w, r, "parkinglotID")
 		return
 	}
 
-	parkingspaceID, err := app.dbRepo.GetNextParkingSpaceByParkingLot(parkinglotID)
-	if err != nil || parkinglotID <= 0 {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+	if parkinglotID <= 0 {
+		app.invalidParam(w, r, "parkinglotID")
 		return
 	}
 
 	var (
-		b struct {
-			UserID int `json:"user_id"`
-		}
+		b   db.ParkRequest
 		dec = json.NewDecoder(r.Body)
 	)
 	if err := dec.Decode(&b); err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	// attendants and API keys park vehicles of any user, drivers their own
+	p, _ := principalFromContext(r.Context())
+	b.UserID = p.user.ID
+	b.AnyVehicle = p.apiKey != nil || p.can(auth.Attendant, parkinglotID)
+
+	// without a vehicle type the type of the registered vehicle is used
+	if b.VehicleType != "" && !b.VehicleType.Valid() {
+		app.invalidField(w, r, "vehicle_type", "unknown vehicle type")
 		return
 	}
 
-	id, err := app.dbRepo.CreateParkingSpaceReservation(parkingspaceID, b.UserID)
+	if b.Plate != "" {
+		b.Plate, err = vehicle.NormalizePlate(b.Plate)
+		if err != nil {
+			app.invalidField(w, r, "plate", err.Error())
+			return
+		}
+	}
+
+	if !app.allowFeatures(w, r, parkinglotID, b.Features) {
+		return
+	}
+
+	id, location, err := app.dbRepo.ParkParkingSpaceByParkingLot(parkinglotID, b)
+	if err == db.ErrLotFull {
+		app.lotFull(w, r, parkinglotID, b)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		app.dbError(w, r, err, "parking lot")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	resultData := struct {
-		ID int64 `json:"id"`
+		ID       int64       `json:"id"`
+		Location db.Location `json:"location"`
 	}{
-		ID: id,
+		ID:       id,
+		Location: location,
 	}
 	encoder := json.NewEncoder(w)
 	w.WriteHeader(http.StatusCreated)
@@ -209,30 +455,78 @@ func (app *application) ParkParkingSpaces(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// UnParkParkingSpace ends a reservation. With a payment token the fee is paid
+// right away, a parking lot requiring payment keeps the exit closed until it is
+// paid, see PayParkingSpaceReservation.
 func (app *application) UnParkParkingSpace(w http.ResponseWriter, r *http.Request) {
 	parkingSpaceReservationsID, err := strconv.Atoi(chi.URLParam(r, "parkingSpaceReservationsID"))
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidParam(w, r, "parkingSpaceReservationsID")
 		return
 	}
 
-	fee, err := app.dbRepo.UnParkParkingSpaceByID(parkingSpaceReservationsID)
-	if err != nil {
-		app.errorLog.Println(err)
-		st := http.StatusInternalServerError
-		if err == db.ErrNilQueryRowContext || err == db.ErrAlreadyUnparked || err == sql.ErrNoRows {
-			st = http.StatusBadRequest
+	var (
+		b struct {
+			PaymentToken string `json:"payment_token"`
 		}
-		w.WriteHeader(st)
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil && err != io.EOF {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	// drivers unpark their own vehicles, attendants and API keys any vehicle of
+	// their lots
+	psr, err := app.dbRepo.GetParkingSpaceReservationByID(parkingSpaceReservationsID)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return
+	}
+	if p, _ := principalFromContext(r.Context()); !p.canActFor(auth.ActionUnpark, psr.ParkingLotID, psr.UserID) {
+		app.forbidAction(w, r, p, auth.ActionUnpark, psr.ParkingLotID)
+		return
+	}
+
+	pl, err := app.dbRepo.GetParkingLotByID(psr.ParkingLotID)
+	if err != nil {
+		app.dbError(w, r, err, "parking lot")
+		return
+	}
+
+	fee, pay, err := app.dbRepo.UnParkParkingSpaceByID(parkingSpaceReservationsID, pl.RequirePayment || b.PaymentToken != "")
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
 		return
 	}
 
+	// the vehicle is unparked already, a payment that could not be settled
+	// stays pending and is paid with PayParkingSpaceReservation
+	if pay != nil && b.PaymentToken != "" {
+		p, err := app.dbRepo.ClaimPayment(parkingSpaceReservationsID)
+		if err == nil {
+			p, err = app.settle(r.Context(), p, b.PaymentToken)
+		}
+		if err != nil {
+			app.errorLog.Printf("reservation %d: settling payment %d: %v", parkingSpaceReservationsID, pay.ID, err)
+		} else {
+			pay = &p
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	resultData := struct {
-		Fee int `json:"fee"`
+		Fee         int                `json:"fee"`
+		RatePlan    pricing.RatePlan   `json:"rate_plan"`
+		Items       []pricing.LineItem `json:"items"`
+		Payment     *db.Payment        `json:"payment,omitempty"`
+		ExitAllowed bool               `json:"exit_allowed"`
 	}{
-		Fee: fee,
+		Fee:         fee.Total,
+		RatePlan:    fee.RatePlan,
+		Items:       fee.Items,
+		Payment:     pay,
+		ExitAllowed: !pl.RequirePayment || fee.Total == 0 || pay.Status == payment.Captured,
 	}
 	encoder := json.NewEncoder(w)
 	w.WriteHeader(http.StatusCreated)
@@ -244,26 +538,23 @@ func (app *application) UnParkParkingSpace(w http.ResponseWriter, r *http.Reques
 func (app *application) ParkingSpaceMaintanance(w http.ResponseWriter, r *http.Request) {
 	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidParam(w, r, "parkinglotID")
 		return
 	}
 
 	parkingspaceID, err := strconv.Atoi(chi.URLParam(r, "parkingspaceID"))
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidParam(w, r, "parkingspaceID")
 		return
 	}
 
 	exists, err := app.dbRepo.DoesParkingSpaceExistForMaintananceByParkingLotIDAndID(parkingspaceID, parkinglotID)
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		app.serverError(w, r, err)
 		return
 	}
 	if !exists {
-		w.WriteHeader(http.StatusBadRequest)
+		app.notFound(w, r, "parking space")
 		return
 	}
 
@@ -274,17 +565,142 @@ func (app *application) ParkingSpaceMaintanance(w http.ResponseWriter, r *http.R
 		dec = json.NewDecoder(r.Body)
 	)
 	if err := dec.Decode(&b); err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+		app.invalidJSON(w, r, err)
 		return
 	}
 
 	err = app.dbRepo.SetParkingSpaceMaintanance(parkingspaceID, b.Maintanance)
 	if err != nil {
-		app.errorLog.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		app.serverError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+func (app *application) GetDailyReport(w http.ResponseWriter, r *http.Request) {
+	p, ok := app.readParkingLot(w, r)
+	if !ok {
+		return
+	}
+
+	// the date is the one of the parking lot, today is where it is
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	day := time.Now().In(loc)
+	if date := r.URL.Query().Get("date"); date != "" {
+		day, err = time.Parse("2006-01-02", date)
+		if err != nil {
+			app.invalidParam(w, r, "date")
+			return
+		}
+	}
+
+	report, err := app.dbRepo.GetDailyReportByParkingLot(p.ID, day)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.DailyReport `json:"data"`
+	}{
+		Data: report,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func (app *application) GetRatePlan(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	ratePlan, err := app.dbRepo.GetRatePlanByParkingLot(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data pricing.RatePlan `json:"data"`
+	}{
+		Data: ratePlan,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// UpdateRatePlan replaces the current rate plan of a parking lot, reservations
+// that are already unparked keep the plan they were charged with.
+func (app *application) UpdateRatePlan(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	var (
+		rp  pricing.RatePlan
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&rp); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	rp.Name = strings.TrimSpace(rp.Name)
+	if err := rp.Validate(); err != nil {
+		app.errorJSON(w, r, http.StatusBadRequest, "validation_failed", err.Error(), nil)
+		return
+	}
+
+	id, err := app.dbRepo.CreateRatePlan(parkinglotID, rp)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		ID int64 `json:"id"`
+	}{
+		ID: id,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
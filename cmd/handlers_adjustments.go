@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxNoteLength is the size of adjustments.note
+const maxNoteLength = 255
+
+// CreateAdjustment corrects the fee of an unparked reservation, a refund
+// returns money through the payment gateway and a waiver lowers what is left to
+// pay. Without an amount everything that is left is refunded or waived. A
+// refund the gateway refused is kept as failed. A refund that could not be
+// marked done is answered with 202 Accepted, it stays pending and is not in the
+// reports until reconcileRefunds completes it.
+func (app *application) CreateAdjustment(w http.ResponseWriter, r *http.Request) {
+	parkingSpaceReservationsID, err := strconv.Atoi(chi.URLParam(r, "parkingSpaceReservationsID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkingSpaceReservationsID")
+		return
+	}
+
+	var (
+		a   db.Adjustment
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&a); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+	if !a.Kind.Valid() {
+		app.invalidField(w, r, "kind", "kind must be refund or waiver")
+		return
+	}
+	if !a.Reason.Valid() {
+		app.invalidField(w, r, "reason", "unknown reason")
+		return
+	}
+	if len(a.Note) > maxNoteLength {
+		app.invalidField(w, r, "note", "note must be at most 255 characters")
+		return
+	}
+	if a.Amount < 0 {
+		app.invalidField(w, r, "amount", "amount must not be negative")
+		return
+	}
+
+	psr, p, ok := app.readAdjustableReservation(w, r, parkingSpaceReservationsID)
+	if !ok {
+		return
+	}
+	if psr.EndTime == nil {
+		app.dbError(w, r, db.ErrReservationActive, "parking space reservation")
+		return
+	}
+
+	payments, adjustments, ok := app.readSettlement(w, r, parkingSpaceReservationsID)
+	if !ok {
+		return
+	}
+
+	a.ParkingSpaceReservationID = parkingSpaceReservationsID
+	a.UserID = p.user.ID
+	var captured *db.Payment
+	switch a.Kind {
+	case db.AdjustmentWaiver:
+		if a.Amount == 0 {
+			a.Amount = psr.Fee - db.Waived(adjustments)
+		}
+		if a.Amount == 0 {
+			app.dbError(w, r, db.ErrAdjustmentTooLarge, "parking space reservation")
+			return
+		}
+	case db.AdjustmentRefund:
+		captured = capturedPayment(payments)
+		if captured == nil {
+			app.dbError(w, r, db.ErrNotPaid, "parking space reservation")
+			return
+		}
+
+		left := captured.Amount - captured.RefundedAmount
+		if a.Amount == 0 {
+			a.Amount = left
+		}
+		if a.Amount == 0 || a.Amount > left {
+			app.dbError(w, r, db.ErrAdjustmentTooLarge, "parking space reservation")
+			return
+		}
+	}
+
+	created, err := app.dbRepo.CreateAdjustment(a)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return
+	}
+
+	// a refund is recorded pending before the money is returned
+	status := http.StatusCreated
+	if created.Kind == db.AdjustmentRefund {
+		created, err = app.refund(r.Context(), created, captured.Reference)
+		if created.Status == db.AdjustmentFailed {
+			app.errorJSON(w, r, http.StatusBadGateway, "refund_failed", "the refund failed: "+created.FailureReason, created)
+			return
+		}
+		if err != nil {
+			// reconcileRefunds marks it done
+			app.errorLog.Printf("adjustment %d: %v", created.ID, err)
+			status = http.StatusAccepted
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Adjustment `json:"data"`
+	}{
+		Data: created,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(status)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// refund returns the money of a pending refund through the payment gateway and
+// marks it done, or failed when the gateway refused it. The adjustment ID is the
+// idempotency key, refunding it again moves no more money. A refund that could
+// not be marked either way is returned still pending with the error,
+// reconcileRefunds refunds it again later.
+func (app *application) refund(ctx context.Context, a db.Adjustment, reference string) (db.Adjustment, error) {
+	if err := app.payments.Refund(ctx, reference, a.Amount, fmt.Sprintf("adjustment-%d", a.ID)); err != nil {
+		failed := a
+		failed.Status, failed.FailureReason = db.AdjustmentFailed, err.Error()
+		if _, err := app.dbRepo.UpdateAdjustment(failed); err != nil {
+			return a, fmt.Errorf("failed at the gateway but still pending: %w", err)
+		}
+
+		return failed, nil
+	}
+
+	done := a
+	done.Status = db.AdjustmentDone
+	if _, err := app.dbRepo.UpdateAdjustment(done); err != nil {
+		return a, fmt.Errorf("refunded at the gateway but still pending: %w", err)
+	}
+
+	return done, nil
+}
+
+func (app *application) GetAdjustments(w http.ResponseWriter, r *http.Request) {
+	parkingSpaceReservationsID, err := strconv.Atoi(chi.URLParam(r, "parkingSpaceReservationsID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkingSpaceReservationsID")
+		return
+	}
+
+	if _, _, ok := app.readAdjustableReservation(w, r, parkingSpaceReservationsID); !ok {
+		return
+	}
+
+	adjustments, err := app.dbRepo.GetAdjustmentsByReservation(parkingSpaceReservationsID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data []db.Adjustment `json:"data"`
+	}{
+		Data: adjustments,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// readAdjustableReservation reads a reservation and writes the error response
+// when it is not found or the caller is no manager of its lot.
+func (app *application) readAdjustableReservation(w http.ResponseWriter, r *http.Request, id int) (db.ParkingSpaceReservation, principal, bool) {
+	psr, err := app.dbRepo.GetParkingSpaceReservationByID(id)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return db.ParkingSpaceReservation{}, principal{}, false
+	}
+
+	p, _ := principalFromContext(r.Context())
+	if !p.can(auth.Manager, psr.ParkingLotID) {
+		app.forbidden(w, r, auth.Manager, psr.ParkingLotID)
+		return db.ParkingSpaceReservation{}, principal{}, false
+	}
+
+	return psr, p, true
+}
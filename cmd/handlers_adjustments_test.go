@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/payment"
+)
+
+// refundGateway is the fake gateway failing refunds while failRefunds is set.
+type refundGateway struct {
+	*payment.Fake
+	failRefunds bool
+}
+
+func (g *refundGateway) Refund(ctx context.Context, reference string, amount int, idempotencyKey string) error {
+	if g.failRefunds {
+		return errors.New("refund refused")
+	}
+	return g.Fake.Refund(ctx, reference, amount, idempotencyKey)
+}
+
+// adjustmentRepository is a repository failing to update adjustments while
+// failUpdates is set.
+type adjustmentRepository struct {
+	db.Repository
+	failUpdates bool
+}
+
+func (r *adjustmentRepository) UpdateAdjustment(a db.Adjustment) (db.Adjustment, error) {
+	if r.failUpdates {
+		return db.Adjustment{}, errors.New("connection lost")
+	}
+	return r.Repository.UpdateAdjustment(a)
+}
+
+type adjustmentResponse struct {
+	Data  db.Adjustment `json:"data"`
+	Error struct {
+		Code    string        `json:"code"`
+		Details db.Adjustment `json:"details"`
+	} `json:"error"`
+}
+
+// adjustableReservation returns an unparked reservation of 20, paid when paid
+// is set, the token of its driver and the one of a manager of its parking lot.
+func (ta *testApp) adjustableReservation(t *testing.T, paid bool) (int, string, string) {
+	t.Helper()
+
+	psrID, token := ta.unparkedReservation(t)
+	if paid {
+		if code, resp := ta.pay(t, psrID, token, "tok_visa"); code != http.StatusCreated {
+			t.Fatalf("pay: status %d %s", code, resp.Error.Code)
+		}
+	}
+
+	psr, err := ta.dbRepo.GetParkingSpaceReservationByID(psrID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, manager := ta.user(t, fmt.Sprintf("manager%d@example.com", psrID),
+		db.Grant{Role: auth.Manager, ParkingLotID: &psr.ParkingLotID})
+
+	return psrID, token, manager
+}
+
+func (ta *testApp) adjust(t *testing.T, psrID int, token string, kind db.AdjustmentKind, amount int) (int, adjustmentResponse) {
+	t.Helper()
+
+	var resp adjustmentResponse
+	path := fmt.Sprintf("/api/parking-reservations/%d/adjustments", psrID)
+	body := map[string]any{"kind": kind, "reason": db.ReasonBillingError, "amount": amount}
+	code := ta.do(t, http.MethodPost, path, token, body, &resp)
+
+	return code, resp
+}
+
+// capturedPaymentOf returns the captured payment of a reservation.
+func (ta *testApp) capturedPaymentOf(t *testing.T, psrID int) db.Payment {
+	t.Helper()
+
+	payments, err := ta.dbRepo.GetPaymentsByReservation(psrID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := capturedPayment(payments); p != nil {
+		return *p
+	}
+	t.Fatalf("reservation %d has no captured payment: %+v", psrID, payments)
+	return db.Payment{}
+}
+
+// report returns the report of the parking lot of a reservation on the day it
+// ended, or on day if given.
+func (ta *testApp) report(t *testing.T, psrID int, day ...time.Time) db.DailyReport {
+	t.Helper()
+
+	psr, err := ta.dbRepo.GetParkingSpaceReservationByID(psrID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(day) == 0 {
+		day = append(day, *psr.EndTime)
+	}
+	report, err := ta.dbRepo.GetDailyReportByParkingLot(psr.ParkingLotID, day[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return report
+}
+
+func TestCreateAdjustmentRefund(t *testing.T) {
+	ta := newTestApp(t)
+	psrID, _, manager := ta.adjustableReservation(t, true)
+	reference := ta.capturedPaymentOf(t, psrID).Reference
+
+	code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 5)
+	if code != http.StatusCreated || resp.Data.Status != db.AdjustmentDone || resp.Data.Amount != 5 {
+		t.Fatalf("refund of 5 = %d %+v, want %d and done", code, resp.Data, http.StatusCreated)
+	}
+	if refunded := ta.gateway.Refunded(reference); refunded != 5 {
+		t.Errorf("refunded %d at the gateway, want 5", refunded)
+	}
+
+	// 15 are left to refund
+	if code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 16); code != http.StatusConflict || resp.Error.Code != "adjustment_too_large" {
+		t.Errorf("refund of 16 = %d %s, want %d adjustment_too_large", code, resp.Error.Code, http.StatusConflict)
+	}
+	if code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 0); code != http.StatusCreated || resp.Data.Amount != 15 {
+		t.Errorf("refund of the rest = %d %+v, want %d and 15", code, resp.Data, http.StatusCreated)
+	}
+	if code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 0); code != http.StatusConflict || resp.Error.Code != "adjustment_too_large" {
+		t.Errorf("refund of a refunded payment = %d %s, want %d adjustment_too_large", code, resp.Error.Code, http.StatusConflict)
+	}
+
+	if p := ta.capturedPaymentOf(t, psrID); p.Status != payment.Refunded || p.RefundedAmount != 20 {
+		t.Errorf("payment = %+v, want refunded 20", p)
+	}
+	if refunded := ta.gateway.Refunded(reference); refunded != 20 {
+		t.Errorf("refunded %d at the gateway, want 20", refunded)
+	}
+	report := ta.report(t, psrID)
+	if report.TotalFee != 20 || report.TotalRefunds != 20 || report.TotalAdjustments != 20 || report.NetRevenue != 0 {
+		t.Errorf("report = %+v, want fee 20, refunds 20 and no net revenue", report)
+	}
+}
+
+func TestCreateAdjustmentRefundFails(t *testing.T) {
+	ta := newTestApp(t)
+	gateway := &refundGateway{Fake: ta.gateway, failRefunds: true}
+	ta.payments = gateway
+	psrID, _, manager := ta.adjustableReservation(t, true)
+
+	code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 5)
+	if code != http.StatusBadGateway || resp.Error.Code != "refund_failed" || resp.Error.Details.Status != db.AdjustmentFailed {
+		t.Fatalf("refused refund = %d %s %+v, want %d refund_failed and failed", code, resp.Error.Code, resp.Error.Details, http.StatusBadGateway)
+	}
+	// the amount of the failed refund can be refunded again
+	if p := ta.capturedPaymentOf(t, psrID); p.Status != payment.Captured || p.RefundedAmount != 0 {
+		t.Errorf("payment after a failed refund = %+v, want captured and nothing refunded", p)
+	}
+	if report := ta.report(t, psrID); report.TotalRefunds != 0 || report.NetRevenue != 20 {
+		t.Errorf("report = %+v, want the failed refund left out", report)
+	}
+
+	gateway.failRefunds = false
+	if code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 0); code != http.StatusCreated || resp.Data.Amount != 20 {
+		t.Errorf("refund after a failed one = %d %+v, want %d and 20", code, resp.Data, http.StatusCreated)
+	}
+}
+
+func TestCreateAdjustmentRefundNotMarked(t *testing.T) {
+	ta := newTestApp(t)
+	repo := &adjustmentRepository{Repository: ta.dbRepo}
+	ta.dbRepo = repo
+	psrID, _, manager := ta.adjustableReservation(t, true)
+	reference := ta.capturedPaymentOf(t, psrID).Reference
+
+	// the refund was made before the reconciliation is due
+	ta.clock.Set(time.Now().Add(-time.Hour))
+	repo.failUpdates = true
+	code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 5)
+	if code != http.StatusAccepted || resp.Data.Status != db.AdjustmentPending {
+		t.Fatalf("refund not marked done = %d %+v, want %d and pending", code, resp.Data, http.StatusAccepted)
+	}
+	if refunded := ta.gateway.Refunded(reference); refunded != 5 {
+		t.Errorf("refunded %d at the gateway, want 5", refunded)
+	}
+	if report := ta.report(t, psrID, resp.Data.CreatedAt); report.TotalRefunds != 0 {
+		t.Errorf("report = %+v, want the pending refund left out", report)
+	}
+
+	if n, err := ta.reconcileRefunds(); err == nil || n != 0 {
+		t.Errorf("reconcileRefunds() while updates fail = %d, %v, want an error", n, err)
+	}
+
+	repo.failUpdates = false
+	if n, err := ta.reconcileRefunds(); err != nil || n != 1 {
+		t.Fatalf("reconcileRefunds() = %d, %v, want 1", n, err)
+	}
+	adjustments, err := ta.dbRepo.GetAdjustmentsByReservation(psrID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adjustments) != 1 || adjustments[0].Status != db.AdjustmentDone {
+		t.Errorf("adjustments after reconciling = %+v, want one done", adjustments)
+	}
+	// the gateway returned the money once
+	if refunded := ta.gateway.Refunded(reference); refunded != 5 {
+		t.Errorf("refunded %d at the gateway, want 5", refunded)
+	}
+	if report := ta.report(t, psrID, resp.Data.CreatedAt); report.TotalRefunds != 5 {
+		t.Errorf("report = %+v, want the reconciled refund of 5", report)
+	}
+	if n, err := ta.reconcileRefunds(); err != nil || n != 0 {
+		t.Errorf("reconcileRefunds() again = %d, %v, want 0", n, err)
+	}
+}
+
+func TestCreateAdjustmentWaiver(t *testing.T) {
+	ta := newTestApp(t)
+	psrID, token, manager := ta.adjustableReservation(t, false)
+
+	if code, resp := ta.adjust(t, psrID, manager, db.AdjustmentRefund, 5); code != http.StatusConflict || resp.Error.Code != "not_paid" {
+		t.Errorf("refund of an unpaid fee = %d %s, want %d not_paid", code, resp.Error.Code, http.StatusConflict)
+	}
+
+	code, resp := ta.adjust(t, psrID, manager, db.AdjustmentWaiver, 5)
+	if code != http.StatusCreated || resp.Data.Status != db.AdjustmentDone {
+		t.Fatalf("waiver of 5 = %d %+v, want %d and done", code, resp.Data, http.StatusCreated)
+	}
+	if code, resp := ta.adjust(t, psrID, manager, db.AdjustmentWaiver, 16); code != http.StatusConflict || resp.Error.Code != "adjustment_too_large" {
+		t.Errorf("waiver of 16 = %d %s, want %d adjustment_too_large", code, resp.Error.Code, http.StatusConflict)
+	}
+	report := ta.report(t, psrID)
+	if report.TotalWaivers != 5 || report.TotalAdjustments != 5 || report.NetRevenue != 15 {
+		t.Errorf("report = %+v, want waivers of 5 and a net revenue of 15", report)
+	}
+
+	// what is left is paid, it can no longer be waived
+	if code, resp := ta.pay(t, psrID, token, "tok_visa"); code != http.StatusCreated || resp.Data.Amount != 15 {
+		t.Fatalf("pay after a waiver = %d %+v, want %d and 15", code, resp.Data, http.StatusCreated)
+	}
+	if code, resp := ta.adjust(t, psrID, manager, db.AdjustmentWaiver, 0); code != http.StatusConflict || resp.Error.Code != "already_paid" {
+		t.Errorf("waiver of a paid fee = %d %s, want %d already_paid", code, resp.Error.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+)
+
+// GetJobRuns lists the runs of the background jobs latest first.
+func (app *application) GetJobRuns(w http.ResponseWriter, r *http.Request) {
+	var (
+		err  error
+		p    = 1
+		page = r.URL.Query().Get("page")
+	)
+	if page != "" {
+		p, err = strconv.Atoi(page)
+		if err != nil {
+			app.invalidParam(w, r, "page")
+			return
+		}
+	}
+
+	if p < 1 {
+		app.invalidParam(w, r, "page")
+		return
+	}
+
+	count, err := app.dbRepo.GetTotalCountJobRuns()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	jobRuns, err := app.dbRepo.GetJobRuns(p)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data        []db.JobRun `json:"data"`
+		TotalCount  int         `json:"total_count"`
+		CurrentPage int         `json:"current_page"`
+	}{
+		Data:        jobRuns,
+		TotalCount:  count,
+		CurrentPage: p,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
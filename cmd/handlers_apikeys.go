@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultAPIKeyOverlap is how long a rotated key keeps working by default
+const defaultAPIKeyOverlap = 24 * time.Hour
+
+func (app *application) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := app.dbRepo.GetAPIKeys()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data []db.APIKey `json:"data"`
+	}{
+		Data: keys,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// CreateAPIKey issues a key for the actions in the parking lots. The key is
+// only part of this response.
+func (app *application) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		b struct {
+			Name          string        `json:"name"`
+			ParkingLotIDs []int         `json:"parking_lot_ids"`
+			Actions       []auth.Action `json:"actions"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	if len(b.ParkingLotIDs) == 0 {
+		app.invalidField(w, r, "parking_lot_ids", "at least one parking lot is required")
+		return
+	}
+	if len(b.Actions) == 0 {
+		app.invalidField(w, r, "actions", "at least one action is required")
+		return
+	}
+	for _, a := range b.Actions {
+		if !a.Valid() {
+			app.invalidField(w, r, "actions", "unknown action "+strconv.Quote(string(a)))
+			return
+		}
+	}
+
+	k := db.APIKey{
+		Name:          strings.TrimSpace(b.Name),
+		ParkingLotIDs: uniqueInts(b.ParkingLotIDs),
+		Actions:       b.Actions,
+	}
+
+	key, err := newAPIKey(&k)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	id, err := app.dbRepo.CreateAPIKey(k)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.invalidField(w, r, "parking_lot_ids", "parking lot not found")
+			return
+		}
+
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.writeAPIKey(w, r, int(id), key)
+}
+
+// RotateAPIKey issues a new key with the scopes of an active key. The old key
+// keeps working for overlap_minutes, 24 hours by default, so the client can be
+// switched over.
+func (app *application) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID, err := strconv.Atoi(chi.URLParam(r, "apiKeyID"))
+	if err != nil {
+		app.invalidParam(w, r, "apiKeyID")
+		return
+	}
+
+	var (
+		b struct {
+			OverlapMinutes *int `json:"overlap_minutes"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	overlap := defaultAPIKeyOverlap
+	if b.OverlapMinutes != nil {
+		if *b.OverlapMinutes < 0 {
+			app.invalidField(w, r, "overlap_minutes", "overlap_minutes must not be negative")
+			return
+		}
+		overlap = time.Duration(*b.OverlapMinutes) * time.Minute
+	}
+
+	var k db.APIKey
+	key, err := newAPIKey(&k)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	id, err := app.dbRepo.RotateAPIKey(apiKeyID, k, time.Now().Add(overlap))
+	if err != nil {
+		app.dbError(w, r, err, "api key")
+		return
+	}
+
+	app.writeAPIKey(w, r, int(id), key)
+}
+
+// RevokeAPIKey stops a key from working immediately.
+func (app *application) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID, err := strconv.Atoi(chi.URLParam(r, "apiKeyID"))
+	if err != nil {
+		app.invalidParam(w, r, "apiKeyID")
+		return
+	}
+
+	if _, err := app.dbRepo.GetAPIKeyByID(apiKeyID); err != nil {
+		app.dbError(w, r, err, "api key")
+		return
+	}
+
+	if err := app.dbRepo.RevokeAPIKey(apiKeyID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newAPIKey generates a key and sets its key ID and secret hash on k.
+func newAPIKey(k *db.APIKey) (string, error) {
+	key, keyID, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return "", err
+	}
+
+	k.KeyID, k.SecretHash = keyID, hash
+
+	return key, nil
+}
+
+// writeAPIKey writes the 201 response of an issued key.
+func (app *application) writeAPIKey(w http.ResponseWriter, r *http.Request, id int, key string) {
+	k, err := app.dbRepo.GetAPIKeyByID(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.APIKey `json:"data"`
+		Key  string    `json:"key"`
+	}{
+		Data: k,
+		Key:  key,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func uniqueInts(s []int) []int {
+	seen := map[int]bool{}
+	unique := make([]int, 0, len(s))
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultBookingGracePeriod is the minutes a booked space is held after the
+// start of the booking when the parking lot does not configure it
+const defaultBookingGracePeriod = 15
+
+func (app *application) CreateBooking(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	var (
+		b   db.Booking
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	u, _ := userFromContext(r.Context())
+	b.UserID = u.ID
+
+	// without a vehicle type the type of the registered vehicle is used
+	if b.VehicleType != "" && !b.VehicleType.Valid() {
+		app.invalidField(w, r, "vehicle_type", "unknown vehicle type")
+		return
+	}
+
+	if b.Plate != "" {
+		b.Plate, err = vehicle.NormalizePlate(b.Plate)
+		if err != nil {
+			app.invalidField(w, r, "plate", err.Error())
+			return
+		}
+	}
+
+	// times are stored with second precision in UTC
+	b.StartTime = b.StartTime.UTC().Truncate(time.Second)
+	b.EndTime = b.EndTime.UTC().Truncate(time.Second)
+	if b.StartTime.Before(time.Now().UTC().Truncate(time.Minute)) {
+		app.invalidField(w, r, "start_time", "start_time must not be in the past")
+		return
+	}
+	if !b.EndTime.After(b.StartTime) {
+		app.invalidField(w, r, "end_time", "end_time must be after start_time")
+		return
+	}
+
+	if !app.allowFeatures(w, r, parkinglotID, b.Features) {
+		return
+	}
+
+	b, err = app.dbRepo.CreateBooking(parkinglotID, b)
+	if err != nil {
+		app.dbError(w, r, err, "parking lot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Booking `json:"data"`
+	}{
+		Data: b,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func (app *application) GetBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.Atoi(chi.URLParam(r, "bookingID"))
+	if err != nil {
+		app.invalidParam(w, r, "bookingID")
+		return
+	}
+
+	b, ok := app.readBooking(w, r, bookingID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Booking `json:"data"`
+	}{
+		Data: b,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// ArriveBooking parks the driver of a booking, the response is the same as for
+// parking without a booking.
+func (app *application) ArriveBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.Atoi(chi.URLParam(r, "bookingID"))
+	if err != nil {
+		app.invalidParam(w, r, "bookingID")
+		return
+	}
+
+	b, ok := app.readBooking(w, r, bookingID)
+	if !ok {
+		return
+	}
+
+	id, location, err := app.dbRepo.ArriveBooking(bookingID)
+	if err == db.ErrLotFull {
+		app.lotFull(w, r, b.ParkingLotID, db.ParkRequest{VehicleType: b.VehicleType, Features: b.Features})
+		return
+	}
+	if err != nil {
+		app.dbError(w, r, err, "booking")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		ID       int64       `json:"id"`
+		Location db.Location `json:"location"`
+	}{
+		ID:       id,
+		Location: location,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// readBooking reads a booking and writes the error response when it is not
+// found or belongs to another user and the caller is no attendant of its lot.
+func (app *application) readBooking(w http.ResponseWriter, r *http.Request, bookingID int) (db.Booking, bool) {
+	b, err := app.dbRepo.GetBookingByID(bookingID)
+	if err != nil {
+		app.dbError(w, r, err, "booking")
+		return db.Booking{}, false
+	}
+
+	if p, _ := principalFromContext(r.Context()); b.UserID != p.user.ID && !p.can(auth.Attendant, b.ParkingLotID) {
+		app.forbidden(w, r, auth.Attendant, b.ParkingLotID)
+		return db.Booking{}, false
+	}
+
+	return b, true
+}
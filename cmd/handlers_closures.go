@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/go-chi/chi/v5"
+)
+
+// dateLayout is the format of the days of a holiday
+const dateLayout = "2006-01-02"
+
+// GetClosures returns the current and upcoming closures of a parking lot.
+func (app *application) GetClosures(w http.ResponseWriter, r *http.Request) {
+	p, ok := app.readParkingLot(w, r)
+	if !ok {
+		return
+	}
+
+	closures, err := app.dbRepo.GetClosuresByParkingLot(p.ID, time.Now())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data []db.Closure `json:"data"`
+	}{
+		Data: closures,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// CreateClosure closes a parking lot. A holiday closes it from the start of date
+// until the end of end_date, or of date without one, in the time zone of the
+// parking lot. An ad hoc closure closes it from start_time until end_time.
+func (app *application) CreateClosure(w http.ResponseWriter, r *http.Request) {
+	p, ok := app.readParkingLot(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		b struct {
+			Kind      db.ClosureKind `json:"kind"`
+			Reason    string         `json:"reason"`
+			Date      string         `json:"date"`
+			EndDate   string         `json:"end_date"`
+			StartTime time.Time      `json:"start_time"`
+			EndTime   time.Time      `json:"end_time"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	c := db.Closure{
+		Kind:   b.Kind,
+		Reason: strings.TrimSpace(b.Reason),
+	}
+	switch c.Kind {
+	case db.ClosureHoliday:
+		loc, err := time.LoadLocation(p.TimeZone)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		start, err := time.ParseInLocation(dateLayout, b.Date, loc)
+		if err != nil {
+			app.invalidField(w, r, "date", "date must be YYYY-MM-DD")
+			return
+		}
+		end := start
+		if b.EndDate != "" {
+			if end, err = time.ParseInLocation(dateLayout, b.EndDate, loc); err != nil {
+				app.invalidField(w, r, "end_date", "end_date must be YYYY-MM-DD")
+				return
+			}
+		}
+		if end.Before(start) {
+			app.invalidField(w, r, "end_date", "end_date must not be before date")
+			return
+		}
+
+		c.StartTime, c.EndTime = start, end.AddDate(0, 0, 1)
+	case db.ClosureAdHoc:
+		c.StartTime, c.EndTime = b.StartTime, b.EndTime
+	default:
+		app.invalidField(w, r, "kind", "kind must be holiday or ad_hoc")
+		return
+	}
+
+	// times are stored with second precision in UTC
+	c.StartTime = c.StartTime.UTC().Truncate(time.Second)
+	c.EndTime = c.EndTime.UTC().Truncate(time.Second)
+	if !c.EndTime.After(c.StartTime) {
+		app.invalidField(w, r, "end_time", "end_time must be after start_time")
+		return
+	}
+	if !c.EndTime.After(time.Now()) {
+		app.invalidField(w, r, "end_time", "end_time must be in the future")
+		return
+	}
+
+	id, err := app.dbRepo.CreateClosure(p.ID, c)
+	if err != nil {
+		app.dbError(w, r, err, "parking lot")
+		return
+	}
+
+	c.ID, c.ParkingLotID = int(id), p.ID
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Closure `json:"data"`
+	}{
+		Data: c,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// DeleteClosure reopens a parking lot that was closed by a closure.
+func (app *application) DeleteClosure(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	closureID, err := strconv.Atoi(chi.URLParam(r, "closureID"))
+	if err != nil {
+		app.invalidParam(w, r, "closureID")
+		return
+	}
+
+	if err := app.dbRepo.DeleteClosure(parkinglotID, closureID); err != nil {
+		app.dbError(w, r, err, "closure")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) GetUserGrants(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		app.invalidParam(w, r, "userID")
+		return
+	}
+
+	grants, err := app.dbRepo.GetGrantsByUser(userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data []db.Grant `json:"data"`
+	}{
+		Data: grants,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// CreateGrant gives a user a role, for one parking lot when parking_lot_id is
+// set. Drivers need no grant and admins are always global.
+func (app *application) CreateGrant(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		app.invalidParam(w, r, "userID")
+		return
+	}
+
+	var (
+		g   db.Grant
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&g); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	g.UserID = userID
+	if !validGrant(g) {
+		app.invalidField(w, r, "role", "role must be attendant, manager or admin and admin grants must not name a parking lot")
+		return
+	}
+
+	id, err := app.dbRepo.CreateGrant(g)
+	if err != nil {
+		app.dbError(w, r, err, "user or parking lot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		ID int64 `json:"id"`
+	}{
+		ID: id,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func validGrant(g db.Grant) bool {
+	if !g.Role.Valid() || g.Role == auth.Driver {
+		return false
+	}
+	if g.ParkingLotID == nil {
+		return true
+	}
+
+	return *g.ParkingLotID > 0 && g.Role != auth.Admin
+}
+
+func (app *application) DeleteGrant(w http.ResponseWriter, r *http.Request) {
+	grantID, err := strconv.Atoi(chi.URLParam(r, "grantID"))
+	if err != nil {
+		app.invalidParam(w, r, "grantID")
+		return
+	}
+
+	if err := app.dbRepo.DeleteGrant(grantID); err != nil {
+		app.dbError(w, r, err, "grant")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxLabelLength is the size of parking_spaces.label
+const maxLabelLength = 32
+
+// level and zone codes are joined with "-" into space labels like B2-C-014
+var codeRegexp = regexp.MustCompile(`^[A-Z0-9]{1,8}$`)
+
+func (app *application) GetLevels(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	levels, err := app.dbRepo.GetLevelsByParkingLot(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data []db.Level `json:"data"`
+	}{
+		Data: levels,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func (app *application) CreateLevel(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	var (
+		l   db.Level
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&l); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	l.Code = strings.ToUpper(strings.TrimSpace(l.Code))
+	l.Name = strings.TrimSpace(l.Name)
+	if !codeRegexp.MatchString(l.Code) {
+		app.invalidField(w, r, "code", "code must be 1 to 8 letters or digits")
+		return
+	}
+
+	id, err := app.dbRepo.CreateLevel(parkinglotID, l)
+	if err != nil {
+		app.dbError(w, r, err, "level")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		ID int64 `json:"id"`
+	}{
+		ID: id,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// readLevel reads the level of the URL and writes the error response when it is
+// not found in the parking lot of the URL.
+func (app *application) readLevel(w http.ResponseWriter, r *http.Request) (db.Level, bool) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return db.Level{}, false
+	}
+
+	levelID, err := strconv.Atoi(chi.URLParam(r, "levelID"))
+	if err != nil {
+		app.invalidParam(w, r, "levelID")
+		return db.Level{}, false
+	}
+
+	l, err := app.dbRepo.GetLevelByParkingLotIDAndID(parkinglotID, levelID)
+	if err != nil {
+		app.dbError(w, r, err, "level")
+		return db.Level{}, false
+	}
+
+	return l, true
+}
+
+func (app *application) GetLevel(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readLevel(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Level `json:"data"`
+	}{
+		Data: l,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// UpdateLevel updates the name and ordinal of a level, the code is part of the
+// space labels and can not be changed.
+func (app *application) UpdateLevel(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readLevel(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		b struct {
+			Name    *string `json:"name"`
+			Ordinal *int    `json:"ordinal"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	if b.Name != nil {
+		l.Name = strings.TrimSpace(*b.Name)
+	}
+	if b.Ordinal != nil {
+		l.Ordinal = *b.Ordinal
+	}
+
+	if err := app.dbRepo.UpdateLevel(l); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteLevel deletes a level that has no zones.
+func (app *application) DeleteLevel(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readLevel(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.dbRepo.DeleteLevel(l.ID); err != nil {
+		app.dbError(w, r, err, "level")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) GetZones(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readLevel(w, r)
+	if !ok {
+		return
+	}
+
+	zones, err := app.dbRepo.GetZonesByLevel(l.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data []db.Zone `json:"data"`
+	}{
+		Data: zones,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func (app *application) CreateZone(w http.ResponseWriter, r *http.Request) {
+	l, ok := app.readLevel(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		z   db.Zone
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&z); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	z.Code = strings.ToUpper(strings.TrimSpace(z.Code))
+	z.Name = strings.TrimSpace(z.Name)
+	if !codeRegexp.MatchString(z.Code) {
+		app.invalidField(w, r, "code", "code must be 1 to 8 letters or digits")
+		return
+	}
+
+	id, err := app.dbRepo.CreateZone(l.ID, z)
+	if err != nil {
+		app.dbError(w, r, err, "zone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		ID int64 `json:"id"`
+	}{
+		ID: id,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// readZone reads the zone of the URL and writes the error response when it is
+// not found in the level and parking lot of the URL.
+func (app *application) readZone(w http.ResponseWriter, r *http.Request) (db.Zone, bool) {
+	l, ok := app.readLevel(w, r)
+	if !ok {
+		return db.Zone{}, false
+	}
+
+	zoneID, err := strconv.Atoi(chi.URLParam(r, "zoneID"))
+	if err != nil {
+		app.invalidParam(w, r, "zoneID")
+		return db.Zone{}, false
+	}
+
+	z, err := app.dbRepo.GetZoneByLevelIDAndID(l.ID, zoneID)
+	if err != nil {
+		app.dbError(w, r, err, "zone")
+		return db.Zone{}, false
+	}
+
+	return z, true
+}
+
+func (app *application) GetZone(w http.ResponseWriter, r *http.Request) {
+	z, ok := app.readZone(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Zone `json:"data"`
+	}{
+		Data: z,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// UpdateZone updates the name of a zone, the code is part of the space labels
+// and can not be changed.
+func (app *application) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	z, ok := app.readZone(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		b struct {
+			Name *string `json:"name"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	if b.Name != nil {
+		z.Name = strings.TrimSpace(*b.Name)
+	}
+
+	if err := app.dbRepo.UpdateZone(z); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteZone deletes a zone that has no parking spaces.
+func (app *application) DeleteZone(w http.ResponseWriter, r *http.Request) {
+	z, ok := app.readZone(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.dbRepo.DeleteZone(z.ID); err != nil {
+		app.dbError(w, r, err, "zone")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
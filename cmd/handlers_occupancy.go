@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxParkingLotSuggestions is how many other parking lots a full parking lot
+// suggests
+const maxParkingLotSuggestions = 3
+
+// lotFull is the 409 response when the parking lot has no space for the park
+// request. It has the occupancy of the parking lot and, with ?suggest=true,
+// other parking lots nearby that are open and have space for the request.
+func (app *application) lotFull(w http.ResponseWriter, r *http.Request, parkingLotID int, pr db.ParkRequest) {
+	occupancy, err := app.dbRepo.GetOccupancyByParkingLot(parkingLotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	details := struct {
+		Occupancy   db.Occupancy               `json:"occupancy"`
+		Suggestions *[]db.ParkingLotSuggestion `json:"suggestions,omitempty"`
+	}{
+		Occupancy: occupancy,
+	}
+
+	if suggest, _ := strconv.ParseBool(r.URL.Query().Get("suggest")); suggest {
+		// a registered plate may park a larger vehicle, regular spaces are a guess
+		if pr.VehicleType == "" {
+			pr.VehicleType = vehicle.DefaultType
+		}
+
+		suggestions, err := app.dbRepo.GetParkingLotSuggestions(parkingLotID, pr, maxParkingLotSuggestions)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		details.Suggestions = &suggestions
+	}
+
+	app.errorJSON(w, r, http.StatusConflict, db.ErrLotFull.Code, db.ErrLotFull.Message, details)
+}
+
+func (app *application) GetOccupancy(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	occupancy, err := app.dbRepo.GetOccupancyByParkingLot(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Occupancy `json:"data"`
+	}{
+		Data: occupancy,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
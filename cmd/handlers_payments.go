@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/payment"
+	"github.com/go-chi/chi/v5"
+)
+
+// ConnectPaymentGateway sets the gateway fees are paid through, PAYMENT_GATEWAY
+// must name it. Only the fake gateway exists so far, it has to be asked for by
+// name so a deploy that forgets the setting does not let every payment pass.
+func (app *application) ConnectPaymentGateway() {
+	switch gateway := os.Getenv("PAYMENT_GATEWAY"); gateway {
+	case "":
+		app.errorLog.Fatal("PAYMENT_GATEWAY is not set")
+	case "fake":
+		app.infoLog.Println("using the fake payment gateway, no money is moved")
+		app.payments = payment.NewFake()
+	default:
+		app.errorLog.Fatalf("unknown payment gateway %q", gateway)
+	}
+}
+
+// GetPayments lists the payments of a reservation and whether its vehicle may
+// exit, exit gates poll it after an unpark that was not paid.
+func (app *application) GetPayments(w http.ResponseWriter, r *http.Request) {
+	parkingSpaceReservationsID, err := strconv.Atoi(chi.URLParam(r, "parkingSpaceReservationsID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkingSpaceReservationsID")
+		return
+	}
+
+	psr, pl, ok := app.readPayableReservation(w, r, parkingSpaceReservationsID)
+	if !ok {
+		return
+	}
+
+	payments, adjustments, ok := app.readSettlement(w, r, parkingSpaceReservationsID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data        []db.Payment `json:"data"`
+		ExitAllowed bool         `json:"exit_allowed"`
+	}{
+		Data:        payments,
+		ExitAllowed: exitAllowed(pl, psr, payments, adjustments),
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// PayParkingSpaceReservation pays the fee of an unparked reservation, it is
+// retried until a payment is captured.
+func (app *application) PayParkingSpaceReservation(w http.ResponseWriter, r *http.Request) {
+	parkingSpaceReservationsID, err := strconv.Atoi(chi.URLParam(r, "parkingSpaceReservationsID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkingSpaceReservationsID")
+		return
+	}
+
+	var (
+		b struct {
+			PaymentToken string `json:"payment_token"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+	if b.PaymentToken == "" {
+		app.invalidField(w, r, "payment_token", "payment_token is required")
+		return
+	}
+
+	psr, _, ok := app.readPayableReservation(w, r, parkingSpaceReservationsID)
+	if !ok {
+		return
+	}
+	if psr.EndTime == nil {
+		app.dbError(w, r, db.ErrReservationActive, "parking space reservation")
+		return
+	}
+
+	// the claim keeps concurrent requests from paying the reservation twice
+	p, err := app.dbRepo.ClaimPayment(parkingSpaceReservationsID)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return
+	}
+
+	p, err = app.settle(r.Context(), p, b.PaymentToken)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if p.Status != payment.Captured {
+		app.errorJSON(w, r, http.StatusPaymentRequired, "payment_failed", "the payment failed: "+p.FailureReason, p)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.Payment `json:"data"`
+	}{
+		Data: p,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// readPayableReservation reads a reservation and its parking lot and writes the
+// error response when it is not found or the caller may not unpark it.
+func (app *application) readPayableReservation(w http.ResponseWriter, r *http.Request, id int) (db.ParkingSpaceReservation, db.ParkingLot, bool) {
+	psr, err := app.dbRepo.GetParkingSpaceReservationByID(id)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return db.ParkingSpaceReservation{}, db.ParkingLot{}, false
+	}
+	if p, _ := principalFromContext(r.Context()); !p.canActFor(auth.ActionUnpark, psr.ParkingLotID, psr.UserID) {
+		app.forbidAction(w, r, p, auth.ActionUnpark, psr.ParkingLotID)
+		return db.ParkingSpaceReservation{}, db.ParkingLot{}, false
+	}
+
+	pl, err := app.dbRepo.GetParkingLotByID(psr.ParkingLotID)
+	if err != nil {
+		app.dbError(w, r, err, "parking lot")
+		return db.ParkingSpaceReservation{}, db.ParkingLot{}, false
+	}
+
+	return psr, pl, true
+}
+
+// settle authorises and captures a claimed payment, it is saved after every
+// step so a failed payment keeps why it failed. An authorised payment is only
+// captured. The payment is authorised with its ID as idempotency key, settling
+// a payment that was abandoned midway does not authorise it twice. A failed
+// capture voids the authorisation so the payment that retries it does not hold
+// the money twice, when the void fails too the payment stays authorised and its
+// capture is retried. Failures of the gateway are returned as a payment that is
+// not captured, the error is only set when saving failed.
+func (app *application) settle(ctx context.Context, p db.Payment, token string) (db.Payment, error) {
+	if p.Status != payment.Authorised {
+		reference, err := app.payments.Authorise(ctx, token, p.Amount, fmt.Sprintf("payment-%d", p.ID))
+		if err != nil {
+			p.Status, p.FailureReason = payment.Failed, err.Error()
+			return app.dbRepo.UpdatePayment(p)
+		}
+
+		p.Status, p.Reference = payment.Authorised, reference
+		if p, err = app.dbRepo.UpdatePayment(p); err != nil {
+			return db.Payment{}, err
+		}
+	}
+
+	if err := app.payments.Capture(ctx, p.Reference, p.Amount); err != nil {
+		p.FailureReason = err.Error()
+		if voidErr := app.payments.Void(ctx, p.Reference); voidErr != nil {
+			app.errorLog.Printf("payment %d: void after failed capture: %v", p.ID, voidErr)
+			return app.dbRepo.UpdatePayment(p)
+		}
+
+		p.Status = payment.Failed
+		return app.dbRepo.UpdatePayment(p)
+	}
+
+	p.Status, p.FailureReason = payment.Captured, ""
+	return app.dbRepo.UpdatePayment(p)
+}
+
+// capturedPayment returns the payment of payments that was captured, refunds
+// included, nil when the reservation was not paid.
+func capturedPayment(payments []db.Payment) *db.Payment {
+	for i := len(payments) - 1; i >= 0; i-- {
+		if payments[i].Status == payment.Captured || payments[i].Status == payment.Refunded {
+			return &payments[i]
+		}
+	}
+
+	return nil
+}
+
+// readSettlement reads the payments and adjustments of a reservation and
+// writes the error response when that fails.
+func (app *application) readSettlement(w http.ResponseWriter, r *http.Request, id int) ([]db.Payment, []db.Adjustment, bool) {
+	payments, err := app.dbRepo.GetPaymentsByReservation(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, nil, false
+	}
+
+	adjustments, err := app.dbRepo.GetAdjustmentsByReservation(id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, nil, false
+	}
+
+	return payments, adjustments, true
+}
+
+// exitAllowed reports whether the vehicle of a reservation may leave the
+// parking lot, that is it was unparked and paid or waived when payment is
+// required.
+func exitAllowed(pl db.ParkingLot, psr db.ParkingSpaceReservation, payments []db.Payment, adjustments []db.Adjustment) bool {
+	if psr.EndTime == nil {
+		return false
+	}
+
+	return !pl.RequirePayment || psr.Fee == db.Waived(adjustments) || capturedPayment(payments) != nil
+}
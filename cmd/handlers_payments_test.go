@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/payment"
+)
+
+// flakyGateway is the fake gateway counting authorisations, it fails the next
+// failCaptures captures and every void while failVoids is set.
+type flakyGateway struct {
+	*payment.Fake
+
+	mu             sync.Mutex
+	authorisations int
+	failCaptures   int
+	failVoids      bool
+}
+
+func (g *flakyGateway) Authorise(ctx context.Context, token string, amount int, idempotencyKey string) (string, error) {
+	g.mu.Lock()
+	g.authorisations++
+	g.mu.Unlock()
+
+	return g.Fake.Authorise(ctx, token, amount, idempotencyKey)
+}
+
+func (g *flakyGateway) Capture(ctx context.Context, reference string, amount int) error {
+	g.mu.Lock()
+	fail := g.failCaptures > 0
+	if fail {
+		g.failCaptures--
+	}
+	g.mu.Unlock()
+
+	if fail {
+		return errors.New("gateway timeout")
+	}
+	return g.Fake.Capture(ctx, reference, amount)
+}
+
+func (g *flakyGateway) Void(ctx context.Context, reference string) error {
+	g.mu.Lock()
+	fail := g.failVoids
+	g.mu.Unlock()
+
+	if fail {
+		return errors.New("gateway timeout")
+	}
+	return g.Fake.Void(ctx, reference)
+}
+
+type paymentResponse struct {
+	Data  db.Payment `json:"data"`
+	Error struct {
+		Code    string     `json:"code"`
+		Details db.Payment `json:"details"`
+	} `json:"error"`
+}
+
+// unparkedReservation parks a vehicle of a new driver in a parking lot that
+// requires payment and unparks it two hours later without paying. It returns
+// the reservation and the token of the driver.
+func (ta *testApp) unparkedReservation(t *testing.T) (int, string) {
+	t.Helper()
+
+	parkingLotID := ta.parkingLot(t, db.ParkingLot{RequirePayment: true}, 1)
+	_, token := ta.user(t, fmt.Sprintf("driver%d@example.com", parkingLotID))
+
+	var parked struct {
+		ID int `json:"id"`
+	}
+	path := fmt.Sprintf("/api/parking-lots/%d/park", parkingLotID)
+	if code := ta.do(t, http.MethodPost, path, token, map[string]string{"vehicle_type": "car"}, &parked); code != http.StatusCreated {
+		t.Fatalf("park: status %d", code)
+	}
+
+	ta.clock.Add(2 * time.Hour)
+
+	var unparked struct {
+		Fee         int         `json:"fee"`
+		Payment     *db.Payment `json:"payment"`
+		ExitAllowed bool        `json:"exit_allowed"`
+	}
+	path = fmt.Sprintf("/api/parking-reservations/%d/unpark", parked.ID)
+	if code := ta.do(t, http.MethodPost, path, token, nil, &unparked); code != http.StatusCreated {
+		t.Fatalf("unpark: status %d", code)
+	}
+	if unparked.Fee != 20 || unparked.Payment == nil || unparked.Payment.Status != payment.Pending || unparked.ExitAllowed {
+		t.Fatalf("unpark = fee %d, payment %+v, exit allowed %v, want a pending payment of 20 and the exit closed",
+			unparked.Fee, unparked.Payment, unparked.ExitAllowed)
+	}
+
+	return parked.ID, token
+}
+
+func (ta *testApp) pay(t *testing.T, psrID int, token, paymentToken string) (int, paymentResponse) {
+	t.Helper()
+
+	var resp paymentResponse
+	path := fmt.Sprintf("/api/parking-reservations/%d/payments", psrID)
+	code := ta.do(t, http.MethodPost, path, token, map[string]string{"payment_token": paymentToken}, &resp)
+
+	return code, resp
+}
+
+func (ta *testApp) exitAllowed(t *testing.T, psrID int, token string) bool {
+	t.Helper()
+
+	var resp struct {
+		ExitAllowed bool `json:"exit_allowed"`
+	}
+	path := fmt.Sprintf("/api/parking-reservations/%d/payments", psrID)
+	if code := ta.do(t, http.MethodGet, path, token, nil, &resp); code != http.StatusOK {
+		t.Fatalf("payments: status %d", code)
+	}
+
+	return resp.ExitAllowed
+}
+
+func TestPayParkingSpaceReservationDeclined(t *testing.T) {
+	ta := newTestApp(t)
+	psrID, token := ta.unparkedReservation(t)
+
+	code, resp := ta.pay(t, psrID, token, payment.DeclinedToken)
+	if code != http.StatusPaymentRequired || resp.Error.Details.Status != payment.Failed {
+		t.Fatalf("declined payment = %d %s, want %d %s", code, resp.Error.Details.Status, http.StatusPaymentRequired, payment.Failed)
+	}
+	if ta.exitAllowed(t, psrID, token) {
+		t.Error("exit allowed after a declined payment")
+	}
+
+	code, resp = ta.pay(t, psrID, token, "tok_visa")
+	if code != http.StatusCreated || resp.Data.Status != payment.Captured || resp.Data.Amount != 20 {
+		t.Fatalf("retried payment = %d %+v, want %d and 20 captured", code, resp.Data, http.StatusCreated)
+	}
+	if !ta.exitAllowed(t, psrID, token) {
+		t.Error("exit not allowed after the payment was captured")
+	}
+}
+
+func TestPayParkingSpaceReservationCaptureFails(t *testing.T) {
+	ta := newTestApp(t)
+	psrID, token := ta.unparkedReservation(t)
+
+	code, resp := ta.pay(t, psrID, token, payment.CaptureFailsToken)
+	failed := resp.Error.Details
+	if code != http.StatusPaymentRequired || failed.Status != payment.Failed {
+		t.Fatalf("payment failing to capture = %d %s, want %d %s", code, failed.Status, http.StatusPaymentRequired, payment.Failed)
+	}
+	if held := ta.gateway.Held(failed.Reference); held != 0 {
+		t.Errorf("the failed payment holds %d, want its authorisation voided", held)
+	}
+
+	code, resp = ta.pay(t, psrID, token, "tok_visa")
+	if code != http.StatusCreated || resp.Data.Status != payment.Captured {
+		t.Fatalf("retried payment = %d %s, want %d %s", code, resp.Data.Status, http.StatusCreated, payment.Captured)
+	}
+	if resp.Data.Reference == failed.Reference {
+		t.Errorf("retried payment reuses the voided authorisation %s", failed.Reference)
+	}
+}
+
+func TestPayParkingSpaceReservationCaptureAndVoidFail(t *testing.T) {
+	ta := newTestApp(t)
+	gateway := &flakyGateway{Fake: ta.gateway, failCaptures: 1, failVoids: true}
+	ta.payments = gateway
+	psrID, token := ta.unparkedReservation(t)
+
+	code, resp := ta.pay(t, psrID, token, "tok_visa")
+	authorised := resp.Error.Details
+	if code != http.StatusPaymentRequired || authorised.Status != payment.Authorised {
+		t.Fatalf("payment failing to capture and void = %d %s, want %d %s", code, authorised.Status, http.StatusPaymentRequired, payment.Authorised)
+	}
+
+	// the authorisation is captured once the claim of the payment expired
+	ta.clock.Add(5 * time.Minute)
+	code, resp = ta.pay(t, psrID, token, "tok_visa")
+	if code != http.StatusCreated || resp.Data.Status != payment.Captured || resp.Data.Reference != authorised.Reference {
+		t.Fatalf("retried payment = %d %s %s, want %d %s %s", code, resp.Data.Status, resp.Data.Reference,
+			http.StatusCreated, payment.Captured, authorised.Reference)
+	}
+	if gateway.authorisations != 1 {
+		t.Errorf("authorised %d times, want once", gateway.authorisations)
+	}
+}
+
+func TestPayParkingSpaceReservationTwice(t *testing.T) {
+	ta := newTestApp(t)
+	gateway := &flakyGateway{Fake: ta.gateway}
+	ta.payments = gateway
+	psrID, token := ta.unparkedReservation(t)
+
+	const submits = 10
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+	for i := 0; i < submits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			code, _ := ta.pay(t, psrID, token, "tok_visa")
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if codes[http.StatusCreated] != 1 || codes[http.StatusConflict] != submits-1 {
+		t.Errorf("statuses of %d submits = %v, want one %d and the others %d", submits, codes, http.StatusCreated, http.StatusConflict)
+	}
+	if gateway.authorisations != 1 {
+		t.Errorf("authorised %d times, want once", gateway.authorisations)
+	}
+
+	code, resp := ta.pay(t, psrID, token, "tok_visa")
+	if code != http.StatusConflict || resp.Error.Code != db.ErrAlreadyPaid.Code {
+		t.Errorf("paying a paid reservation = %d %s, want %d %s", code, resp.Error.Code, http.StatusConflict, db.ErrAlreadyPaid.Code)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/pricing"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) GetParkingSpaceReservation(w http.ResponseWriter, r *http.Request) {
+	parkingSpaceReservationsID, err := strconv.Atoi(chi.URLParam(r, "parkingSpaceReservationsID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkingSpaceReservationsID")
+		return
+	}
+
+	psr, ok := app.readParkingSpaceReservation(w, r, parkingSpaceReservationsID)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.ParkingSpaceReservation `json:"data"`
+	}{
+		Data: psr,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// maxQuoteAhead is how far in the future a fee may be quoted, pricing a stay
+// takes longer the longer it is
+const maxQuoteAhead = 3 * 24 * time.Hour
+
+// QuoteParkingSpaceReservation returns the fee of an active reservation if it
+// was unparked now or at the optional RFC 3339 time at, at most maxQuoteAhead
+// from now. The reservation stays active.
+func (app *application) QuoteParkingSpaceReservation(w http.ResponseWriter, r *http.Request) {
+	parkingSpaceReservationsID, err := strconv.Atoi(chi.URLParam(r, "parkingSpaceReservationsID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkingSpaceReservationsID")
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	at := now
+	if s := r.URL.Query().Get("at"); s != "" {
+		at, err = time.Parse(time.RFC3339, s)
+		if err != nil || at.Before(now.Add(-time.Minute)) || at.After(now.Add(maxQuoteAhead)) {
+			app.invalidParam(w, r, "at")
+			return
+		}
+	}
+
+	// whoever may unpark the vehicle may see what it costs
+	psr, err := app.dbRepo.GetParkingSpaceReservationByID(parkingSpaceReservationsID)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return
+	}
+	if p, _ := principalFromContext(r.Context()); !p.canActFor(auth.ActionUnpark, psr.ParkingLotID, psr.UserID) {
+		app.forbidAction(w, r, p, auth.ActionUnpark, psr.ParkingLotID)
+		return
+	}
+
+	fee, err := app.dbRepo.QuoteParkingSpaceReservation(parkingSpaceReservationsID, at)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Fee      int                `json:"fee"`
+		At       time.Time          `json:"at"`
+		RatePlan pricing.RatePlan   `json:"rate_plan"`
+		Items    []pricing.LineItem `json:"items"`
+	}{
+		Fee:      fee.Total,
+		At:       at.UTC().Truncate(time.Second),
+		RatePlan: fee.RatePlan,
+		Items:    fee.Items,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// GetUserReservations lists the reservations of a user, drivers may only list
+// their own.
+func (app *application) GetUserReservations(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		app.invalidParam(w, r, "userID")
+		return
+	}
+
+	if p, _ := principalFromContext(r.Context()); userID != p.user.ID && !p.can(auth.Admin, 0) {
+		app.forbidden(w, r, auth.Admin, 0)
+		return
+	}
+
+	f, page, ok := app.readReservationFilter(w, r)
+	if !ok {
+		return
+	}
+	f.UserID = userID
+
+	app.writeParkingSpaceReservations(w, r, f, page)
+}
+
+func (app *application) GetParkingLotReservations(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	f, page, ok := app.readReservationFilter(w, r)
+	if !ok {
+		return
+	}
+	f.ParkingLotID = parkinglotID
+
+	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	app.writeParkingSpaceReservations(w, r, f, page)
+}
+
+// readReservationFilter reads the filter and page of a reservation listing from
+// the query and writes the error response when a parameter is invalid. from and
+// to are RFC 3339 times or dates in UTC, a date in to includes the whole day.
+func (app *application) readReservationFilter(w http.ResponseWriter, r *http.Request) (db.ReservationFilter, int, bool) {
+	var (
+		err   error
+		f     db.ReservationFilter
+		p     = 1
+		query = r.URL.Query()
+	)
+	if page := query.Get("page"); page != "" {
+		p, err = strconv.Atoi(page)
+		if err != nil || p < 1 {
+			app.invalidParam(w, r, "page")
+			return db.ReservationFilter{}, 0, false
+		}
+	}
+
+	switch f.Status = query.Get("status"); f.Status {
+	case "", db.ReservationActive, db.ReservationCompleted:
+	default:
+		app.invalidParam(w, r, "status")
+		return db.ReservationFilter{}, 0, false
+	}
+
+	if id := query.Get("parking_space_id"); id != "" {
+		f.ParkingSpaceID, err = strconv.Atoi(id)
+		if err != nil || f.ParkingSpaceID < 1 {
+			app.invalidParam(w, r, "parking_space_id")
+			return db.ReservationFilter{}, 0, false
+		}
+	}
+
+	if f.Sort = query.Get("sort"); f.Sort != "" && !db.ValidReservationSort(f.Sort) {
+		app.invalidParam(w, r, "sort")
+		return db.ReservationFilter{}, 0, false
+	}
+
+	if from := query.Get("from"); from != "" {
+		var ok bool
+		if f.From, ok = parseQueryTime(from, false); !ok {
+			app.invalidParam(w, r, "from")
+			return db.ReservationFilter{}, 0, false
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		var ok bool
+		if f.To, ok = parseQueryTime(to, true); !ok {
+			app.invalidParam(w, r, "to")
+			return db.ReservationFilter{}, 0, false
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.To.After(f.From) {
+		app.invalidParam(w, r, "to")
+		return db.ReservationFilter{}, 0, false
+	}
+
+	return f, p, true
+}
+
+// parseQueryTime parses an RFC 3339 time or a date in UTC, with end a date is
+// parsed as the end of the day.
+func parseQueryTime(s string, end bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, true
+}
+
+func (app *application) writeParkingSpaceReservations(w http.ResponseWriter, r *http.Request, f db.ReservationFilter, p int) {
+	count, err := app.dbRepo.GetTotalCountParkingSpaceReservations(f)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	reservations, err := app.dbRepo.GetParkingSpaceReservations(f, p)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data        []db.ParkingSpaceReservation `json:"data"`
+		TotalCount  int                          `json:"total_count"`
+		CurrentPage int                          `json:"current_page"`
+	}{
+		Data:        reservations,
+		TotalCount:  count,
+		CurrentPage: p,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// readParkingSpaceReservation reads a reservation and writes the error response
+// when it is not found or belongs to another user and the caller is no
+// attendant of its lot.
+func (app *application) readParkingSpaceReservation(w http.ResponseWriter, r *http.Request, id int) (db.ParkingSpaceReservation, bool) {
+	psr, err := app.dbRepo.GetParkingSpaceReservationByID(id)
+	if err != nil {
+		app.dbError(w, r, err, "parking space reservation")
+		return db.ParkingSpaceReservation{}, false
+	}
+
+	if p, _ := principalFromContext(r.Context()); psr.UserID != p.user.ID && !p.can(auth.Attendant, psr.ParkingLotID) {
+		app.forbidden(w, r, auth.Attendant, psr.ParkingLotID)
+		return db.ParkingSpaceReservation{}, false
+	}
+
+	return psr, true
+}
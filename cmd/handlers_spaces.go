@@ -0,0 +1,227 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxBulkParkingSpaces is the most spaces created by one bulk request
+const maxBulkParkingSpaces = 500
+
+// validParkingSpace normalises ps and writes the response when it is not
+// valid.
+func (app *application) validParkingSpace(w http.ResponseWriter, r *http.Request, ps *db.ParkingSpace) bool {
+	ps.Label = strings.TrimSpace(ps.Label)
+	if len(ps.Label) > maxLabelLength {
+		app.invalidField(w, r, "label", fmt.Sprintf("label must not be longer than %d characters", maxLabelLength))
+		return false
+	}
+	if ps.DistanceToEntrance != nil && *ps.DistanceToEntrance < 0 {
+		app.invalidField(w, r, "distance_to_entrance", "distance_to_entrance must not be negative")
+		return false
+	}
+
+	return true
+}
+
+// readParkingSpace reads the parking lot and the parking space of the URL and
+// writes the response when either does not exist.
+func (app *application) readParkingSpace(w http.ResponseWriter, r *http.Request) (int, db.ParkingSpace, bool) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return 0, db.ParkingSpace{}, false
+	}
+
+	parkingspaceID, err := strconv.Atoi(chi.URLParam(r, "parkingspaceID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkingspaceID")
+		return 0, db.ParkingSpace{}, false
+	}
+
+	ps, err := app.dbRepo.GetParkingSpaceByParkingLotIDAndID(parkinglotID, parkingspaceID)
+	if err != nil {
+		app.dbError(w, r, err, "parking space")
+		return 0, db.ParkingSpace{}, false
+	}
+
+	return parkinglotID, ps, true
+}
+
+func (app *application) GetParkingSpace(w http.ResponseWriter, r *http.Request) {
+	_, ps, ok := app.readParkingSpace(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeParkingSpace(w, ps)
+}
+
+// UpdateParkingSpace changes the attributes present in the body, null clears
+// zone_id and distance_to_entrance. The slot number never changes.
+func (app *application) UpdateParkingSpace(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, ps, ok := app.readParkingSpace(w, r)
+	if !ok {
+		return
+	}
+
+	id, status, slotNumber := ps.ID, ps.Status, ps.SlotNumber
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&ps); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+	ps.ID, ps.Status, ps.SlotNumber = id, status, slotNumber
+
+	if !app.validParkingSpace(w, r, &ps) {
+		return
+	}
+	if ps.Label == "" {
+		app.invalidField(w, r, "label", "label must not be empty")
+		return
+	}
+
+	err := app.dbRepo.UpdateParkingSpace(parkinglotID, ps)
+	if err == sql.ErrNoRows {
+		app.invalidField(w, r, "zone_id", "the zone is not in the parking lot")
+		return
+	}
+	if err != nil {
+		app.dbError(w, r, err, "parking space")
+		return
+	}
+
+	app.writeParkingSpace(w, ps)
+}
+
+// DecommissionParkingSpace takes a parking space without a parked vehicle or
+// held booking out of use for good. Its reservations are kept and its label
+// stays taken.
+func (app *application) DecommissionParkingSpace(w http.ResponseWriter, r *http.Request) {
+	_, ps, ok := app.readParkingSpace(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.dbRepo.DecommissionParkingSpace(ps.ID); err != nil {
+		app.dbError(w, r, err, "parking space")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateParkingSpacesBulk creates count parking spaces or one for each of
+// labels with the same attributes, either all of them or none.
+func (app *application) CreateParkingSpacesBulk(w http.ResponseWriter, r *http.Request) {
+	parkinglotID, err := strconv.Atoi(chi.URLParam(r, "parkinglotID"))
+	if err != nil {
+		app.invalidParam(w, r, "parkinglotID")
+		return
+	}
+
+	exists, err := app.dbRepo.DoesParkingLotExistByID(parkinglotID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if !exists {
+		app.notFound(w, r, "parking lot")
+		return
+	}
+
+	var (
+		b = struct {
+			db.ParkingSpace
+			Count  int      `json:"count"`
+			Labels []string `json:"labels"`
+		}{
+			ParkingSpace: db.ParkingSpace{
+				SizeClass: vehicle.SizeRegular,
+			},
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	if (b.Count > 0) == (len(b.Labels) > 0) {
+		app.invalidField(w, r, "count", "either count or labels is required")
+		return
+	}
+	if b.Count < 0 || b.Count > maxBulkParkingSpaces || len(b.Labels) > maxBulkParkingSpaces {
+		app.invalidField(w, r, "count", fmt.Sprintf("at most %d parking spaces can be created at once", maxBulkParkingSpaces))
+		return
+	}
+
+	// labels are generated for the spaces of a count
+	b.Label = ""
+	if !app.validParkingSpace(w, r, &b.ParkingSpace) {
+		return
+	}
+
+	spaces := make([]db.ParkingSpace, 0, b.Count+len(b.Labels))
+	for i := 0; i < b.Count; i++ {
+		spaces = append(spaces, b.ParkingSpace)
+	}
+	seen := map[string]bool{}
+	for _, label := range b.Labels {
+		ps := b.ParkingSpace
+		ps.Label = label
+		if !app.validParkingSpace(w, r, &ps) {
+			return
+		}
+		if ps.Label == "" || seen[ps.Label] {
+			app.invalidField(w, r, "labels", "labels must be unique and not empty")
+			return
+		}
+
+		seen[ps.Label] = true
+		spaces = append(spaces, ps)
+	}
+
+	ids, err := app.dbRepo.CreateParkingSpacesFromParkingLotID(parkinglotID, spaces)
+	if err == sql.ErrNoRows {
+		app.invalidField(w, r, "zone_id", "the zone is not in the parking lot")
+		return
+	}
+	if err != nil {
+		app.dbError(w, r, err, "parking space")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		IDs []int64 `json:"ids"`
+	}{
+		IDs: ids,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func (app *application) writeParkingSpace(w http.ResponseWriter, ps db.ParkingSpace) {
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.ParkingSpace `json:"data"`
+	}{
+		Data: ps,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
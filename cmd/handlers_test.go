@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+)
+
+func TestGetDailyReportTimeZone(t *testing.T) {
+	ta := newTestApp(t)
+	parkingLotID := ta.parkingLot(t, db.ParkingLot{TimeZone: "Europe/Berlin"}, 1)
+	_, token := ta.user(t, "manager@example.com", db.Grant{Role: auth.Manager, ParkingLotID: &parkingLotID})
+
+	// the stays end at 00:30 in Berlin, on 2024-03-31 the clocks skip an hour
+	for _, end := range []time.Time{
+		time.Date(2024, 3, 29, 23, 30, 0, 0, time.UTC),
+		time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC),
+	} {
+		ta.clock.Set(end.Add(-time.Hour))
+		psrID := ta.parked(t, parkingLotID)
+		ta.clock.Set(end)
+		if _, _, err := ta.dbRepo.UnParkParkingSpaceByID(psrID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		date     string
+		vehicles int
+	}{
+		{"2024-03-29", 0},
+		{"2024-03-30", 1},
+		{"2024-03-31", 1},
+		{"2024-04-01", 1},
+		{"2024-04-02", 0},
+	}
+	for _, tt := range tests {
+		var report struct {
+			Data db.DailyReport `json:"data"`
+		}
+		path := fmt.Sprintf("/api/parking-lots/%d/reports/daily?date=%s", parkingLotID, tt.date)
+		if code := ta.do(t, http.MethodGet, path, token, nil, &report); code != http.StatusOK {
+			t.Fatalf("report of %s: status %d, want %d", tt.date, code, http.StatusOK)
+		}
+		if report.Data.Date != tt.date || report.Data.TotalVehicles != tt.vehicles || report.Data.TotalParkingTime != int64(tt.vehicles)*3600 {
+			t.Errorf("report of %s = %+v, want %d vehicles of an hour", tt.date, report.Data, tt.vehicles)
+		}
+	}
+}
+
+func TestGetDailyReportToday(t *testing.T) {
+	ta := newTestApp(t)
+	// it is already tomorrow in Auckland for most of the day in UTC
+	parkingLotID := ta.parkingLot(t, db.ParkingLot{TimeZone: "Pacific/Auckland"}, 1)
+	_, token := ta.user(t, "manager@example.com", db.Grant{Role: auth.Manager, ParkingLotID: &parkingLotID})
+
+	var report struct {
+		Data db.DailyReport `json:"data"`
+	}
+	path := fmt.Sprintf("/api/parking-lots/%d/reports/daily", parkingLotID)
+	if code := ta.do(t, http.MethodGet, path, token, nil, &report); code != http.StatusOK {
+		t.Fatalf("status %d, want %d", code, http.StatusOK)
+	}
+
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if today := time.Now().In(auckland).Format("2006-01-02"); report.Data.Date != today {
+		t.Errorf("report date = %s, want %s", report.Data.Date, today)
+	}
+}
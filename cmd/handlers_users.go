@@ -0,0 +1,143 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+)
+
+func (app *application) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var (
+		b struct {
+			Email    string `json:"email"`
+			Name     string `json:"name"`
+			Password string `json:"password"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	u := db.User{
+		Email: strings.ToLower(strings.TrimSpace(b.Email)),
+		Name:  strings.TrimSpace(b.Name),
+	}
+	if _, err := mail.ParseAddress(u.Email); err != nil {
+		app.invalidField(w, r, "email", "invalid email address")
+		return
+	}
+
+	var err error
+	u.PasswordHash, err = auth.HashPassword(b.Password)
+	if err == auth.ErrPasswordTooShort {
+		app.invalidField(w, r, "password", fmt.Sprintf("password must have at least %d characters", auth.MinPasswordLength))
+		return
+	}
+	if err == auth.ErrPasswordTooLong {
+		app.invalidField(w, r, "password", fmt.Sprintf("password must have at most %d bytes", auth.MaxPasswordLength))
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	id, err := app.dbRepo.CreateUser(u)
+	if err != nil {
+		app.dbError(w, r, err, "user")
+		return
+	}
+
+	if app.bootstrapAdmin != "" && u.Email == app.bootstrapAdmin {
+		if _, err := app.dbRepo.CreateGrant(db.Grant{UserID: int(id), Role: auth.Admin}); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		ID int64 `json:"id"`
+	}{
+		ID: id,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// Login exchanges an email and password for a bearer token.
+func (app *application) Login(w http.ResponseWriter, r *http.Request) {
+	var (
+		b struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		dec = json.NewDecoder(r.Body)
+	)
+	if err := dec.Decode(&b); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	u, err := app.dbRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(b.Email)))
+	if err != nil && err != sql.ErrNoRows {
+		app.serverError(w, r, err)
+		return
+	}
+	// unknown emails take as long to check as known ones
+	known := err == nil
+	if !known {
+		u.PasswordHash = auth.DummyHash
+	}
+	if !auth.CheckPassword(u.PasswordHash, b.Password) || !known {
+		app.errorJSON(w, r, http.StatusUnauthorized, "invalid_credentials", "invalid email or password", nil)
+		return
+	}
+
+	now := time.Now()
+	token, err := auth.NewToken(u.ID, app.authSecret, now, tokenTTL)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{
+		Token:     token,
+		ExpiresAt: now.Add(tokenTTL).UTC().Truncate(time.Second),
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+func (app *application) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	u, _ := userFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.User `json:"data"`
+	}{
+		Data: u,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
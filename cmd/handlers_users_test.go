@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+)
+
+// signUp creates a user through the API and logs in as it.
+func (ta *testApp) signUp(t *testing.T, email string) (int, string) {
+	t.Helper()
+
+	var created struct {
+		ID int `json:"id"`
+	}
+	body := map[string]string{"email": email, "name": "Driver", "password": "secret123"}
+	if code := ta.do(t, http.MethodPost, "/api/users", "", body, &created); code != http.StatusCreated {
+		t.Fatalf("signing up %s: status %d, want %d", email, code, http.StatusCreated)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	body = map[string]string{"email": email, "password": "secret123"}
+	if code := ta.do(t, http.MethodPost, "/api/auth/login", "", body, &login); code != http.StatusOK {
+		t.Fatalf("logging in as %s: status %d, want %d", email, code, http.StatusOK)
+	}
+
+	return created.ID, login.Token
+}
+
+func (ta *testApp) isAdmin(t *testing.T, userID int) bool {
+	t.Helper()
+
+	grants, err := ta.dbRepo.GetGrantsByUser(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range grants {
+		if g.Role == auth.Admin && g.ParkingLotID == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCreateUserBootstrapAdmin(t *testing.T) {
+	ta := newTestApp(t)
+	ta.bootstrapAdmin = "admin@example.com"
+
+	driver, _ := ta.signUp(t, "driver@example.com")
+	if ta.isAdmin(t, driver) {
+		t.Error("a user other than the bootstrap admin is admin")
+	}
+
+	admin, _ := ta.signUp(t, " Admin@Example.com")
+	if !ta.isAdmin(t, admin) {
+		t.Error("the bootstrap admin is not admin")
+	}
+}
+
+func TestCreateUserWithoutBootstrapAdmin(t *testing.T) {
+	ta := newTestApp(t)
+
+	// the first user is not made admin
+	id, token := ta.signUp(t, "admin@example.com")
+	if ta.isAdmin(t, id) {
+		t.Error("a user is admin without a bootstrap admin")
+	}
+	if code := ta.do(t, http.MethodGet, "/api/admin/job-runs", token, nil, nil); code != http.StatusForbidden {
+		t.Errorf("listing job runs: status %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	ta := newTestApp(t)
+	ta.signUp(t, "driver@example.com")
+
+	tests := []struct {
+		name string
+		body map[string]string
+		want int
+	}{
+		{"invalid email", map[string]string{"email": "driver", "password": "secret123"}, http.StatusBadRequest},
+		{"short password", map[string]string{"email": "other@example.com", "password": "short"}, http.StatusBadRequest},
+		{"taken email", map[string]string{"email": "Driver@example.com", "password": "secret123"}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ta.do(t, http.MethodPost, "/api/users", "", tt.body, nil); code != tt.want {
+				t.Errorf("status %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ta := newTestApp(t)
+	id, token := ta.signUp(t, "driver@example.com")
+
+	var me struct {
+		Data struct {
+			ID    int    `json:"id"`
+			Email string `json:"email"`
+		} `json:"data"`
+	}
+	if code := ta.do(t, http.MethodGet, "/api/users/me", token, nil, &me); code != http.StatusOK {
+		t.Fatalf("getting the current user: status %d, want %d", code, http.StatusOK)
+	}
+	if me.Data.ID != id || me.Data.Email != "driver@example.com" {
+		t.Errorf("current user = %+v, want %d driver@example.com", me.Data, id)
+	}
+
+	for _, body := range []map[string]string{
+		{"email": "driver@example.com", "password": "wrong password"},
+		{"email": "nobody@example.com", "password": "secret123"},
+	} {
+		var e struct {
+			Error apiError `json:"error"`
+		}
+		if code := ta.do(t, http.MethodPost, "/api/auth/login", "", body, &e); code != http.StatusUnauthorized || e.Error.Code != "invalid_credentials" {
+			t.Errorf("logging in as %s: status %d, code %q, want %d invalid_credentials", body["email"], code, e.Error.Code, http.StatusUnauthorized)
+		}
+	}
+
+	if code := ta.do(t, http.MethodGet, "/api/users/me", "invalid", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("getting the current user with an invalid token: status %d, want %d", code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) CreateVehicle(w http.ResponseWriter, r *http.Request) {
+	var (
+		v   db.Vehicle
+		dec = json.NewDecoder(r.Body)
+		err error
+	)
+	if err := dec.Decode(&v); err != nil {
+		app.invalidJSON(w, r, err)
+		return
+	}
+
+	u, _ := userFromContext(r.Context())
+	v.UserID = u.ID
+
+	v.Plate, err = vehicle.NormalizePlate(v.Plate)
+	if err != nil {
+		app.invalidField(w, r, "plate", err.Error())
+		return
+	}
+
+	if v.VehicleType == "" {
+		v.VehicleType = vehicle.DefaultType
+	}
+	if !v.VehicleType.Valid() {
+		app.invalidField(w, r, "vehicle_type", "unknown vehicle type")
+		return
+	}
+
+	id, err := app.dbRepo.CreateVehicle(v)
+	if err != nil {
+		app.dbError(w, r, err, "vehicle")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		ID int64 `json:"id"`
+	}{
+		ID: id,
+	}
+	encoder := json.NewEncoder(w)
+	w.WriteHeader(http.StatusCreated)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
+
+// GetVehicleCurrentParking tells the owner, or an attendant of the parking lot,
+// where a vehicle is parked. 404 is returned to the owner and global attendants
+// when the plate is not registered or the vehicle is not parked, 403 to anyone
+// else.
+func (app *application) GetVehicleCurrentParking(w http.ResponseWriter, r *http.Request) {
+	plate, err := vehicle.NormalizePlate(chi.URLParam(r, "plate"))
+	if err != nil {
+		app.invalidParam(w, r, "plate")
+		return
+	}
+
+	// the caller is authorised first, only owners and global attendants learn
+	// that a plate is not registered or not parked
+	var (
+		cp       db.CurrentParking
+		resource = "vehicle"
+	)
+	v, err := app.dbRepo.GetVehicleByPlate(plate)
+	if err == nil {
+		cp, err = app.dbRepo.GetCurrentParkingByVehicle(v.ID)
+		resource = "current parking"
+	}
+	if err != nil && err != sql.ErrNoRows {
+		app.serverError(w, r, err)
+		return
+	}
+
+	p, _ := principalFromContext(r.Context())
+	owner := v.ID > 0 && p.user.ID > 0 && v.UserID == p.user.ID
+	if !owner && !p.can(auth.Attendant, cp.ParkingLotID) {
+		// the parking lot is not named, it would tell where the vehicle is
+		app.forbidden(w, r, auth.Attendant, 0)
+		return
+	}
+	if err != nil {
+		app.dbError(w, r, err, resource)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resultData := struct {
+		Data db.CurrentParking `json:"data"`
+	}{
+		Data: cp,
+	}
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(resultData); err != nil {
+		app.errorLog.Println(err)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/hours"
+	"github.com/arifmahmudrana/parking-lot/scheduler"
+)
+
+const (
+	// jobRunRetention is how long the history of a job run is kept
+	jobRunRetention = 30 * 24 * time.Hour
+	// refundReconcileAfter is how long a refund is pending before it is
+	// reconciled, the request that created it has long finished by then
+	refundReconcileAfter = 10 * time.Minute
+)
+
+func (app *application) newScheduler() *scheduler.Scheduler {
+	s := scheduler.New(app.recordJobRun)
+
+	s.Add(scheduler.Job{
+		Name:     "release_no_show_bookings",
+		Interval: time.Minute,
+		Run:      app.dbRepo.ReleaseNoShowBookings,
+	})
+	s.Add(scheduler.Job{
+		Name:     "flag_overstays",
+		Interval: 5 * time.Minute,
+		Run:      app.dbRepo.FlagOverstays,
+	})
+	s.Add(scheduler.Job{
+		Name:     "enforce_closing_policies",
+		Interval: 5 * time.Minute,
+		Run:      app.enforceClosingPolicies,
+	})
+	s.Add(scheduler.Job{
+		Name:     "close_stale_reservations",
+		Interval: time.Hour,
+		Run:      app.closeStaleReservations,
+	})
+	s.Add(scheduler.Job{
+		Name:     "reconcile_refunds",
+		Interval: 5 * time.Minute,
+		Run:      app.reconcileRefunds,
+	})
+	s.Add(scheduler.Job{
+		Name:     "prune_job_runs",
+		Interval: 24 * time.Hour,
+		Run:      app.pruneJobRuns,
+	})
+
+	return s
+}
+
+// closeStaleReservations unparks the reservations older than the stale after
+// of their parking lot, they are charged like any other reservation.
+func (app *application) closeStaleReservations() (int64, error) {
+	ids, err := app.dbRepo.GetStaleReservationIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	requirePayment := map[int]bool{} // by parking lot
+	return app.unparkReservations(ids, func(id int) (bool, error) {
+		psr, err := app.dbRepo.GetParkingSpaceReservationByID(id)
+		if err != nil {
+			return false, err
+		}
+
+		require, ok := requirePayment[psr.ParkingLotID]
+		if !ok {
+			pl, err := app.dbRepo.GetParkingLotByID(psr.ParkingLotID)
+			if err != nil {
+				return false, err
+			}
+			require = pl.RequirePayment
+			requirePayment[psr.ParkingLotID] = require
+		}
+
+		return require, nil
+	})
+}
+
+// unparkReservations unparks the reservations with ids and returns how many it
+// unparked. The fee of a reservation pay reports true for is recorded as a
+// pending payment like the one of a driver unparking without paying, so it is
+// collected with PayParkingSpaceReservation.
+func (app *application) unparkReservations(ids []int, pay func(id int) (bool, error)) (int64, error) {
+	var n int64
+	for _, id := range ids {
+		p, err := pay(id)
+		if err != nil {
+			return n, err
+		}
+
+		_, _, err = app.dbRepo.UnParkParkingSpaceByID(id, p)
+		if err == db.ErrAlreadyUnparked {
+			// unparked since it was read
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+// reconcileRefunds refunds the refunds left pending again, with the same
+// idempotency key the gateway returns the money of those it already refunded
+// only once. It returns how many it marked done or failed.
+func (app *application) reconcileRefunds() (int64, error) {
+	refunds, err := app.dbRepo.GetPendingRefunds(time.Now().Add(-refundReconcileAfter))
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, a := range refunds {
+		payments, err := app.dbRepo.GetPaymentsByReservation(a.ParkingSpaceReservationID)
+		if err != nil {
+			return n, err
+		}
+
+		var reference string
+		for _, p := range payments {
+			if a.PaymentID != nil && p.ID == *a.PaymentID {
+				reference = p.Reference
+			}
+		}
+
+		if _, err := app.refund(context.Background(), a, reference); err != nil {
+			return n, fmt.Errorf("adjustment %d: %w", a.ID, err)
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// pruneJobRuns deletes the job runs older than jobRunRetention.
+func (app *application) pruneJobRuns() (int64, error) {
+	return app.dbRepo.DeleteJobRunsBefore(time.Now().Add(-jobRunRetention))
+}
+
+// enforceClosingPolicies applies the closing policy of every closed parking lot
+// to the vehicles still parked in it.
+func (app *application) enforceClosingPolicies() (int64, error) {
+	now := time.Now()
+	var n int64
+	for page := 1; ; page++ {
+		parkingLots, err := app.dbRepo.GetParkingLots(page)
+		if err != nil {
+			return n, err
+		}
+		if len(parkingLots) == 0 {
+			return n, nil
+		}
+
+		for _, p := range parkingLots {
+			if p.ClosingPolicy == hours.Stay {
+				continue
+			}
+
+			closures, err := app.dbRepo.GetClosuresByParkingLot(p.ID, now)
+			if err != nil {
+				return n, err
+			}
+			open, err := p.OpenAt(now, closures)
+			if err != nil {
+				return n, err
+			}
+			if open {
+				continue
+			}
+
+			affected, err := app.applyClosingPolicy(p)
+			n += affected
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+func (app *application) applyClosingPolicy(p db.ParkingLot) (int64, error) {
+	if p.ClosingPolicy == hours.Flag {
+		return app.dbRepo.FlagActiveReservationsByParkingLot(p.ID)
+	}
+
+	ids, err := app.dbRepo.GetActiveReservationIDsByParkingLot(p.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return app.unparkReservations(ids, func(int) (bool, error) {
+		return p.RequirePayment, nil
+	})
+}
+
+func (app *application) recordJobRun(r scheduler.Run) {
+	jr := db.JobRun{
+		Name:       r.Job,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		Status:     db.JobRunSucceeded,
+		Affected:   r.Affected,
+	}
+	if r.Err != nil {
+		app.errorLog.Printf("job %s: %v", r.Job, r.Err)
+		jr.Status = db.JobRunFailed
+		jr.Error = r.Err.Error()
+	}
+
+	if _, err := app.dbRepo.CreateJobRun(jr); err != nil {
+		app.errorLog.Println(err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/hours"
+	"github.com/arifmahmudrana/parking-lot/payment"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// parked parks a vehicle in a parking lot and returns the reservation.
+func (ta *testApp) parked(t *testing.T, parkingLotID int) int {
+	t.Helper()
+
+	id, _, err := ta.dbRepo.ParkParkingSpaceByParkingLot(parkingLotID, db.ParkRequest{VehicleType: vehicle.Car})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return int(id)
+}
+
+// assertPendingPayment checks whether the fee of an unparked reservation is
+// recorded as a pending payment.
+func (ta *testApp) assertPendingPayment(t *testing.T, psrID int, want bool) {
+	t.Helper()
+
+	psr, err := ta.dbRepo.GetParkingSpaceReservationByID(psrID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if psr.EndTime == nil || psr.Fee == 0 {
+		t.Fatalf("reservation %d = %+v, want it unparked with a fee", psrID, psr)
+	}
+
+	payments, err := ta.dbRepo.GetPaymentsByReservation(psrID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !want {
+		if len(payments) != 0 {
+			t.Errorf("reservation %d has payments %+v, want none", psrID, payments)
+		}
+		return
+	}
+	if len(payments) != 1 || payments[0].Status != payment.Pending || payments[0].Amount != psr.Fee {
+		t.Errorf("reservation %d has payments %+v, want one pending payment of %d", psrID, payments, psr.Fee)
+	}
+}
+
+func TestCloseStaleReservations(t *testing.T) {
+	ta := newTestApp(t)
+	staleAfter := 60
+	paying := ta.parkingLot(t, db.ParkingLot{StaleAfter: &staleAfter, RequirePayment: true}, 1)
+	free := ta.parkingLot(t, db.ParkingLot{StaleAfter: &staleAfter}, 1)
+	never := ta.parkingLot(t, db.ParkingLot{}, 1)
+
+	payingID, freeID, neverID := ta.parked(t, paying), ta.parked(t, free), ta.parked(t, never)
+	ta.clock.Add(30 * time.Minute)
+	if n, err := ta.closeStaleReservations(); err != nil || n != 0 {
+		t.Fatalf("closeStaleReservations() before stale after = %d, %v, want 0", n, err)
+	}
+
+	ta.clock.Add(time.Hour)
+	if n, err := ta.closeStaleReservations(); err != nil || n != 2 {
+		t.Fatalf("closeStaleReservations() = %d, %v, want 2", n, err)
+	}
+	ta.assertPendingPayment(t, payingID, true)
+	ta.assertPendingPayment(t, freeID, false)
+
+	psr, err := ta.dbRepo.GetParkingSpaceReservationByID(neverID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if psr.EndTime != nil {
+		t.Errorf("unparked reservation %d of a parking lot without stale after", neverID)
+	}
+}
+
+func TestApplyClosingPolicy(t *testing.T) {
+	ta := newTestApp(t)
+	paying := ta.parkingLot(t, db.ParkingLot{ClosingPolicy: hours.Unpark, RequirePayment: true}, 1)
+	free := ta.parkingLot(t, db.ParkingLot{ClosingPolicy: hours.Unpark}, 1)
+	payingID, freeID := ta.parked(t, paying), ta.parked(t, free)
+	ta.clock.Add(2 * time.Hour)
+
+	for _, id := range []int{paying, free} {
+		pl, err := ta.dbRepo.GetParkingLotByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := ta.applyClosingPolicy(pl); err != nil || n != 1 {
+			t.Fatalf("applyClosingPolicy(%d) = %d, %v, want 1", id, n, err)
+		}
+	}
+	ta.assertPendingPayment(t, payingID, true)
+	ta.assertPendingPayment(t, freeID, false)
+}
+
+func TestPruneJobRuns(t *testing.T) {
+	ta := newTestApp(t)
+	now := time.Now()
+	for _, age := range []time.Duration{jobRunRetention + time.Hour, jobRunRetention - time.Hour, time.Minute} {
+		_, err := ta.dbRepo.CreateJobRun(db.JobRun{
+			Name:       "test",
+			StartedAt:  now.Add(-age),
+			FinishedAt: now.Add(-age),
+			Status:     db.JobRunSucceeded,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := ta.pruneJobRuns(); err != nil || n != 1 {
+		t.Fatalf("pruneJobRuns() = %d, %v, want 1", n, err)
+	}
+
+	runs, err := ta.dbRepo.GetJobRuns(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, err := ta.dbRepo.GetTotalCountJobRuns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || total != 2 || runs[0].ID != 3 || runs[1].ID != 2 {
+		t.Errorf("job runs after pruning = %+v, total %d, want runs 3 and 2", runs, total)
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // parking lot time zones must not depend on the host
 
 	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/payment"
 )
 
 const version = "1.0.0"
@@ -17,10 +19,19 @@ const version = "1.0.0"
 type application struct {
 	infoLog, errorLog *log.Logger
 	version           string
-	dbRepo            *db.DB // TODO: use interface for testing
+	dbRepo            db.Repository
+	authSecret        []byte
+	payments          payment.Gateway
+	bootstrapAdmin    string // email of the user made admin when signing up
 }
 
 func (app *application) ConnectDB() {
+	if os.Getenv("DB_DRIVER") == "memory" {
+		app.infoLog.Println("using in-memory database, data is lost on exit")
+		app.dbRepo = db.NewMemoryDB()
+		return
+	}
+
 	dbRepo, err := db.NewDB(os.Getenv("MYSQL_DSN"))
 	if err != nil {
 		app.errorLog.Fatal(err)
@@ -29,7 +40,15 @@ func (app *application) ConnectDB() {
 	app.dbRepo = dbRepo
 }
 
-// To run the application compile or run `MYSQL_DSN='root:root@tcp(127.0.0.1:3306)/parking_lot' go run cmd/*.go“
+// To run the application compile or run `MYSQL_DSN='root:root@tcp(127.0.0.1:3306)/parking_lot' AUTH_SECRET='...' go run cmd/*.go“
+// To run without MySQL use `DB_DRIVER=memory PAYMENT_GATEWAY=fake go run cmd/*.go`
+// PAYMENT_GATEWAY is required and selects the payment gateway, only `fake` exists so far
+// MySQL 8.0 or later is required, migrations use window functions
+// To create or update the schema run `go run cmd/*.go migrate up`
+// Databases created before migrations are adopted with `go run cmd/*.go migrate baseline 3`, it records
+// the migrations that created the parking_lots, parking_spaces and parking_space_reservations tables as applied
+// To make a user admin run `go run cmd/*.go grant <email> admin`, without MySQL there is no grant
+// subcommand, set BOOTSTRAP_ADMIN_EMAIL and the user signing up with that email is made admin
 func main() {
 	app := &application{
 		infoLog:  log.New(os.Stdout, "INFO\t", log.Ldate|log.Ltime),
@@ -37,8 +56,21 @@ func main() {
 		version:  version,
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		app.migrate(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "grant" {
+		app.grant(os.Args[2:])
+		return
+	}
+
+	app.LoadAuthSecret()
+	app.LoadBootstrapAdmin()
 	app.ConnectDB()
 	defer app.dbRepo.Close()
+	app.ConnectPaymentGateway()
 
 	// The HTTP Server
 	server := &http.Server{
@@ -53,15 +85,21 @@ func main() {
 	// Server run context
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
+	// Background jobs run until shutdown
+	jobsCtx, jobsStopCtx := context.WithCancel(serverCtx)
+	jobs := app.newScheduler()
+	jobs.Start(jobsCtx)
+
 	// Listen for syscall signals for process to interrupt/quit
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-sig
 
-		// Shutdown signal with grace period of 30 seconds
-		// For not calling cancel(https://github.com/grpc/grpc-go/issues/1099)
-		shutdownCtx, _ := context.WithTimeout(serverCtx, 30*time.Second)
+		// Shutdown signal with grace period of 30 seconds, it also bounds waiting
+		// for running jobs
+		shutdownCtx, shutdownCancel := context.WithTimeout(serverCtx, 30*time.Second)
+		defer shutdownCancel()
 
 		go func() {
 			<-shutdownCtx.Done()
@@ -75,6 +113,11 @@ func main() {
 		if err != nil {
 			app.errorLog.Fatal(err)
 		}
+
+		// Let running jobs finish
+		jobsStopCtx()
+		jobs.Wait()
+
 		serverStopCtx()
 	}()
 
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+	"github.com/arifmahmudrana/parking-lot/db"
+	"github.com/arifmahmudrana/parking-lot/payment"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// testClock is the clock of the in-memory database of a test, tests move it to
+// let time pass.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *testClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// testApp is an application on an in-memory database and the fake payment
+// gateway.
+type testApp struct {
+	*application
+	handler http.Handler
+	clock   *testClock
+	gateway *payment.Fake
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+
+	clock := &testClock{now: time.Now().UTC().Truncate(time.Second)}
+	gateway := payment.NewFake()
+	app := &application{
+		infoLog:    log.New(io.Discard, "", 0),
+		errorLog:   log.New(io.Discard, "", 0),
+		version:    version,
+		dbRepo:     db.NewMemoryDBWithClock(clock.Now),
+		authSecret: []byte("test secret"),
+		payments:   gateway,
+	}
+
+	return &testApp{
+		application: app,
+		handler:     app.routes(),
+		clock:       clock,
+		gateway:     gateway,
+	}
+}
+
+// user creates a user with the grants and returns its bearer token.
+func (ta *testApp) user(t *testing.T, email string, grants ...db.Grant) (int, string) {
+	t.Helper()
+
+	id, err := ta.dbRepo.CreateUser(db.User{Email: email, Name: email, PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range grants {
+		g.UserID = int(id)
+		if _, err := ta.dbRepo.CreateGrant(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token, err := auth.NewToken(int(id), ta.authSecret, time.Now(), tokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return int(id), token
+}
+
+// parkingLot creates a parking lot with regular parking spaces.
+func (ta *testApp) parkingLot(t *testing.T, pl db.ParkingLot, spaces int) int {
+	t.Helper()
+
+	if pl.Name == "" {
+		pl.Name = "test"
+	}
+	if pl.TimeZone == "" {
+		pl.TimeZone = "UTC"
+	}
+	if pl.AllocationStrategy == "" {
+		pl.AllocationStrategy = "first_available"
+	}
+	id, err := ta.dbRepo.CreateParkingLot(pl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < spaces; i++ {
+		if _, err := ta.dbRepo.CreateParkingSpaceFromParkingLotID(int(id), db.ParkingSpace{SizeClass: vehicle.SizeRegular}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return int(id)
+}
+
+// do sends a request with the JSON body, if any, as the bearer of token and
+// decodes the response into out, if any.
+func (ta *testApp) do(t *testing.T, method, path, token string, body, out any) int {
+	t.Helper()
+
+	var r io.Reader = http.NoBody
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req := httptest.NewRequest(method, path, r)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	ta.handler.ServeHTTP(rec, req)
+
+	if out != nil {
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding %d response: %v", method, path, rec.Code, err)
+		}
+	}
+
+	return rec.Code
+}
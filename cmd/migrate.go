@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/arifmahmudrana/parking-lot/db"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status | baseline <version>"
+
+// migrate runs the migrate subcommand, e.g. `go run cmd/*.go migrate up`
+func (app *application) migrate(args []string) {
+	if len(args) == 0 {
+		app.errorLog.Fatal(migrateUsage)
+	}
+
+	dbRepo, err := db.NewDB(os.Getenv("MYSQL_DSN"))
+	if err != nil {
+		app.errorLog.Fatal(err)
+	}
+	defer dbRepo.Close()
+
+	switch args[0] {
+	case "up":
+		migrations, err := dbRepo.MigrateUp()
+		for _, m := range migrations {
+			app.infoLog.Printf("applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			app.errorLog.Fatal(err)
+		}
+		if len(migrations) == 0 {
+			app.infoLog.Println("no pending migrations")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				app.errorLog.Fatal(migrateUsage)
+			}
+		}
+
+		migrations, err := dbRepo.MigrateDown(steps)
+		for _, m := range migrations {
+			app.infoLog.Printf("reverted %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			app.errorLog.Fatal(err)
+		}
+		if len(migrations) == 0 {
+			app.infoLog.Println("no applied migrations")
+		}
+	case "baseline":
+		if len(args) != 2 {
+			app.errorLog.Fatal(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			app.errorLog.Fatal(migrateUsage)
+		}
+
+		migrations, err := dbRepo.MigrateBaseline(version)
+		for _, m := range migrations {
+			app.infoLog.Printf("recorded %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			app.errorLog.Fatal(err)
+		}
+		if len(migrations) == 0 {
+			app.infoLog.Println("no migrations to record")
+		}
+	case "status":
+		migrations, err := dbRepo.MigrationStatus()
+		if err != nil {
+			app.errorLog.Fatal(err)
+		}
+		for _, m := range migrations {
+			appliedAt := "pending"
+			if m.AppliedAt != nil {
+				appliedAt = "applied at " + *m.AppliedAt
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, appliedAt)
+		}
+	default:
+		app.errorLog.Fatal(migrateUsage)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"net/http"
 
+	"github.com/arifmahmudrana/parking-lot/auth"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -14,17 +15,106 @@ func (app *application) routes() http.Handler {
 		middleware.Logger,
 		middleware.RequestID,
 		middleware.Recoverer,
+		app.authenticate,
+		app.authenticateAPIKey,
 	)
 
+	mux.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		app.notFound(w, r, "route")
+	})
+	mux.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		app.errorJSON(w, r, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed", nil)
+	})
+
+	mux.Post("/api/users", app.CreateUser)
+	mux.Post("/api/auth/login", app.Login)
+
+	// the caller is the driver
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireUser)
+
+		mux.Get("/api/users/me", app.GetCurrentUser)
+
+		mux.Post("/api/vehicles", app.CreateVehicle)
+		mux.Get("/api/vehicles/{plate}/current", app.GetVehicleCurrentParking)
+
+		mux.Post("/api/parking-lots/{parkinglotID}/bookings", app.CreateBooking)
+		mux.Get("/api/bookings/{bookingID}", app.GetBooking)
+		mux.Post("/api/bookings/{bookingID}/arrive", app.ArriveBooking)
+
+		mux.Get("/api/parking-reservations/{parkingSpaceReservationsID}", app.GetParkingSpaceReservation)
+		mux.Get("/api/users/{userID}/reservations", app.GetUserReservations)
+
+		// managers of the parking lot of the reservation
+		mux.Get("/api/parking-reservations/{parkingSpaceReservationsID}/adjustments", app.GetAdjustments)
+		mux.Post("/api/parking-reservations/{parkingSpaceReservationsID}/adjustments", app.CreateAdjustment)
+	})
+
 	mux.Get("/api/parking-lots", app.GetParkingLots)
-	mux.Post("/api/parking-lots", app.CreateParkingLots)
+	mux.Get("/api/parking-lots/{parkinglotID}", app.GetParkingLot)
 	mux.Get("/api/parking-lots/{parkinglotID}/parking-spaces", app.GetParkingSpaces)
-	mux.Post("/api/parking-lots/{parkinglotID}/parking-spaces", app.CreateParkingSpaces)
-	mux.Post("/api/parking-lots/{parkinglotID}/park", app.ParkParkingSpaces)
-	mux.Post("/api/parking-reservations/{parkingSpaceReservationsID}/unpark", app.UnParkParkingSpace)
-	mux.Post("/api/parking-lots/{parkinglotID}/parking-spaces/{parkingspaceID}/maintanance", app.ParkingSpaceMaintanance)
+	mux.Get("/api/parking-lots/{parkinglotID}/parking-spaces/{parkingspaceID}", app.GetParkingSpace)
+	mux.Get("/api/parking-lots/{parkinglotID}/occupancy", app.GetOccupancy)
+	mux.Get("/api/parking-lots/{parkinglotID}/closures", app.GetClosures)
+	mux.Get("/api/parking-lots/{parkinglotID}/rate-plan", app.GetRatePlan)
+	mux.Get("/api/parking-lots/{parkinglotID}/levels", app.GetLevels)
+	mux.Get("/api/parking-lots/{parkinglotID}/levels/{levelID}", app.GetLevel)
+	mux.Get("/api/parking-lots/{parkinglotID}/levels/{levelID}/zones", app.GetZones)
+	mux.Get("/api/parking-lots/{parkinglotID}/levels/{levelID}/zones/{zoneID}", app.GetZone)
+
+	// drivers and gate controllers with an API key
+	mux.With(app.requireAction(auth.ActionPark)).Post("/api/parking-lots/{parkinglotID}/park", app.ParkParkingSpaces)
+	mux.With(app.requireAction(auth.ActionUnpark)).Post("/api/parking-reservations/{parkingSpaceReservationsID}/unpark", app.UnParkParkingSpace)
+	mux.With(app.requireAction(auth.ActionUnpark)).Get("/api/parking-reservations/{parkingSpaceReservationsID}/quote", app.QuoteParkingSpaceReservation)
+	mux.With(app.requireAction(auth.ActionUnpark)).Get("/api/parking-reservations/{parkingSpaceReservationsID}/payments", app.GetPayments)
+	mux.With(app.requireAction(auth.ActionUnpark)).Post("/api/parking-reservations/{parkingSpaceReservationsID}/payments", app.PayParkingSpaceReservation)
+
+	// grants are checked against the parking lot of the URL
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireRole(auth.Attendant))
+
+		mux.Post("/api/parking-lots/{parkinglotID}/parking-spaces/{parkingspaceID}/maintanance", app.ParkingSpaceMaintanance)
+		mux.Get("/api/parking-lots/{parkinglotID}/reservations", app.GetParkingLotReservations)
+	})
+
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireRole(auth.Manager))
+
+		mux.Patch("/api/parking-lots/{parkinglotID}", app.UpdateParkingLot)
+		mux.Post("/api/parking-lots/{parkinglotID}/parking-spaces", app.CreateParkingSpaces)
+		mux.Post("/api/parking-lots/{parkinglotID}/parking-spaces/bulk", app.CreateParkingSpacesBulk)
+		mux.Patch("/api/parking-lots/{parkinglotID}/parking-spaces/{parkingspaceID}", app.UpdateParkingSpace)
+		mux.Delete("/api/parking-lots/{parkinglotID}/parking-spaces/{parkingspaceID}", app.DecommissionParkingSpace)
+		mux.Get("/api/parking-lots/{parkinglotID}/reports/daily", app.GetDailyReport)
+		mux.Put("/api/parking-lots/{parkinglotID}/rate-plan", app.UpdateRatePlan)
+		mux.Post("/api/parking-lots/{parkinglotID}/closures", app.CreateClosure)
+		mux.Delete("/api/parking-lots/{parkinglotID}/closures/{closureID}", app.DeleteClosure)
+
+		mux.Post("/api/parking-lots/{parkinglotID}/levels", app.CreateLevel)
+		mux.Patch("/api/parking-lots/{parkinglotID}/levels/{levelID}", app.UpdateLevel)
+		mux.Delete("/api/parking-lots/{parkinglotID}/levels/{levelID}", app.DeleteLevel)
+		mux.Post("/api/parking-lots/{parkinglotID}/levels/{levelID}/zones", app.CreateZone)
+		mux.Patch("/api/parking-lots/{parkinglotID}/levels/{levelID}/zones/{zoneID}", app.UpdateZone)
+		mux.Delete("/api/parking-lots/{parkinglotID}/levels/{levelID}/zones/{zoneID}", app.DeleteZone)
+	})
+
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireRole(auth.Admin))
+
+		mux.Post("/api/parking-lots", app.CreateParkingLots)
+		mux.Delete("/api/parking-lots/{parkinglotID}", app.DeleteParkingLot)
+
+		mux.Get("/api/users/{userID}/grants", app.GetUserGrants)
+		mux.Post("/api/users/{userID}/grants", app.CreateGrant)
+		mux.Delete("/api/grants/{grantID}", app.DeleteGrant)
+
+		mux.Get("/api/api-keys", app.GetAPIKeys)
+		mux.Post("/api/api-keys", app.CreateAPIKey)
+		mux.Post("/api/api-keys/{apiKeyID}/rotate", app.RotateAPIKey)
+		mux.Delete("/api/api-keys/{apiKeyID}", app.RevokeAPIKey)
 
-	// TODO: implement feature The Parking Manager should be able to get the total number of vehicles parked on any day, total parking time and the total fee collected on
+		mux.Get("/api/admin/job-runs", app.GetJobRuns)
+	})
 
 	return mux
 }
@@ -0,0 +1,339 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/payment"
+)
+
+// AdjustmentKind is how an adjustment corrects the fee of a reservation.
+type AdjustmentKind string
+
+const (
+	// AdjustmentRefund returns money of the captured payment of the reservation.
+	AdjustmentRefund AdjustmentKind = "refund"
+	// AdjustmentWaiver lowers what is left to pay of an unpaid fee.
+	AdjustmentWaiver AdjustmentKind = "waiver"
+)
+
+func (k AdjustmentKind) Valid() bool {
+	return k == AdjustmentRefund || k == AdjustmentWaiver
+}
+
+// AdjustmentReason is why a fee was corrected.
+type AdjustmentReason string
+
+const (
+	ReasonBrokenEquipment   AdjustmentReason = "broken_equipment"
+	ReasonCustomerComplaint AdjustmentReason = "customer_complaint"
+	ReasonBillingError      AdjustmentReason = "billing_error"
+	ReasonGoodwill          AdjustmentReason = "goodwill"
+)
+
+func (r AdjustmentReason) Valid() bool {
+	switch r {
+	case ReasonBrokenEquipment, ReasonCustomerComplaint, ReasonBillingError, ReasonGoodwill:
+		return true
+	}
+
+	return false
+}
+
+// AdjustmentStatus is whether an adjustment took effect. A waiver is done when
+// it is made, a refund is pending until the payment gateway returned the money.
+type AdjustmentStatus string
+
+const (
+	AdjustmentPending AdjustmentStatus = "pending"
+	AdjustmentDone    AdjustmentStatus = "done"
+	AdjustmentFailed  AdjustmentStatus = "failed"
+)
+
+// Adjustment lowers the fee of an unparked reservation by Amount, the fee of
+// the reservation itself is not changed so reports can tell gross fees from
+// adjustments.
+type Adjustment struct {
+	ID                        int              `json:"id"`
+	ParkingSpaceReservationID int              `json:"parking_space_reservation_id"`
+	PaymentID                 *int             `json:"payment_id"` // the refunded payment
+	UserID                    int              `json:"user_id"`    // the manager who made it
+	Kind                      AdjustmentKind   `json:"kind"`
+	Reason                    AdjustmentReason `json:"reason"`
+	Note                      string           `json:"note"`
+	Amount                    int              `json:"amount"`
+	Status                    AdjustmentStatus `json:"status"`
+	FailureReason             string           `json:"failure_reason"`
+	CreatedAt                 time.Time        `json:"created_at"`
+}
+
+// Waived returns how much of a fee adjustments waived.
+func Waived(adjustments []Adjustment) int {
+	var waived int
+	for _, a := range adjustments {
+		if a.Kind == AdjustmentWaiver {
+			waived += a.Amount
+		}
+	}
+
+	return waived
+}
+
+// CreateAdjustment records an adjustment of an unparked reservation. A refund
+// is recorded pending and added to the refunded amount of its captured payment
+// so concurrent refunds cannot exceed it, it is done or failed with
+// UpdateAdjustment once the payment gateway was asked to return the money.
+// ErrAlreadyPaid is returned for a waiver of a paid fee, ErrNotPaid for a
+// refund of an unpaid one and ErrAdjustmentTooLarge when the amount exceeds
+// what is left to waive or refund.
+func (d *DB) CreateAdjustment(a Adjustment) (Adjustment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	defer tx.Rollback()
+
+	// the lock serialises the adjustments of the reservation
+	var (
+		endTime sql.NullString
+		fee     int
+	)
+	err = tx.QueryRowContext(ctx, `SELECT end_time, fee FROM parking_space_reservations WHERE id = ? FOR UPDATE`,
+		a.ParkingSpaceReservationID).
+		Scan(&endTime, &fee)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	if !endTime.Valid {
+		return Adjustment{}, ErrReservationActive
+	}
+
+	a.PaymentID, a.FailureReason = nil, ""
+	switch a.Kind {
+	case AdjustmentWaiver:
+		a.Status = AdjustmentDone
+		err = checkWaiver(ctx, tx, a, fee)
+	case AdjustmentRefund:
+		a.Status = AdjustmentPending
+		a.PaymentID, err = refundPayment(ctx, tx, a)
+	}
+	if err != nil {
+		return Adjustment{}, err
+	}
+
+	a.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	stmt, err := tx.PrepareContext(ctx, `insert into adjustments (
+																				parking_space_reservations_id, payments_id, user_id, kind, reason, note,
+																				amount, status, failure_reason, created_at
+																			) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, a.ParkingSpaceReservationID, intPtrNull(a.PaymentID), a.UserID, a.Kind,
+		a.Reason, a.Note, a.Amount, a.Status, a.FailureReason, a.CreatedAt.Format(dateFormat))
+	if err != nil {
+		return Adjustment{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Adjustment{}, err
+	}
+	a.ID = int(id)
+
+	if err = tx.Commit(); err != nil {
+		return Adjustment{}, err
+	}
+
+	return a, nil
+}
+
+func checkWaiver(ctx context.Context, tx *sql.Tx, a Adjustment, fee int) error {
+	var paid int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(id) FROM payments
+																	WHERE parking_space_reservations_id = ? and status in (?, ?)`,
+		a.ParkingSpaceReservationID, payment.Captured, payment.Refunded).
+		Scan(&paid)
+	if err != nil {
+		return err
+	}
+	if paid > 0 {
+		return ErrAlreadyPaid
+	}
+
+	var waived int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM adjustments
+																	WHERE parking_space_reservations_id = ? and kind = ?`,
+		a.ParkingSpaceReservationID, AdjustmentWaiver).
+		Scan(&waived)
+	if err != nil {
+		return err
+	}
+	if a.Amount > fee-waived {
+		return ErrAdjustmentTooLarge
+	}
+
+	return nil
+}
+
+// refundPayment adds the amount of a refund to the captured payment of its
+// reservation and returns the ID of the payment.
+func refundPayment(ctx context.Context, tx *sql.Tx, a Adjustment) (*int, error) {
+	var id, amount, refunded int
+	err := tx.QueryRowContext(ctx, `SELECT id, amount, refunded_amount FROM payments
+																	WHERE parking_space_reservations_id = ? and status in (?, ?)
+																	order by id desc
+																	limit 1
+																	FOR UPDATE`,
+		a.ParkingSpaceReservationID, payment.Captured, payment.Refunded).
+		Scan(&id, &amount, &refunded)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotPaid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.Amount > amount-refunded {
+		return nil, ErrAdjustmentTooLarge
+	}
+
+	status := payment.Captured
+	if refunded+a.Amount == amount {
+		status = payment.Refunded
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET refunded_amount = refunded_amount + ?, status = ?, updated_at = ?
+																WHERE (id = ?)`,
+		a.Amount, status, time.Now().UTC().Format(dateFormat), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// UpdateAdjustment saves the status and failure reason of a pending
+// adjustment, the amount of a failed refund is taken off the refunded amount of
+// its payment again. sql.ErrNoRows is returned when the adjustment is not
+// pending.
+func (d *DB) UpdateAdjustment(a Adjustment) (Adjustment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE adjustments SET status = ?, failure_reason = ?
+																			WHERE (id = ? and status = ?)`,
+		a.Status, a.FailureReason, a.ID, AdjustmentPending)
+	if err != nil {
+		return Adjustment{}, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return Adjustment{}, err
+	}
+	if affected != 1 {
+		return Adjustment{}, sql.ErrNoRows
+	}
+
+	if a.Status == AdjustmentFailed && a.Kind == AdjustmentRefund && a.PaymentID != nil {
+		_, err = tx.ExecContext(ctx, `UPDATE payments SET refunded_amount = refunded_amount - ?, status = ?, updated_at = ?
+																		WHERE (id = ?)`,
+			a.Amount, payment.Captured, time.Now().UTC().Format(dateFormat), *a.PaymentID)
+		if err != nil {
+			return Adjustment{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Adjustment{}, err
+	}
+
+	return a, nil
+}
+
+// GetAdjustmentsByReservation returns the adjustments of a reservation, the
+// latest last.
+func (d *DB) GetAdjustmentsByReservation(parkingSpaceReservationsID int) ([]Adjustment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, parking_space_reservations_id, payments_id, user_id, kind,
+																						 reason, note, amount, status, failure_reason, created_at
+																						 FROM adjustments
+																						 WHERE parking_space_reservations_id = ?
+																						 order by id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, parkingSpaceReservationsID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAdjustments(rows)
+}
+
+// GetPendingRefunds returns the refunds created before createdBefore that are
+// still pending, the oldest first.
+func (d *DB) GetPendingRefunds(createdBefore time.Time) ([]Adjustment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, parking_space_reservations_id, payments_id, user_id, kind,
+																						 reason, note, amount, status, failure_reason, created_at
+																						 FROM adjustments
+																						 WHERE status = ? and kind = ? and created_at < ?
+																						 order by id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, AdjustmentPending, AdjustmentRefund, createdBefore.UTC().Format(dateFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAdjustments(rows)
+}
+
+func scanAdjustments(rows *sql.Rows) ([]Adjustment, error) {
+	adjustments := []Adjustment{}
+	for rows.Next() {
+		var (
+			a         Adjustment
+			paymentID sql.NullInt64
+			createdAt string
+		)
+		err := rows.Scan(&a.ID, &a.ParkingSpaceReservationID, &paymentID, &a.UserID, &a.Kind,
+			&a.Reason, &a.Note, &a.Amount, &a.Status, &a.FailureReason, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		a.PaymentID = nullIntPtr(paymentID)
+		if a.CreatedAt, err = time.Parse(dateFormat, createdAt); err != nil {
+			return nil, err
+		}
+
+		adjustments = append(adjustments, a)
+	}
+
+	return adjustments, rows.Err()
+}
@@ -0,0 +1,366 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+)
+
+// APIKey lets a machine client such as a gate controller do the actions in the
+// parking lots it is scoped to. Only the hash of its secret is stored.
+type APIKey struct {
+	ID            int           `json:"id"`
+	Name          string        `json:"name"`
+	KeyID         string        `json:"key_id"`
+	SecretHash    string        `json:"-"`
+	ParkingLotIDs []int         `json:"parking_lot_ids"`
+	Actions       []auth.Action `json:"actions"`
+	CreatedAt     time.Time     `json:"created_at"`
+	ExpiresAt     *time.Time    `json:"expires_at"` // set once the key is rotated
+	RevokedAt     *time.Time    `json:"revoked_at"`
+	LastUsedAt    *time.Time    `json:"last_used_at"`
+}
+
+// Active reports whether the key is neither revoked nor expired.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Allows reports whether the key may do the action in the parking lot.
+func (k APIKey) Allows(action auth.Action, parkingLotID int) bool {
+	var actionAllowed, lotAllowed bool
+	for _, a := range k.Actions {
+		actionAllowed = actionAllowed || a == action
+	}
+	for _, id := range k.ParkingLotIDs {
+		lotAllowed = lotAllowed || id == parkingLotID
+	}
+
+	return actionAllowed && lotAllowed
+}
+
+// CreateAPIKey returns sql.ErrNoRows when one of the parking lots does not
+// exist.
+func (d *DB) CreateAPIKey(k APIKey) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := createAPIKey(ctx, tx, k, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func createAPIKey(ctx context.Context, tx *sql.Tx, k APIKey, now time.Time) (int64, error) {
+	actions := make([]string, len(k.Actions))
+	for i, a := range k.Actions {
+		actions[i] = string(a)
+	}
+
+	result, err := tx.ExecContext(ctx, `insert into api_keys (name, key_id, secret_hash, actions, created_at) values (?, ?, ?, ?, ?)`,
+		k.Name, k.KeyID, k.SecretHash, strings.Join(actions, ","), now.Format(dateFormat))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `insert into api_key_parking_lots (api_keys_id, parking_lots_id) values (?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, parkingLotID := range k.ParkingLotIDs {
+		_, err := stmt.ExecContext(ctx, id, parkingLotID)
+		if isMissingReference(err) {
+			return 0, sql.ErrNoRows
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+const apiKeyColumns = `id, name, key_id, secret_hash, actions, created_at, expires_at, revoked_at, last_used_at`
+
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (APIKey, error) {
+	var (
+		k                                APIKey
+		actions, createdAt               string
+		expiresAt, revokedAt, lastUsedAt sql.NullString
+	)
+	if err := row.Scan(&k.ID, &k.Name, &k.KeyID, &k.SecretHash, &actions, &createdAt, &expiresAt, &revokedAt, &lastUsedAt); err != nil {
+		return APIKey{}, err
+	}
+
+	k.Actions = []auth.Action{}
+	for _, a := range strings.Split(actions, ",") {
+		if a != "" {
+			k.Actions = append(k.Actions, auth.Action(a))
+		}
+	}
+
+	var err error
+	if k.CreatedAt, err = time.Parse(dateFormat, createdAt); err != nil {
+		return APIKey{}, err
+	}
+	if k.ExpiresAt, err = parseNullTime(expiresAt); err != nil {
+		return APIKey{}, err
+	}
+	if k.RevokedAt, err = parseNullTime(revokedAt); err != nil {
+		return APIKey{}, err
+	}
+	if k.LastUsedAt, err = parseNullTime(lastUsedAt); err != nil {
+		return APIKey{}, err
+	}
+
+	return k, nil
+}
+
+func parseNullTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	t, err := time.Parse(dateFormat, s.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// getAPIKeyParkingLots returns the parking lot IDs of every key in keys.
+func getAPIKeyParkingLots(ctx context.Context, q preparer, keys []APIKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	args := make([]any, len(keys))
+	for i, k := range keys {
+		args[i] = k.ID
+	}
+
+	stmt, err := q.PrepareContext(ctx, `SELECT api_keys_id, parking_lots_id
+																			FROM api_key_parking_lots
+																			WHERE api_keys_id IN (?`+strings.Repeat(", ?", len(keys)-1)+`)
+																			order by parking_lots_id asc`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	parkingLotIDs := map[int][]int{}
+	for rows.Next() {
+		var apiKeyID, parkingLotID int
+		if err := rows.Scan(&apiKeyID, &parkingLotID); err != nil {
+			return err
+		}
+
+		parkingLotIDs[apiKeyID] = append(parkingLotIDs[apiKeyID], parkingLotID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range keys {
+		keys[i].ParkingLotIDs = parkingLotIDs[keys[i].ID]
+		if keys[i].ParkingLotIDs == nil {
+			keys[i].ParkingLotIDs = []int{}
+		}
+	}
+
+	return nil
+}
+
+func (d *DB) GetAPIKeys() ([]APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT `+apiKeyColumns+`
+																						 FROM api_keys
+																						 order by id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := getAPIKeyParkingLots(ctx, d.dbConn, keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (d *DB) GetAPIKeyByID(id int) (APIKey, error) {
+	return d.getAPIKey(`id = ?`, id)
+}
+
+// GetAPIKeyByKeyID looks a key up by the public part of it.
+func (d *DB) GetAPIKeyByKeyID(keyID string) (APIKey, error) {
+	return d.getAPIKey(`key_id = ?`, keyID)
+}
+
+func (d *DB) getAPIKey(where string, arg any) (APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT `+apiKeyColumns+`
+																						 FROM api_keys
+																						 WHERE `+where)
+	if err != nil {
+		return APIKey{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, arg)
+	if row == nil {
+		return APIKey{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	k, err := scanAPIKey(row)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	keys := []APIKey{k}
+	if err := getAPIKeyParkingLots(ctx, d.dbConn, keys); err != nil {
+		return APIKey{}, err
+	}
+
+	return keys[0], nil
+}
+
+// RotateAPIKey replaces an active key by a new one with the same name and
+// scopes, k only needs the key ID and secret hash. The old key keeps working
+// until overlapUntil so clients can be switched over. ErrAPIKeyInactive is
+// returned when the old key is revoked or expired.
+func (d *DB) RotateAPIKey(id int, k APIKey, overlapUntil time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	old, err := scanAPIKey(tx.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ? FOR UPDATE`, id))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	if !old.Active(now) {
+		return 0, ErrAPIKeyInactive
+	}
+
+	keys := []APIKey{old}
+	if err := getAPIKeyParkingLots(ctx, tx, keys); err != nil {
+		return 0, err
+	}
+
+	k.Name, k.Actions, k.ParkingLotIDs = old.Name, old.Actions, keys[0].ParkingLotIDs
+	newID, err := createAPIKey(ctx, tx, k, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// a key rotated twice expires with the earlier overlap
+	if old.ExpiresAt == nil || overlapUntil.Before(*old.ExpiresAt) {
+		_, err = tx.ExecContext(ctx, `UPDATE api_keys SET expires_at = ? WHERE (id = ?)`, overlapUntil.UTC().Format(dateFormat), id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return newID, nil
+}
+
+// RevokeAPIKey stops a key from working immediately, revoking it again keeps
+// the first revocation time.
+func (d *DB) RevokeAPIKey(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE (id = ?) and revoked_at IS NULL`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, time.Now().UTC().Format(dateFormat), id)
+
+	return err
+}
+
+// TouchAPIKey records when a key was last used.
+func (d *DB) TouchAPIKey(id int, usedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, usedAt.UTC().Format(dateFormat), id)
+
+	return err
+}
@@ -0,0 +1,318 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/allocation"
+	"github.com/arifmahmudrana/parking-lot/space"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+type BookingStatus string
+
+const (
+	BookingHeld     BookingStatus = "held"
+	BookingArrived  BookingStatus = "arrived"
+	BookingReleased BookingStatus = "released" // the driver did not arrive
+)
+
+// Booking holds a parking space for a future window. It turns into a parking
+// space reservation when the driver arrives.
+type Booking struct {
+	ID                        int            `json:"id"`
+	ParkingLotID              int            `json:"parking_lot_id"`
+	ParkingSpaceID            int            `json:"parking_space_id"`
+	UserID                    int            `json:"user_id"`
+	Plate                     string         `json:"plate"` // of a registered vehicle, optional
+	VehicleType               vehicle.Type   `json:"vehicle_type"`
+	Features                  space.Features `json:"features"`
+	StartTime                 time.Time      `json:"start_time"`
+	EndTime                   time.Time      `json:"end_time"`
+	Status                    BookingStatus  `json:"status"`
+	ParkingSpaceReservationID *int           `json:"parking_space_reservation_id"`
+
+	// NoShowFee is what a released booking owes, it is recorded for the
+	// operator to collect and not charged.
+	NoShowFee int `json:"no_show_fee"`
+}
+
+func (b Booking) parkRequest() ParkRequest {
+	return ParkRequest{
+		UserID:      b.UserID,
+		Plate:       b.Plate,
+		VehicleType: b.VehicleType,
+		Features:    b.Features,
+	}
+}
+
+// CreateBooking holds a parking space of a parking lot for the window of b.
+// The space must be available now and not held by another booking, it is kept
+// from walk-ins and other bookings until the booking is arrived at or released
+// so it is free when the driver arrives. ErrBookingConflict is returned when
+// there is no such space and the errors of lookupVehicle for the plate, the
+// vehicle may be parked elsewhere until it arrives.
+func (d *DB) CreateBooking(parkingLotID int, b Booking) (Booking, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return Booking{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := lookupVehicle(ctx, tx, b.parkRequest())
+	if err != nil {
+		return Booking{}, err
+	}
+	b.VehicleType = pr.VehicleType
+
+	la, err := lockParkingLotAllocation(ctx, tx, parkingLotID)
+	if err != nil {
+		return Booking{}, err
+	}
+
+	candidates, err := getAllocationCandidates(ctx, tx, parkingLotID, la, pr)
+	if err != nil {
+		return Booking{}, err
+	}
+
+	c, ok := allocation.Allocate(la.strategy, candidates, la.request(pr))
+	if !ok {
+		return Booking{}, ErrBookingConflict
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `insert into bookings (
+																				parking_lots_id, parking_spaces_id, user_id, plate, vehicle_type, features, start_time, end_time, status
+																			) values (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return Booking{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx,
+		parkingLotID, c.ID, b.UserID, b.Plate, b.VehicleType, b.Features,
+		b.StartTime.Format(dateFormat), b.EndTime.Format(dateFormat), BookingHeld,
+	)
+	if err != nil {
+		return Booking{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Booking{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Booking{}, err
+	}
+
+	b.ID = int(id)
+	b.ParkingLotID = parkingLotID
+	b.ParkingSpaceID = c.ID
+	b.Status = BookingHeld
+	b.ParkingSpaceReservationID = nil
+	b.NoShowFee = 0
+
+	return b, nil
+}
+
+const bookingColumns = `bookings.id, bookings.parking_lots_id, parking_spaces_id, user_id, plate, vehicle_type, features,
+												start_time, end_time, status, parking_space_reservations_id, bookings.no_show_fee`
+
+func scanBooking(row interface{ Scan(dest ...any) error }, extra ...any) (Booking, error) {
+	var (
+		b                  Booking
+		startTime, endTime string
+		psrID              sql.NullInt64
+	)
+	dest := append([]any{
+		&b.ID, &b.ParkingLotID, &b.ParkingSpaceID, &b.UserID, &b.Plate, &b.VehicleType, &b.Features,
+		&startTime, &endTime, &b.Status, &psrID, &b.NoShowFee,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return Booking{}, err
+	}
+
+	var err error
+	if b.StartTime, err = time.Parse(dateFormat, startTime); err != nil {
+		return Booking{}, err
+	}
+	if b.EndTime, err = time.Parse(dateFormat, endTime); err != nil {
+		return Booking{}, err
+	}
+	b.ParkingSpaceReservationID = nullIntPtr(psrID)
+
+	return b, nil
+}
+
+func (d *DB) GetBookingByID(id int) (Booking, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT `+bookingColumns+`
+																						 FROM bookings
+																						 WHERE id = ?`)
+	if err != nil {
+		return Booking{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, id)
+	if row == nil {
+		return Booking{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return Booking{}, err
+	}
+
+	return scanBooking(row)
+}
+
+// ArriveBooking turns a held booking into a parking space reservation. The
+// driver may arrive from bookingLeadTime before the start of the booking until
+// the grace period of the parking lot has passed, ErrBookingTooEarly is
+// returned before and ErrBookingNotHeld after that. The held space is only
+// unavailable when it was put under maintenance, the driver then gets the next
+// available space like a walk-in and ErrLotFull is returned when there is none. ErrLotClosed is returned while the parking lot
+// is closed and the errors of resolveVehicle for the plate of the booking.
+func (d *DB) ArriveBooking(id int) (int64, Location, error) {
+	var (
+		psrID int64
+		loc   Location
+		err   error
+	)
+	for i := 0; i < parkRetries; i++ {
+		psrID, loc, err = d.arriveBooking(id)
+		if !isRetryable(err) {
+			break
+		}
+	}
+
+	return psrID, loc, err
+}
+
+func (d *DB) arriveBooking(id int) (int64, Location, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, Location{}, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `SELECT `+bookingColumns+`, parking_lots.booking_grace_period
+																			 FROM bookings
+																			 INNER JOIN parking_lots ON parking_lots.id = bookings.parking_lots_id
+																			 WHERE bookings.id = ?
+																			 FOR UPDATE OF bookings`)
+	if err != nil {
+		return 0, Location{}, err
+	}
+	defer stmt.Close()
+
+	var gracePeriod int
+	b, err := scanBooking(stmt.QueryRowContext(ctx, id), &gracePeriod)
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	now := time.Now().UTC()
+	if b.Status != BookingHeld {
+		return 0, Location{}, ErrBookingNotHeld
+	}
+	if isNoShow(b, now, time.Duration(gracePeriod)*time.Minute) {
+		if _, err := releaseNoShowBookings(ctx, tx, now); err != nil {
+			return 0, Location{}, err
+		}
+		if err = tx.Commit(); err != nil {
+			return 0, Location{}, err
+		}
+
+		return 0, Location{}, ErrBookingNotHeld
+	}
+	if now.Before(b.StartTime.Add(-bookingLeadTime)) {
+		return 0, Location{}, ErrBookingTooEarly
+	}
+
+	pr, err := resolveVehicle(ctx, tx, b.parkRequest())
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	if err := checkParkingLotOpen(ctx, tx, b.ParkingLotID, now); err != nil {
+		return 0, Location{}, err
+	}
+
+	psrID, loc, err := occupyParkingSpace(ctx, tx, b.ParkingSpaceID, pr)
+	if err == errParkingSpaceTaken {
+		var parkingspaceID int
+		parkingspaceID, err = getNextParkingSpaceByParkingLot(ctx, tx, b.ParkingLotID, pr)
+		if err != nil {
+			return 0, Location{}, err
+		}
+
+		psrID, loc, err = occupyParkingSpace(ctx, tx, parkingspaceID, pr)
+	}
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	stmt, err = tx.PrepareContext(ctx, `UPDATE bookings SET status = ?, parking_space_reservations_id = ? WHERE (id = ?)`)
+	if err != nil {
+		return 0, Location{}, err
+	}
+	defer stmt.Close()
+
+	if _, err = stmt.ExecContext(ctx, BookingArrived, psrID, b.ID); err != nil {
+		return 0, Location{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, Location{}, err
+	}
+
+	return psrID, loc, nil
+}
+
+// isNoShow reports whether the driver of a held booking can no longer arrive.
+func isNoShow(b Booking, now time.Time, gracePeriod time.Duration) bool {
+	return now.After(b.StartTime.Add(gracePeriod)) || !now.Before(b.EndTime)
+}
+
+// ReleaseNoShowBookings releases the held bookings whose driver did not arrive
+// within the grace period of the parking lot and records its no show fee on
+// them. It returns the number of released bookings.
+func (d *DB) ReleaseNoShowBookings() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return releaseNoShowBookings(ctx, d.dbConn, time.Now().UTC())
+}
+
+func releaseNoShowBookings(ctx context.Context, q preparer, now time.Time) (int64, error) {
+	stmt, err := q.PrepareContext(ctx, `UPDATE bookings
+																			INNER JOIN parking_lots ON parking_lots.id = bookings.parking_lots_id
+																			SET bookings.status = ?, bookings.no_show_fee = parking_lots.no_show_fee
+																			WHERE bookings.status = ?
+																			and (
+																				bookings.start_time < DATE_SUB(?, INTERVAL parking_lots.booking_grace_period MINUTE)
+																				or bookings.end_time <= ?
+																			)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, BookingReleased, BookingHeld, now.Format(dateFormat), now.Format(dateFormat))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
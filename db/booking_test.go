@@ -0,0 +1,200 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/allocation"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// createTestParkingLot creates a parking lot with regular parking spaces.
+func createTestParkingLot(t *testing.T, repo Repository, spaces int) int {
+	t.Helper()
+
+	id, err := repo.CreateParkingLot(ParkingLot{
+		Name:               "bookings",
+		TimeZone:           "UTC",
+		AllocationStrategy: allocation.FirstAvailable,
+		BookingGracePeriod: 15,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < spaces; i++ {
+		if _, err := repo.CreateParkingSpaceFromParkingLotID(int(id), ParkingSpace{SizeClass: vehicle.SizeRegular}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return int(id)
+}
+
+func testBooking(start, end time.Duration) Booking {
+	now := time.Now().UTC().Truncate(time.Second)
+	return Booking{
+		VehicleType: vehicle.Car,
+		StartTime:   now.Add(start),
+		EndTime:     now.Add(end),
+	}
+}
+
+func TestCreateBookingConflicts(t *testing.T) {
+	for name, repo := range testRepositories(t) {
+		repo := repo
+		t.Run(name, func(t *testing.T) {
+			parkingLotID := createTestParkingLot(t, repo, 2)
+
+			first, err := repo.CreateBooking(parkingLotID, testBooking(30*time.Minute, 2*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the windows do not overlap, a space is held by one booking at a time
+			second, err := repo.CreateBooking(parkingLotID, testBooking(24*time.Hour, 26*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if second.ParkingSpaceID == first.ParkingSpaceID {
+				t.Errorf("bookings %d and %d hold the same parking space %d", first.ID, second.ID, first.ParkingSpaceID)
+			}
+
+			if _, err := repo.CreateBooking(parkingLotID, testBooking(48*time.Hour, 50*time.Hour)); err != ErrBookingConflict {
+				t.Errorf("booking a parking lot held by bookings: error = %v, want %v", err, ErrBookingConflict)
+			}
+			// the booking of tomorrow keeps walk-ins off its space today
+			if _, _, err := repo.ParkParkingSpaceByParkingLot(parkingLotID, ParkRequest{VehicleType: vehicle.Car}); err != ErrLotFull {
+				t.Errorf("parking in a parking lot held by bookings: error = %v, want %v", err, ErrLotFull)
+			}
+		})
+	}
+}
+
+func TestCreateBookingOccupiedParkingLot(t *testing.T) {
+	for name, repo := range testRepositories(t) {
+		repo := repo
+		t.Run(name, func(t *testing.T) {
+			parkingLotID := createTestParkingLot(t, repo, 1)
+			if _, _, err := repo.ParkParkingSpaceByParkingLot(parkingLotID, ParkRequest{VehicleType: vehicle.Car}); err != nil {
+				t.Fatal(err)
+			}
+
+			// the parked vehicle may still be there tomorrow
+			if _, err := repo.CreateBooking(parkingLotID, testBooking(24*time.Hour, 26*time.Hour)); err != ErrBookingConflict {
+				t.Errorf("booking an occupied parking lot: error = %v, want %v", err, ErrBookingConflict)
+			}
+		})
+	}
+}
+
+func TestArriveBookingFullParkingLot(t *testing.T) {
+	for name, repo := range testRepositories(t) {
+		repo := repo
+		t.Run(name, func(t *testing.T) {
+			parkingLotID := createTestParkingLot(t, repo, 2)
+
+			early, err := repo.CreateBooking(parkingLotID, testBooking(3*time.Hour, 5*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := repo.ArriveBooking(early.ID); err != ErrBookingTooEarly {
+				t.Errorf("arriving 3 hours early: error = %v, want %v", err, ErrBookingTooEarly)
+			}
+
+			b, err := repo.CreateBooking(parkingLotID, testBooking(0, 2*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := repo.ParkParkingSpaceByParkingLot(parkingLotID, ParkRequest{VehicleType: vehicle.Car}); err != ErrLotFull {
+				t.Fatalf("parking in a parking lot held by bookings: error = %v, want %v", err, ErrLotFull)
+			}
+
+			psrID, loc, err := repo.ArriveBooking(b.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loc.ParkingSpaceID != b.ParkingSpaceID {
+				t.Errorf("arrived at parking space %d, want the held parking space %d", loc.ParkingSpaceID, b.ParkingSpaceID)
+			}
+
+			arrived, err := repo.GetBookingByID(b.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if arrived.Status != BookingArrived || arrived.ParkingSpaceReservationID == nil || *arrived.ParkingSpaceReservationID != int(psrID) {
+				t.Errorf("arrived booking = %s reservation %v, want %s reservation %d",
+					arrived.Status, arrived.ParkingSpaceReservationID, BookingArrived, psrID)
+			}
+			if _, _, err := repo.ArriveBooking(b.ID); err != ErrBookingNotHeld {
+				t.Errorf("arriving twice: error = %v, want %v", err, ErrBookingNotHeld)
+			}
+		})
+	}
+}
+
+func TestBookingVehicle(t *testing.T) {
+	for name, repo := range testRepositories(t) {
+		repo := repo
+		t.Run(name, func(t *testing.T) {
+			parkingLotID := createTestParkingLot(t, repo, 2)
+			otherParkingLotID := createTestParkingLot(t, repo, 1)
+
+			// plates are unique, the MySQL database is not cleaned
+			plate := fmt.Sprintf("B%d", time.Now().UnixNano()%1e12)
+			const userID = 7
+			vehicleID, err := repo.CreateVehicle(Vehicle{Plate: plate, UserID: userID, VehicleType: vehicle.Motorcycle})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			booking := func(plate string, userID int) Booking {
+				b := testBooking(0, 2*time.Hour)
+				b.Plate, b.UserID = plate, userID
+				return b
+			}
+			if _, err := repo.CreateBooking(parkingLotID, booking(plate+"X", userID)); err != ErrVehicleNotRegistered {
+				t.Errorf("booking for an unknown plate: error = %v, want %v", err, ErrVehicleNotRegistered)
+			}
+			if _, err := repo.CreateBooking(parkingLotID, booking(plate, userID+1)); err != ErrVehicleNotOwned {
+				t.Errorf("booking for the vehicle of another user: error = %v, want %v", err, ErrVehicleNotOwned)
+			}
+
+			// the vehicle may be parked elsewhere until it arrives
+			psrID, _, err := repo.ParkParkingSpaceByParkingLot(otherParkingLotID, ParkRequest{UserID: userID, Plate: plate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := booking(plate, userID)
+			b.VehicleType = ""
+			b, err = repo.CreateBooking(parkingLotID, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.VehicleType != vehicle.Motorcycle || b.Plate != plate {
+				t.Errorf("booking = %s %s, want the registered %s %s", b.Plate, b.VehicleType, plate, vehicle.Motorcycle)
+			}
+			if _, _, err := repo.ArriveBooking(b.ID); err != ErrVehicleParked {
+				t.Fatalf("arriving with a parked vehicle: error = %v, want %v", err, ErrVehicleParked)
+			}
+
+			if _, _, err := repo.UnParkParkingSpaceByID(int(psrID), false); err != nil {
+				t.Fatal(err)
+			}
+			arrivedID, _, err := repo.ArriveBooking(b.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cp, err := repo.GetCurrentParkingByVehicle(int(vehicleID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cp.ParkingSpaceReservationID != int(arrivedID) {
+				t.Errorf("vehicle is parked in reservation %d, want the arrival %d", cp.ParkingSpaceReservationID, arrivedID)
+			}
+			// the arrival holds the vehicle like any other reservation
+			if _, _, err := repo.ParkParkingSpaceByParkingLot(otherParkingLotID, ParkRequest{UserID: userID, Plate: plate}); err != ErrVehicleParked {
+				t.Errorf("parking an arrived vehicle: error = %v, want %v", err, ErrVehicleParked)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type ClosureKind string
+
+const (
+	ClosureHoliday ClosureKind = "holiday" // whole local days of the parking lot
+	ClosureAdHoc   ClosureKind = "ad_hoc"
+)
+
+func (k ClosureKind) Valid() bool {
+	return k == ClosureHoliday || k == ClosureAdHoc
+}
+
+// Closure closes a parking lot from StartTime until EndTime regardless of its
+// opening hours.
+type Closure struct {
+	ID           int         `json:"id"`
+	ParkingLotID int         `json:"parking_lot_id"`
+	Kind         ClosureKind `json:"kind"`
+	Reason       string      `json:"reason"`
+	StartTime    time.Time   `json:"start_time"`
+	EndTime      time.Time   `json:"end_time"`
+}
+
+func (c Closure) contains(t time.Time) bool {
+	return !t.Before(c.StartTime) && t.Before(c.EndTime)
+}
+
+// OpenAt reports whether the parking lot is open at t, that is within its
+// opening hours and not closed by one of closures.
+func (pl ParkingLot) OpenAt(t time.Time, closures []Closure) (bool, error) {
+	for _, c := range closures {
+		if c.contains(t) {
+			return false, nil
+		}
+	}
+
+	loc, err := time.LoadLocation(pl.TimeZone)
+	if err != nil {
+		return false, err
+	}
+
+	return pl.OpeningHours.Open(t.In(loc)), nil
+}
+
+// CreateClosure closes a parking lot, sql.ErrNoRows is returned when the
+// parking lot does not exist.
+func (d *DB) CreateClosure(parkingLotID int, c Closure) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `insert into parking_lot_closures (
+																				parking_lots_id, kind, reason, start_time, end_time
+																			) values (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, parkingLotID, c.Kind, c.Reason,
+		c.StartTime.UTC().Format(dateFormat), c.EndTime.UTC().Format(dateFormat))
+	if isMissingReference(err) {
+		return 0, sql.ErrNoRows
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetClosuresByParkingLot returns the closures of a parking lot that end after
+// endingAfter, the earliest first.
+func (d *DB) GetClosuresByParkingLot(parkingLotID int, endingAfter time.Time) ([]Closure, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return getClosuresByParkingLot(ctx, d.dbConn, parkingLotID, endingAfter)
+}
+
+func getClosuresByParkingLot(ctx context.Context, q preparer, parkingLotID int, endingAfter time.Time) ([]Closure, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT id, parking_lots_id, kind, reason, start_time, end_time
+																						 FROM parking_lot_closures
+																						 WHERE parking_lots_id = ? and end_time > ?
+																						 order by start_time asc, id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, parkingLotID, endingAfter.UTC().Format(dateFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	closures := []Closure{}
+	for rows.Next() {
+		var (
+			c                  Closure
+			startTime, endTime string
+		)
+		if err := rows.Scan(&c.ID, &c.ParkingLotID, &c.Kind, &c.Reason, &startTime, &endTime); err != nil {
+			return nil, err
+		}
+
+		if c.StartTime, err = time.Parse(dateFormat, startTime); err != nil {
+			return nil, err
+		}
+		if c.EndTime, err = time.Parse(dateFormat, endTime); err != nil {
+			return nil, err
+		}
+		closures = append(closures, c)
+	}
+
+	return closures, rows.Err()
+}
+
+// DeleteClosure reopens a parking lot, sql.ErrNoRows is returned when the
+// closure is not one of the parking lot.
+func (d *DB) DeleteClosure(parkingLotID, id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `DELETE FROM parking_lot_closures WHERE (id = ? and parking_lots_id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, id, parkingLotID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// checkParkingLotOpen returns ErrLotClosed when the parking lot is closed at
+// now and ErrLotNotFound when it does not exist.
+func checkParkingLotOpen(ctx context.Context, tx *sql.Tx, parkingLotID int, now time.Time) error {
+	pl := ParkingLot{
+		ID: parkingLotID,
+	}
+	err := tx.QueryRowContext(ctx, `SELECT time_zone FROM parking_lots WHERE id = ? and archived_at IS NULL`, parkingLotID).
+		Scan(&pl.TimeZone)
+	if err == sql.ErrNoRows {
+		return ErrLotNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	open, err := parkingLotOpen(ctx, tx, pl, now)
+	if err != nil {
+		return err
+	}
+	if !open {
+		return ErrLotClosed
+	}
+
+	return nil
+}
+
+// parkingLotOpen reads the opening hours and closures of a parking lot and
+// reports whether it is open at now.
+func parkingLotOpen(ctx context.Context, q preparer, pl ParkingLot, now time.Time) (bool, error) {
+	var err error
+	pl.OpeningHours, err = getOpeningHoursByParkingLot(ctx, q, pl.ID)
+	if err != nil {
+		return false, err
+	}
+
+	closures, err := getClosuresByParkingLot(ctx, q, pl.ID, now)
+	if err != nil {
+		return false, err
+	}
+
+	return pl.OpenAt(now, closures)
+}
@@ -1,11 +1,12 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 type status int8
@@ -14,18 +15,65 @@ const (
 	maintanance status = iota
 	available
 	booked
+	decommissioned // kept for the history of its reservations
 
-	dbTimeout = time.Second * 3
-	size      = 10
+	dbTimeout   = time.Second * 3
+	size        = 10
+	parkRetries = 3
+
+	// bookingLeadTime is how long before its start a booking may be arrived at
+	bookingLeadTime = time.Hour
 
 	dateFormat = "2006-01-02 15:04:05"
 )
 
+// Error is an outcome of the domain rules as opposed to a failure of the
+// database, Code identifies it to clients.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrAlreadyUnparked = &Error{"already_unparked", "already unparked"}
+	ErrDuplicate       = &Error{"duplicate", "already exists"}
+	ErrInUse           = &Error{"in_use", "still in use"}
+	ErrBookingConflict = &Error{"booking_conflict", "no parking space is free for the booking window"}
+	ErrBookingNotHeld  = &Error{"booking_not_held", "booking is not held"}
+	ErrBookingTooEarly = &Error{"booking_too_early", "booking has not started yet"}
+	ErrVehicleParked   = &Error{"vehicle_parked", "vehicle is already parked"}
+	ErrAPIKeyInactive  = &Error{"api_key_inactive", "api key is revoked or expired"}
+	ErrLotNotFound     = &Error{"lot_not_found", "parking lot not found"}
+	ErrLotFull         = &Error{"lot_full", "no parking space is available for the vehicle"}
+	ErrLotCapacity     = &Error{"lot_capacity", "parking lot would exceed its capacity"}
+	ErrLotActive       = &Error{"lot_active", "parking lot has parked vehicles or held bookings"}
+	ErrLotClosed       = &Error{"lot_closed", "parking lot is closed"}
+
+	ErrReservationActive  = &Error{"reservation_active", "reservation has not been unparked"}
+	ErrAlreadyPaid        = &Error{"already_paid", "reservation is already paid"}
+	ErrNotPaid            = &Error{"not_paid", "reservation has no captured payment"}
+	ErrPaymentInProgress  = &Error{"payment_in_progress", "reservation is being paid"}
+	ErrAdjustmentTooLarge = &Error{"adjustment_too_large", "amount exceeds what is left to adjust"}
+
+	ErrVehicleNotRegistered = &Error{"vehicle_not_registered", "vehicle is not registered"}
+	ErrVehicleNotOwned      = &Error{"vehicle_not_owned", "vehicle belongs to another user"}
+)
+
 var (
 	ErrNilQueryRowContext = errors.New("no data")
-	ErrAlreadyUnparked    = errors.New("already unparked")
+
+	errParkingSpaceTaken = errors.New("parking space already taken")
 )
 
+// preparer is implemented by both *sql.DB and *sql.Tx
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
 type DB struct {
 	dbConn *sql.DB
 }
@@ -53,6 +101,45 @@ func getOffset(p int) int {
 	return (p - 1) * size
 }
 
+// isRetryable reports whether a transaction failed only because it raced with
+// another one and can be run again.
+func isRetryable(err error) bool {
+	if err == errParkingSpaceTaken {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		// ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+
+	return false
+}
+
+// isDuplicateEntry reports whether err is a unique key violation.
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	// ER_DUP_ENTRY
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// isForeignKeyViolation reports whether err is caused by deleting a row that is
+// still referenced.
+func isForeignKeyViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	// ER_ROW_IS_REFERENCED_2
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1451
+}
+
+// isMissingReference reports whether err is caused by inserting a row that
+// references a row that does not exist.
+func isMissingReference(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	// ER_NO_REFERENCED_ROW_2
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1452
+}
+
 func (s status) value() string {
 	switch s {
 	case maintanance:
@@ -61,7 +148,47 @@ func (s status) value() string {
 		return "AVAILABLE"
 	case booked:
 		return "BOOKED"
+	case decommissioned:
+		return "DECOMMISSIONED"
 	}
 
 	panic("NO_MATCH_FOUND")
 }
+
+// nullIntPtr converts a nullable column to a pointer that is nil for NULL.
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+
+	i := int(n.Int64)
+	return &i
+}
+
+// intPtrNull converts a pointer to a nullable column value.
+func intPtrNull(i *int) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(*i), Valid: true}
+}
+
+// nullFloatPtr converts a nullable column to a pointer that is nil for NULL.
+func nullFloatPtr(n sql.NullFloat64) *float64 {
+	if !n.Valid {
+		return nil
+	}
+
+	f := n.Float64
+	return &f
+}
+
+// floatPtrNull converts a pointer to a nullable column value.
+func floatPtrNull(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
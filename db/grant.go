@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/arifmahmudrana/parking-lot/auth"
+)
+
+// Grant gives a user a role for a parking lot, or for every parking lot when
+// ParkingLotID is nil.
+type Grant struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	Role         auth.Role `json:"role"`
+	ParkingLotID *int      `json:"parking_lot_id"`
+}
+
+// Allows reports whether the grant allows what role may do in the parking lot,
+// parkingLotID 0 asks for a global grant.
+func (g Grant) Allows(role auth.Role, parkingLotID int) bool {
+	if !g.Role.AtLeast(role) {
+		return false
+	}
+
+	return g.ParkingLotID == nil || (parkingLotID != 0 && *g.ParkingLotID == parkingLotID)
+}
+
+// CreateGrant returns sql.ErrNoRows when the user or parking lot does not
+// exist and ErrDuplicate when the user already has the grant.
+func (d *DB) CreateGrant(g Grant) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	grants, err := d.GetGrantsByUser(g.UserID)
+	if err != nil {
+		return 0, err
+	}
+	if hasGrant(grants, g) {
+		return 0, ErrDuplicate
+	}
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `insert into role_grants (users_id, role, parking_lots_id) values (?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, g.UserID, g.Role, intPtrNull(g.ParkingLotID))
+	if isMissingReference(err) {
+		return 0, sql.ErrNoRows
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func hasGrant(grants []Grant, g Grant) bool {
+	for _, other := range grants {
+		if other.Role != g.Role || (other.ParkingLotID == nil) != (g.ParkingLotID == nil) {
+			continue
+		}
+		if other.ParkingLotID == nil || *other.ParkingLotID == *g.ParkingLotID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *DB) GetGrantsByUser(userID int) ([]Grant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, users_id, role, parking_lots_id
+																						 FROM role_grants
+																						 WHERE users_id = ?
+																						 order by id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []Grant{}
+	for rows.Next() {
+		var (
+			g            Grant
+			parkingLotID sql.NullInt64
+		)
+		if err := rows.Scan(&g.ID, &g.UserID, &g.Role, &parkingLotID); err != nil {
+			return nil, err
+		}
+
+		g.ParkingLotID = nullIntPtr(parkingLotID)
+		grants = append(grants, g)
+	}
+
+	return grants, rows.Err()
+}
+
+func (d *DB) DeleteGrant(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `DELETE FROM role_grants WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	JobRunSucceeded = "succeeded"
+	JobRunFailed    = "failed"
+)
+
+// JobRun is the history of a background job run.
+type JobRun struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Status     string    `json:"status"`
+	Affected   int64     `json:"affected"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func (d *DB) CreateJobRun(jr JobRun) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `insert into job_runs (
+																				name, started_at, finished_at, status, affected, error
+																			) values (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx,
+		jr.Name, jr.StartedAt.UTC().Format(dateFormat), jr.FinishedAt.UTC().Format(dateFormat),
+		jr.Status, jr.Affected, sql.NullString{String: jr.Error, Valid: jr.Error != ""},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetJobRuns returns the job runs latest first.
+func (d *DB) GetJobRuns(page int) ([]JobRun, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, name, started_at, finished_at, status, affected, error
+																						 FROM job_runs
+																						 order by id desc
+																						 LIMIT ?, ?`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, getOffset(page), size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobRuns := make([]JobRun, 0, size)
+	for rows.Next() {
+		var (
+			jr                    JobRun
+			startedAt, finishedAt string
+			jobErr                sql.NullString
+		)
+		if err := rows.Scan(&jr.ID, &jr.Name, &startedAt, &finishedAt, &jr.Status, &jr.Affected, &jobErr); err != nil {
+			return nil, err
+		}
+
+		if jr.StartedAt, err = time.Parse(dateFormat, startedAt); err != nil {
+			return nil, err
+		}
+		if jr.FinishedAt, err = time.Parse(dateFormat, finishedAt); err != nil {
+			return nil, err
+		}
+		jr.Error = jobErr.String
+		jobRuns = append(jobRuns, jr)
+	}
+
+	return jobRuns, rows.Err()
+}
+
+func (d *DB) GetTotalCountJobRuns() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var count int
+	if err := d.dbConn.QueryRowContext(ctx, `select count(id) from job_runs`).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// DeleteJobRunsBefore deletes the job runs that finished before finishedBefore
+// and returns how many were deleted.
+func (d *DB) DeleteJobRunsBefore(finishedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `delete from job_runs where finished_at < ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, finishedBefore.UTC().Format(dateFormat))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// FlagOverstays flags the active reservations that exceed the max stay of
+// their parking lot and returns how many were flagged.
+func (d *DB) FlagOverstays() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE parking_space_reservations
+																			INNER JOIN parking_spaces ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																			INNER JOIN parking_lots ON parking_lots.id = parking_spaces.parking_lots_id
+																			SET parking_space_reservations.overstayed_at = ?
+																			WHERE parking_space_reservations.end_time IS NULL
+																			and parking_space_reservations.overstayed_at IS NULL
+																			and parking_lots.max_stay IS NOT NULL
+																			and parking_space_reservations.start_time < DATE_SUB(?, INTERVAL parking_lots.max_stay MINUTE)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC().Format(dateFormat)
+	result, err := stmt.ExecContext(ctx, now, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// FlagActiveReservationsByParkingLot flags the active reservations of a parking
+// lot that are not flagged yet as overstays and returns how many were flagged.
+func (d *DB) FlagActiveReservationsByParkingLot(parkingLotID int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE parking_space_reservations
+																			INNER JOIN parking_spaces ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																			SET parking_space_reservations.overstayed_at = ?
+																			WHERE parking_space_reservations.end_time IS NULL
+																			and parking_space_reservations.overstayed_at IS NULL
+																			and parking_spaces.parking_lots_id = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, time.Now().UTC().Format(dateFormat), parkingLotID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetActiveReservationIDsByParkingLot returns the IDs of the active
+// reservations of a parking lot.
+func (d *DB) GetActiveReservationIDsByParkingLot(parkingLotID int) ([]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT parking_space_reservations.id
+																						 FROM parking_space_reservations
+																						 INNER JOIN parking_spaces
+																						 ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																						 WHERE end_time IS NULL and parking_spaces.parking_lots_id = ?
+																						 order by parking_space_reservations.id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, parkingLotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetStaleReservationIDs returns the IDs of the active reservations that are
+// older than the stale after of their parking lot, parking lots without one are
+// skipped.
+func (d *DB) GetStaleReservationIDs() ([]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT parking_space_reservations.id
+																						 FROM parking_space_reservations
+																						 INNER JOIN parking_spaces ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																						 INNER JOIN parking_lots ON parking_lots.id = parking_spaces.parking_lots_id
+																						 WHERE parking_space_reservations.end_time IS NULL
+																						 and parking_lots.stale_after IS NOT NULL
+																						 and parking_space_reservations.start_time < DATE_SUB(?, INTERVAL parking_lots.stale_after MINUTE)
+																						 order by parking_space_reservations.id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, time.Now().UTC().Format(dateFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
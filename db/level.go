@@ -0,0 +1,339 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Level is a floor of a parking lot, Ordinal sorts levels from the lowest to the
+// highest. Code is part of the labels of the spaces on the level so it can not
+// be changed once created.
+type Level struct {
+	ID      int    `json:"id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Ordinal int    `json:"ordinal"`
+}
+
+// Zone is an area of a level. Code is part of the labels of the spaces in the
+// zone so it can not be changed once created.
+type Zone struct {
+	ID      int    `json:"id"`
+	LevelID int    `json:"level_id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+}
+
+func (d *DB) CreateLevel(parkingLotID int, l Level) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `insert into levels (parking_lots_id, code, name, ordinal) values (?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, parkingLotID, l.Code, l.Name, l.Ordinal)
+	if isDuplicateEntry(err) {
+		return 0, ErrDuplicate
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (d *DB) GetLevelsByParkingLot(parkingLotID int) ([]Level, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, code, name, ordinal
+																						 FROM levels
+																						 WHERE parking_lots_id = ?
+																						 order by ordinal asc, id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, parkingLotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []Level{}
+	for rows.Next() {
+		var l Level
+		if err := rows.Scan(&l.ID, &l.Code, &l.Name, &l.Ordinal); err != nil {
+			return nil, err
+		}
+
+		levels = append(levels, l)
+	}
+
+	return levels, rows.Err()
+}
+
+// GetLevelByParkingLotIDAndID returns sql.ErrNoRows when the level does not
+// belong to the parking lot.
+func (d *DB) GetLevelByParkingLotIDAndID(parkingLotID, id int) (Level, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, code, name, ordinal
+																						 FROM levels
+																						 WHERE parking_lots_id = ? and id = ?`)
+	if err != nil {
+		return Level{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, parkingLotID, id)
+	if row == nil {
+		return Level{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return Level{}, err
+	}
+
+	var l Level
+	if err := row.Scan(&l.ID, &l.Code, &l.Name, &l.Ordinal); err != nil {
+		return Level{}, err
+	}
+
+	return l, nil
+}
+
+// UpdateLevel updates the name and ordinal of a level.
+func (d *DB) UpdateLevel(l Level) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE levels SET name = ?, ordinal = ? WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, l.Name, l.Ordinal, l.ID)
+
+	return err
+}
+
+// DeleteLevel deletes a level, ErrInUse is returned while it still has zones.
+func (d *DB) DeleteLevel(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `DELETE FROM levels WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, id)
+	if isForeignKeyViolation(err) {
+		return ErrInUse
+	}
+
+	return err
+}
+
+func (d *DB) CreateZone(levelID int, z Zone) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `insert into zones (levels_id, code, name) values (?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, levelID, z.Code, z.Name)
+	if isDuplicateEntry(err) {
+		return 0, ErrDuplicate
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (d *DB) GetZonesByLevel(levelID int) ([]Zone, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, levels_id, code, name
+																						 FROM zones
+																						 WHERE levels_id = ?
+																						 order by code asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, levelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []Zone{}
+	for rows.Next() {
+		var z Zone
+		if err := rows.Scan(&z.ID, &z.LevelID, &z.Code, &z.Name); err != nil {
+			return nil, err
+		}
+
+		zones = append(zones, z)
+	}
+
+	return zones, rows.Err()
+}
+
+// GetZoneByLevelIDAndID returns sql.ErrNoRows when the zone does not belong to
+// the level.
+func (d *DB) GetZoneByLevelIDAndID(levelID, id int) (Zone, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, levels_id, code, name
+																						 FROM zones
+																						 WHERE levels_id = ? and id = ?`)
+	if err != nil {
+		return Zone{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, levelID, id)
+	if row == nil {
+		return Zone{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return Zone{}, err
+	}
+
+	var z Zone
+	if err := row.Scan(&z.ID, &z.LevelID, &z.Code, &z.Name); err != nil {
+		return Zone{}, err
+	}
+
+	return z, nil
+}
+
+// UpdateZone updates the name of a zone.
+func (d *DB) UpdateZone(z Zone) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE zones SET name = ? WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, z.Name, z.ID)
+
+	return err
+}
+
+// DeleteZone deletes a zone, ErrInUse is returned while it still has spaces.
+func (d *DB) DeleteZone(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `DELETE FROM zones WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, id)
+	if isForeignKeyViolation(err) {
+		return ErrInUse
+	}
+
+	return err
+}
+
+// Location tells a driver where a parking space is, Level and Zone are nil for
+// spaces that are not in a zone.
+type Location struct {
+	ParkingSpaceID int    `json:"parking_space_id"`
+	Label          string `json:"label"`
+	SlotNumber     int    `json:"slot_number"`
+	Level          *Level `json:"level"`
+	Zone           *Zone  `json:"zone"`
+}
+
+func getLocationByParkingSpace(ctx context.Context, q preparer, parkingSpaceID int) (Location, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT parking_spaces.id, label, slot_number,
+																						 zones.id, zones.levels_id, zones.code, zones.name,
+																						 levels.code, levels.name, levels.ordinal
+																						 FROM parking_spaces
+																						 LEFT JOIN zones ON zones.id = parking_spaces.zones_id
+																						 LEFT JOIN levels ON levels.id = zones.levels_id
+																						 WHERE parking_spaces.id = ?`)
+	if err != nil {
+		return Location{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, parkingSpaceID)
+	if row == nil {
+		return Location{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return Location{}, err
+	}
+
+	var (
+		loc                                      Location
+		zoneID, levelID, levelOrdinal            sql.NullInt64
+		zoneCode, zoneName, levelCode, levelName sql.NullString
+	)
+	if err := row.Scan(
+		&loc.ParkingSpaceID, &loc.Label, &loc.SlotNumber,
+		&zoneID, &levelID, &zoneCode, &zoneName,
+		&levelCode, &levelName, &levelOrdinal,
+	); err != nil {
+		return Location{}, err
+	}
+
+	if zoneID.Valid {
+		loc.Zone = &Zone{
+			ID:      int(zoneID.Int64),
+			LevelID: int(levelID.Int64),
+			Code:    zoneCode.String,
+			Name:    zoneName.String,
+		}
+		loc.Level = &Level{
+			ID:      int(levelID.Int64),
+			Code:    levelCode.String,
+			Name:    levelName.String,
+			Ordinal: int(levelOrdinal.Int64),
+		}
+	}
+
+	return loc, nil
+}
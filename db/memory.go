@@ -0,0 +1,832 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/allocation"
+	"github.com/arifmahmudrana/parking-lot/hours"
+	"github.com/arifmahmudrana/parking-lot/pricing"
+	"github.com/arifmahmudrana/parking-lot/space"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+var errForeignKey = errors.New("foreign key constraint fails")
+
+type memParkingLot struct {
+	ParkingLot
+	archivedAt *time.Time
+}
+
+type memParkingSpace struct {
+	id           int
+	createdAt    time.Time
+	status       status
+	parkingLotID int
+	sizeClass    vehicle.SizeClass
+	features     space.Features
+	slotNumber   int
+	label        string
+	zoneID       *int
+
+	distanceToEntrance *int
+	decommissionedAt   *time.Time
+}
+
+type memLevel struct {
+	parkingLotID int
+	level        Level
+}
+
+type memParkingSpaceReservation struct {
+	id, userID, fee, parkingSpaceID, ratePlanID int
+	startTime                                   time.Time
+	endTime                                     *time.Time
+	vehicleType                                 vehicle.Type
+	overstayedAt                                *time.Time
+	vehicleID                                   int
+}
+
+type memRatePlan struct {
+	parkingLotID int
+	ratePlan     pricing.RatePlan
+}
+
+// MemoryDB is an in-memory Repository with the same semantics as DB. Rows are
+// kept in insertion order and IDs are assigned like AUTO_INCREMENT, so the row
+// with ID n is stored at index n-1.
+type MemoryDB struct {
+	mu sync.Mutex
+
+	parkingLots              []*memParkingLot
+	parkingSpaces            []*memParkingSpace
+	parkingSpaceReservations []*memParkingSpaceReservation
+	ratePlans                []memRatePlan
+	levels                   []*memLevel // nil once deleted
+	zones                    []*Zone     // nil once deleted
+	bookings                 []*Booking
+	jobRuns                  []*JobRun // nil once deleted
+	vehicles                 []*Vehicle
+	users                    []User
+	grants                   []*Grant // nil once deleted
+	apiKeys                  []*APIKey
+	closures                 []*Closure // nil once deleted
+	payments                 []*Payment
+	adjustments              []Adjustment
+
+	now func() time.Time
+}
+
+func NewMemoryDB() *MemoryDB {
+	return NewMemoryDBWithClock(time.Now)
+}
+
+// NewMemoryDBWithClock returns a MemoryDB that takes the current time from now,
+// tests use it to let time pass.
+func NewMemoryDBWithClock(now func() time.Time) *MemoryDB {
+	return &MemoryDB{
+		now: now,
+	}
+}
+
+func (m *MemoryDB) Close() error {
+	return nil
+}
+
+// currentTime returns the time truncated to what a DATETIME column can store.
+func (m *MemoryDB) currentTime() time.Time {
+	return m.now().UTC().Truncate(time.Second)
+}
+
+func (m *MemoryDB) parkingLotExists(id int) bool {
+	return id > 0 && id <= len(m.parkingLots) && m.parkingLots[id-1].archivedAt == nil
+}
+
+// parkingLot returns a copy that shares no opening hours with the stored one.
+func (pl *memParkingLot) parkingLot() ParkingLot {
+	p := pl.ParkingLot
+	p.OpeningHours = make(hours.Week, 0, len(pl.OpeningHours))
+	for _, period := range pl.OpeningHours {
+		period.Days = append([]string(nil), period.Days...)
+		p.OpeningHours = append(p.OpeningHours, period)
+	}
+
+	return p
+}
+
+// activeParkingLots returns the parking lots that are not archived.
+func (m *MemoryDB) activeParkingLots() []*memParkingLot {
+	var parkingLots []*memParkingLot
+	for _, pl := range m.parkingLots {
+		if pl.archivedAt == nil {
+			parkingLots = append(parkingLots, pl)
+		}
+	}
+
+	return parkingLots
+}
+
+func (m *MemoryDB) parkingSpace(id int) *memParkingSpace {
+	if id <= 0 || id > len(m.parkingSpaces) {
+		return nil
+	}
+
+	return m.parkingSpaces[id-1]
+}
+
+func (m *MemoryDB) CreateParkingLot(pl ParkingLot) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pl.ID = len(m.parkingLots) + 1
+	m.parkingLots = append(m.parkingLots, &memParkingLot{ParkingLot: pl})
+
+	return int64(pl.ID), nil
+}
+
+func (m *MemoryDB) GetParkingLots(page int) ([]ParkingLot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := m.activeParkingLots()
+	parkingLots := make([]ParkingLot, 0, size)
+	for i := getOffset(page); i >= 0 && i < len(active) && len(parkingLots) < size; i++ {
+		parkingLots = append(parkingLots, active[i].parkingLot())
+	}
+
+	return parkingLots, nil
+}
+
+func (m *MemoryDB) GetParkingLotByID(id int) (ParkingLot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(id) {
+		return ParkingLot{}, sql.ErrNoRows
+	}
+
+	return m.parkingLots[id-1].parkingLot(), nil
+}
+
+func (m *MemoryDB) UpdateParkingLot(pl ParkingLot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(pl.ID) {
+		return sql.ErrNoRows
+	}
+	if pl.Capacity != nil && m.spacesByParkingLot(pl.ID) > *pl.Capacity {
+		return ErrLotCapacity
+	}
+
+	m.parkingLots[pl.ID-1].ParkingLot = pl
+
+	return nil
+}
+
+func (m *MemoryDB) ArchiveParkingLot(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(id) {
+		return ErrLotNotFound
+	}
+
+	for _, psr := range m.parkingSpaceReservations {
+		if psr.endTime == nil && m.parkingSpace(psr.parkingSpaceID).parkingLotID == id {
+			return ErrLotActive
+		}
+	}
+	for _, b := range m.bookings {
+		if b.ParkingLotID == id && b.Status == BookingHeld {
+			return ErrLotActive
+		}
+	}
+
+	now := m.currentTime()
+	m.parkingLots[id-1].archivedAt = &now
+
+	return nil
+}
+
+func (m *MemoryDB) GetTotalCountParkingLots() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.activeParkingLots()), nil
+}
+
+func (m *MemoryDB) DoesParkingLotExistByID(parkingLotID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.parkingLotExists(parkingLotID), nil
+}
+
+func (m *MemoryDB) GetParkingSpacesByParkingLot(parkingLotID int) ([]ParkingSpace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// slot numbers are assigned in insertion order
+	var parkingSpaces []ParkingSpace
+	for _, ps := range m.parkingSpaces {
+		if ps.parkingLotID != parkingLotID || ps.status == decommissioned {
+			continue
+		}
+
+		parkingSpaces = append(parkingSpaces, ps.parkingSpace())
+	}
+
+	return parkingSpaces, nil
+}
+
+func (m *MemoryDB) GetParkingSpaceByParkingLotIDAndID(parkingLotID, id int) (ParkingSpace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ps := m.parkingSpace(id)
+	if ps == nil || ps.parkingLotID != parkingLotID || ps.status == decommissioned || !m.parkingLotExists(parkingLotID) {
+		return ParkingSpace{}, sql.ErrNoRows
+	}
+
+	return ps.parkingSpace(), nil
+}
+
+func (m *MemoryDB) UpdateParkingSpace(parkingLotID int, p ParkingSpace) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ps := m.parkingSpace(p.ID)
+	if ps == nil || ps.parkingLotID != parkingLotID {
+		return nil
+	}
+	if p.ZoneID != nil {
+		z := m.zone(*p.ZoneID)
+		if z == nil || m.level(z.LevelID) == nil || m.level(z.LevelID).parkingLotID != parkingLotID {
+			return sql.ErrNoRows
+		}
+	}
+	for _, other := range m.parkingSpaces {
+		if other != ps && other.parkingLotID == parkingLotID && other.label == p.Label {
+			return ErrDuplicate
+		}
+	}
+
+	ps.label = p.Label
+	ps.zoneID = p.ZoneID
+	ps.sizeClass = p.SizeClass
+	ps.features = p.Features
+	ps.distanceToEntrance = p.DistanceToEntrance
+
+	return nil
+}
+
+func (m *MemoryDB) DecommissionParkingSpace(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ps := m.parkingSpace(id)
+	if ps == nil {
+		return sql.ErrNoRows
+	}
+	if ps.status == decommissioned {
+		return nil
+	}
+	if ps.status == booked {
+		return ErrInUse
+	}
+	for _, b := range m.bookings {
+		if b.ParkingSpaceID == id && b.Status == BookingHeld {
+			return ErrInUse
+		}
+	}
+
+	now := m.currentTime()
+	ps.status = decommissioned
+	ps.decommissionedAt = &now
+
+	return nil
+}
+
+func (ps *memParkingSpace) parkingSpace() ParkingSpace {
+	return ParkingSpace{
+		ID:         ps.id,
+		Status:     ps.status.value(),
+		SlotNumber: ps.slotNumber,
+		Label:      ps.label,
+		ZoneID:     ps.zoneID,
+		SizeClass:  ps.sizeClass,
+		Features:   ps.features,
+
+		DistanceToEntrance: ps.distanceToEntrance,
+	}
+}
+
+func (m *MemoryDB) CreateParkingSpaceFromParkingLotID(plID int, p ParkingSpace) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(plID) {
+		return 0, sql.ErrNoRows
+	}
+	if err := m.checkCapacity(plID, 1); err != nil {
+		return 0, err
+	}
+
+	return m.createParkingSpace(plID, p)
+}
+
+func (m *MemoryDB) CreateParkingSpacesFromParkingLotID(plID int, spaces []ParkingSpace) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(plID) {
+		return nil, sql.ErrNoRows
+	}
+	if err := m.checkCapacity(plID, len(spaces)); err != nil {
+		return nil, err
+	}
+
+	// the spaces are only kept when all of them are created
+	created := len(m.parkingSpaces)
+	ids := make([]int64, 0, len(spaces))
+	for _, p := range spaces {
+		id, err := m.createParkingSpace(plID, p)
+		if err != nil {
+			m.parkingSpaces = m.parkingSpaces[:created]
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (m *MemoryDB) spacesByParkingLot(plID int) int {
+	var spaces int
+	for _, ps := range m.parkingSpaces {
+		if ps.parkingLotID == plID && ps.status != decommissioned {
+			spaces++
+		}
+	}
+
+	return spaces
+}
+
+// checkCapacity returns ErrLotCapacity when n more spaces do not fit the
+// capacity of the parking lot.
+func (m *MemoryDB) checkCapacity(plID, n int) error {
+	capacity := m.parkingLots[plID-1].Capacity
+	if capacity != nil && m.spacesByParkingLot(plID)+n > *capacity {
+		return ErrLotCapacity
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) createParkingSpace(plID int, p ParkingSpace) (int64, error) {
+	slotNumber, zoneCount := 1, 1
+	for _, ps := range m.parkingSpaces {
+		if ps.parkingLotID == plID && ps.slotNumber >= slotNumber {
+			slotNumber = ps.slotNumber + 1
+		}
+		if p.ZoneID != nil && ps.zoneID != nil && *ps.zoneID == *p.ZoneID {
+			zoneCount++
+		}
+	}
+
+	label := p.Label
+	if p.ZoneID != nil {
+		z := m.zone(*p.ZoneID)
+		if z == nil || m.level(z.LevelID) == nil || m.level(z.LevelID).parkingLotID != plID {
+			return 0, sql.ErrNoRows
+		}
+
+		if label == "" {
+			label = fmt.Sprintf("%s-%s-%03d", m.level(z.LevelID).level.Code, z.Code, zoneCount)
+		}
+	}
+	if label == "" {
+		label = fmt.Sprintf("%03d", slotNumber)
+	}
+
+	for _, ps := range m.parkingSpaces {
+		if ps.parkingLotID == plID && ps.label == label {
+			return 0, ErrDuplicate
+		}
+	}
+
+	ps := &memParkingSpace{
+		id:           len(m.parkingSpaces) + 1,
+		createdAt:    m.currentTime(),
+		status:       available,
+		parkingLotID: plID,
+		sizeClass:    p.SizeClass,
+		features:     p.Features,
+		slotNumber:   slotNumber,
+		label:        label,
+		zoneID:       p.ZoneID,
+
+		distanceToEntrance: p.DistanceToEntrance,
+	}
+	m.parkingSpaces = append(m.parkingSpaces, ps)
+
+	return int64(ps.id), nil
+}
+
+func (m *MemoryDB) DoesParkingSpaceExistForMaintananceByParkingLotIDAndID(id, parkingLotID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ps := m.parkingSpace(id)
+
+	return ps != nil && ps.parkingLotID == parkingLotID && (ps.status == maintanance || ps.status == available), nil
+}
+
+func (m *MemoryDB) SetParkingSpaceMaintanance(id int, mt bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ps := m.parkingSpace(id)
+	if ps == nil {
+		return nil
+	}
+
+	ps.status = available
+	if mt {
+		ps.status = maintanance
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) ParkParkingSpaceByParkingLot(parkingLotID int, pr ParkRequest) (int64, Location, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(parkingLotID) {
+		return 0, Location{}, ErrLotNotFound
+	}
+
+	pr, err := m.resolveVehicle(pr)
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	now := m.currentTime()
+	if err := m.checkOpen(parkingLotID, now); err != nil {
+		return 0, Location{}, err
+	}
+
+	ps, err := m.allocate(parkingLotID, pr)
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	id, loc := m.occupy(ps, pr)
+
+	return id, loc, nil
+}
+
+// allocate picks a space of a parking lot with the allocation strategy of the
+// parking lot, see candidates. ErrLotFull is returned when there is none.
+func (m *MemoryDB) allocate(parkingLotID int, pr ParkRequest) (*memParkingSpace, error) {
+	pl := m.parkingLots[parkingLotID-1]
+	strategy, err := allocation.ByName(pl.AllocationStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := allocation.Allocate(strategy, m.candidates(parkingLotID), allocation.Request{
+		VehicleType:   pr.VehicleType,
+		Features:      pr.Features,
+		AllowOverflow: pl.AllowTaggedOverflow,
+	})
+	if !ok {
+		return nil, ErrLotFull
+	}
+
+	return m.parkingSpace(c.ID), nil
+}
+
+// occupy books an available parking space and creates the reservation for it.
+func (m *MemoryDB) occupy(ps *memParkingSpace, pr ParkRequest) (int64, Location) {
+	ps.status = booked
+	psr := &memParkingSpaceReservation{
+		id:             len(m.parkingSpaceReservations) + 1,
+		userID:         pr.UserID,
+		parkingSpaceID: ps.id,
+		startTime:      m.currentTime(),
+		vehicleType:    pr.VehicleType,
+		vehicleID:      pr.vehicleID,
+	}
+	m.parkingSpaceReservations = append(m.parkingSpaceReservations, psr)
+
+	return int64(psr.id), m.location(ps)
+}
+
+// candidates returns the available spaces of a parking lot that are not held by
+// a booking.
+func (m *MemoryDB) candidates(parkingLotID int) []allocation.Candidate {
+	occupancy := map[int][2]int{}
+	for _, ps := range m.parkingSpaces {
+		if ps.parkingLotID != parkingLotID || ps.zoneID == nil || ps.status == decommissioned {
+			continue
+		}
+
+		o := occupancy[*ps.zoneID]
+		if ps.status == booked {
+			o[0]++
+		}
+		o[1]++
+		occupancy[*ps.zoneID] = o
+	}
+
+	held := map[int]bool{}
+	heldSince := m.currentTime().Add(-time.Duration(m.parkingLots[parkingLotID-1].BookingGracePeriod) * time.Minute)
+	for _, b := range m.bookings {
+		if b.ParkingLotID == parkingLotID && b.Status == BookingHeld && !b.StartTime.Before(heldSince) {
+			held[b.ParkingSpaceID] = true
+		}
+	}
+
+	var candidates []allocation.Candidate
+	for _, ps := range m.parkingSpaces {
+		if ps.parkingLotID != parkingLotID || held[ps.id] {
+			continue
+		}
+		if ps.status != available {
+			continue
+		}
+
+		c := allocation.Candidate{
+			ID:         ps.id,
+			SlotNumber: ps.slotNumber,
+			SizeClass:  ps.sizeClass,
+			Features:   ps.features,
+			Distance:   ps.distanceToEntrance,
+		}
+		if ps.zoneID != nil {
+			c.ZoneID = *ps.zoneID
+			c.ZoneOccupied, c.ZoneSpaces = occupancy[c.ZoneID][0], occupancy[c.ZoneID][1]
+			if z := m.zone(c.ZoneID); z != nil && m.level(z.LevelID) != nil {
+				ordinal := m.level(z.LevelID).level.Ordinal
+				c.LevelOrdinal = &ordinal
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+func (m *MemoryDB) GetParkingSpaceReservationByID(id int) (ParkingSpaceReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.parkingSpaceReservations) {
+		return ParkingSpaceReservation{}, sql.ErrNoRows
+	}
+
+	return m.parkingSpaceReservation(m.parkingSpaceReservations[id-1]), nil
+}
+
+func (m *MemoryDB) parkingSpaceReservation(psr *memParkingSpaceReservation) ParkingSpaceReservation {
+	return ParkingSpaceReservation{
+		ID:             psr.id,
+		UserID:         psr.userID,
+		ParkingLotID:   m.parkingSpace(psr.parkingSpaceID).parkingLotID,
+		ParkingSpaceID: psr.parkingSpaceID,
+		VehicleType:    psr.vehicleType,
+		StartTime:      psr.startTime,
+		EndTime:        psr.endTime,
+		Fee:            psr.fee,
+		OverstayedAt:   psr.overstayedAt,
+	}
+}
+
+func (m *MemoryDB) filterParkingSpaceReservations(f ReservationFilter) []ParkingSpaceReservation {
+	var reservations []ParkingSpaceReservation
+	for _, psr := range m.parkingSpaceReservations {
+		r := m.parkingSpaceReservation(psr)
+		switch {
+		case f.UserID > 0 && r.UserID != f.UserID,
+			f.ParkingLotID > 0 && r.ParkingLotID != f.ParkingLotID,
+			f.ParkingSpaceID > 0 && r.ParkingSpaceID != f.ParkingSpaceID,
+			f.Status == ReservationActive && r.EndTime != nil,
+			f.Status == ReservationCompleted && r.EndTime == nil,
+			!f.From.IsZero() && r.StartTime.Before(f.From),
+			!f.To.IsZero() && !r.StartTime.Before(f.To):
+			continue
+		}
+
+		reservations = append(reservations, r)
+	}
+
+	return reservations
+}
+
+func (m *MemoryDB) GetParkingSpaceReservations(f ReservationFilter, page int) ([]ParkingSpaceReservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sortBy := f.Sort
+	if !ValidReservationSort(sortBy) {
+		sortBy = DefaultReservationSort
+	}
+	desc := strings.HasPrefix(sortBy, "-")
+	key := func(r ParkingSpaceReservation) int64 {
+		switch strings.TrimPrefix(sortBy, "-") {
+		case "start_time":
+			return r.StartTime.Unix()
+		case "end_time":
+			// like NULL in MySQL unfinished reservations sort lowest
+			if r.EndTime == nil {
+				return math.MinInt64
+			}
+			return r.EndTime.Unix()
+		case "fee":
+			return int64(r.Fee)
+		}
+		return int64(r.ID)
+	}
+
+	filtered := m.filterParkingSpaceReservations(f)
+	sort.SliceStable(filtered, func(i, j int) bool {
+		a, b := filtered[i], filtered[j]
+		if desc {
+			a, b = b, a
+		}
+		if key(a) != key(b) {
+			return key(a) < key(b)
+		}
+		return a.ID < b.ID
+	})
+
+	reservations := make([]ParkingSpaceReservation, 0, size)
+	for i := getOffset(page); i >= 0 && i < len(filtered) && len(reservations) < size; i++ {
+		reservations = append(reservations, filtered[i])
+	}
+
+	return reservations, nil
+}
+
+func (m *MemoryDB) GetTotalCountParkingSpaceReservations(f ReservationFilter) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.filterParkingSpaceReservations(f)), nil
+}
+
+// reservationFee calculates the fee of psr if it ends at end.
+func (m *MemoryDB) reservationFee(psr *memParkingSpaceReservation, end time.Time) (pricing.Fee, error) {
+	if psr.endTime != nil {
+		return pricing.Fee{}, ErrAlreadyUnparked
+	}
+
+	parkingLotID := m.parkingSpace(psr.parkingSpaceID).parkingLotID
+	loc, err := time.LoadLocation(m.parkingLots[parkingLotID-1].TimeZone)
+	if err != nil {
+		return pricing.Fee{}, err
+	}
+
+	return m.ratePlanByParkingLot(parkingLotID).Calculate(pricing.Stay{
+		Start:       psr.startTime,
+		End:         end,
+		Location:    loc,
+		VehicleType: psr.vehicleType,
+	}), nil
+}
+
+func (m *MemoryDB) QuoteParkingSpaceReservation(parkingSpaceReservationsID int, at time.Time) (pricing.Fee, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if parkingSpaceReservationsID <= 0 || parkingSpaceReservationsID > len(m.parkingSpaceReservations) {
+		return pricing.Fee{}, sql.ErrNoRows
+	}
+
+	return m.reservationFee(m.parkingSpaceReservations[parkingSpaceReservationsID-1], at.UTC().Truncate(time.Second))
+}
+
+func (m *MemoryDB) UnParkParkingSpaceByID(parkingSpaceReservationsID int, pay bool) (pricing.Fee, *Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if parkingSpaceReservationsID <= 0 || parkingSpaceReservationsID > len(m.parkingSpaceReservations) {
+		return pricing.Fee{}, nil, sql.ErrNoRows
+	}
+
+	psr := m.parkingSpaceReservations[parkingSpaceReservationsID-1]
+	endTime := m.currentTime()
+	fee, err := m.reservationFee(psr, endTime)
+	if err != nil {
+		return pricing.Fee{}, nil, err
+	}
+	psr.endTime = &endTime
+	psr.fee = fee.Total
+	psr.ratePlanID = fee.RatePlan.ID
+
+	m.parkingSpace(psr.parkingSpaceID).status = available
+
+	var p *Payment
+	if pay && fee.Total > 0 {
+		created := m.insertPayment(Payment{
+			ParkingSpaceReservationID: parkingSpaceReservationsID,
+			Amount:                    fee.Total,
+		})
+		p = &created
+	}
+
+	return fee, p, nil
+}
+
+func (m *MemoryDB) CreateRatePlan(parkingLotID int, rp pricing.RatePlan) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(parkingLotID) {
+		return 0, errForeignKey
+	}
+
+	rp.ID = len(m.ratePlans) + 1
+	m.ratePlans = append(m.ratePlans, memRatePlan{
+		parkingLotID: parkingLotID,
+		ratePlan:     rp,
+	})
+
+	return int64(rp.ID), nil
+}
+
+func (m *MemoryDB) GetRatePlanByParkingLot(parkingLotID int) (pricing.RatePlan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.ratePlanByParkingLot(parkingLotID), nil
+}
+
+func (m *MemoryDB) ratePlanByParkingLot(parkingLotID int) pricing.RatePlan {
+	for i := len(m.ratePlans) - 1; i >= 0; i-- {
+		if m.ratePlans[i].parkingLotID == parkingLotID {
+			return m.ratePlans[i].ratePlan
+		}
+	}
+
+	return pricing.DefaultRatePlan
+}
+
+func (m *MemoryDB) GetDailyReportByParkingLot(parkingLotID int, day time.Time) (DailyReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(parkingLotID) {
+		return DailyReport{}, sql.ErrNoRows
+	}
+	from, to, err := reportDay(m.parkingLots[parkingLotID-1].TimeZone, day)
+	if err != nil {
+		return DailyReport{}, err
+	}
+
+	report := DailyReport{
+		Date: from.Format("2006-01-02"),
+	}
+	for _, psr := range m.parkingSpaceReservations {
+		ps := m.parkingSpace(psr.parkingSpaceID)
+		if ps == nil || ps.parkingLotID != parkingLotID {
+			continue
+		}
+		if psr.endTime == nil || psr.endTime.Before(from) || !psr.endTime.Before(to) {
+			continue
+		}
+
+		report.TotalVehicles++
+		report.TotalParkingTime += int64(psr.endTime.Sub(psr.startTime) / time.Second)
+		report.TotalFee += psr.fee
+	}
+	for _, a := range m.adjustments {
+		ps := m.parkingSpace(m.parkingSpaceReservations[a.ParkingSpaceReservationID-1].parkingSpaceID)
+		if ps == nil || ps.parkingLotID != parkingLotID || a.Status != AdjustmentDone ||
+			a.CreatedAt.Before(from) || !a.CreatedAt.Before(to) {
+			continue
+		}
+
+		switch a.Kind {
+		case AdjustmentRefund:
+			report.TotalRefunds += a.Amount
+		case AdjustmentWaiver:
+			report.TotalWaivers += a.Amount
+		}
+	}
+
+	report.TotalAdjustments = report.TotalRefunds + report.TotalWaivers
+	report.NetRevenue = report.TotalFee - report.TotalAdjustments
+
+	return report, nil
+}
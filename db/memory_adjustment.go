@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/payment"
+)
+
+func (m *MemoryDB) CreateAdjustment(a Adjustment) (Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a.ParkingSpaceReservationID <= 0 || a.ParkingSpaceReservationID > len(m.parkingSpaceReservations) {
+		return Adjustment{}, sql.ErrNoRows
+	}
+	psr := m.parkingSpaceReservations[a.ParkingSpaceReservationID-1]
+	if psr.endTime == nil {
+		return Adjustment{}, ErrReservationActive
+	}
+
+	// the captured payment, refunded ones included
+	var captured *Payment
+	for _, p := range m.payments {
+		if p.ParkingSpaceReservationID == psr.id && (p.Status == payment.Captured || p.Status == payment.Refunded) {
+			captured = p
+		}
+	}
+
+	a.PaymentID, a.FailureReason = nil, ""
+	switch a.Kind {
+	case AdjustmentWaiver:
+		a.Status = AdjustmentDone
+		if captured != nil {
+			return Adjustment{}, ErrAlreadyPaid
+		}
+		if a.Amount > psr.fee-Waived(m.adjustmentsByReservation(psr.id)) {
+			return Adjustment{}, ErrAdjustmentTooLarge
+		}
+	case AdjustmentRefund:
+		a.Status = AdjustmentPending
+		if captured == nil {
+			return Adjustment{}, ErrNotPaid
+		}
+		if a.Amount > captured.Amount-captured.RefundedAmount {
+			return Adjustment{}, ErrAdjustmentTooLarge
+		}
+
+		captured.RefundedAmount += a.Amount
+		if captured.RefundedAmount == captured.Amount {
+			captured.Status = payment.Refunded
+		}
+		captured.UpdatedAt = m.currentTime()
+		id := captured.ID
+		a.PaymentID = &id
+	}
+
+	a.ID = len(m.adjustments) + 1
+	a.CreatedAt = m.currentTime()
+	m.adjustments = append(m.adjustments, a)
+
+	return a, nil
+}
+
+func (m *MemoryDB) UpdateAdjustment(a Adjustment) (Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a.ID <= 0 || a.ID > len(m.adjustments) || m.adjustments[a.ID-1].Status != AdjustmentPending {
+		return Adjustment{}, sql.ErrNoRows
+	}
+
+	stored := &m.adjustments[a.ID-1]
+	stored.Status = a.Status
+	stored.FailureReason = a.FailureReason
+
+	if stored.Status == AdjustmentFailed && stored.Kind == AdjustmentRefund && stored.PaymentID != nil {
+		p := m.payments[*stored.PaymentID-1]
+		p.RefundedAmount -= stored.Amount
+		p.Status = payment.Captured
+		p.UpdatedAt = m.currentTime()
+	}
+
+	return *stored, nil
+}
+
+func (m *MemoryDB) GetAdjustmentsByReservation(parkingSpaceReservationsID int) ([]Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.adjustmentsByReservation(parkingSpaceReservationsID), nil
+}
+
+func (m *MemoryDB) GetPendingRefunds(createdBefore time.Time) ([]Adjustment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	adjustments := []Adjustment{}
+	for _, a := range m.adjustments {
+		if a.Status == AdjustmentPending && a.Kind == AdjustmentRefund && a.CreatedAt.Before(createdBefore) {
+			adjustments = append(adjustments, a)
+		}
+	}
+
+	return adjustments, nil
+}
+
+func (m *MemoryDB) adjustmentsByReservation(parkingSpaceReservationsID int) []Adjustment {
+	adjustments := []Adjustment{}
+	for _, a := range m.adjustments {
+		if a.ParkingSpaceReservationID == parkingSpaceReservationsID {
+			adjustments = append(adjustments, a)
+		}
+	}
+
+	return adjustments
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+func (m *MemoryDB) CreateAPIKey(k APIKey) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createAPIKey(k)
+}
+
+func (m *MemoryDB) createAPIKey(k APIKey) (int64, error) {
+	for _, id := range k.ParkingLotIDs {
+		if !m.parkingLotExists(id) {
+			return 0, sql.ErrNoRows
+		}
+	}
+
+	k.ID = len(m.apiKeys) + 1
+	k.CreatedAt = m.currentTime()
+	k.ParkingLotIDs = append([]int{}, k.ParkingLotIDs...)
+	k.Actions = append(k.Actions[:0:0], k.Actions...)
+	k.ExpiresAt, k.RevokedAt, k.LastUsedAt = nil, nil, nil
+	m.apiKeys = append(m.apiKeys, &k)
+
+	return int64(k.ID), nil
+}
+
+func (m *MemoryDB) GetAPIKeys() ([]APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]APIKey, 0, len(m.apiKeys))
+	for _, k := range m.apiKeys {
+		keys = append(keys, *k)
+	}
+
+	return keys, nil
+}
+
+func (m *MemoryDB) GetAPIKeyByID(id int) (APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.apiKeys) {
+		return APIKey{}, sql.ErrNoRows
+	}
+
+	return *m.apiKeys[id-1], nil
+}
+
+func (m *MemoryDB) GetAPIKeyByKeyID(keyID string) (APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.apiKeys {
+		if k.KeyID == keyID {
+			return *k, nil
+		}
+	}
+
+	return APIKey{}, sql.ErrNoRows
+}
+
+func (m *MemoryDB) RotateAPIKey(id int, k APIKey, overlapUntil time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.apiKeys) {
+		return 0, sql.ErrNoRows
+	}
+
+	old := m.apiKeys[id-1]
+	if !old.Active(m.currentTime()) {
+		return 0, ErrAPIKeyInactive
+	}
+
+	k.Name, k.Actions, k.ParkingLotIDs = old.Name, old.Actions, old.ParkingLotIDs
+	newID, err := m.createAPIKey(k)
+	if err != nil {
+		return 0, err
+	}
+
+	overlapUntil = overlapUntil.UTC().Truncate(time.Second)
+	if old.ExpiresAt == nil || overlapUntil.Before(*old.ExpiresAt) {
+		old.ExpiresAt = &overlapUntil
+	}
+
+	return newID, nil
+}
+
+func (m *MemoryDB) RevokeAPIKey(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.apiKeys) {
+		return nil
+	}
+
+	if k := m.apiKeys[id-1]; k.RevokedAt == nil {
+		now := m.currentTime()
+		k.RevokedAt = &now
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) TouchAPIKey(id int, usedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.apiKeys) {
+		return nil
+	}
+
+	usedAt = usedAt.UTC().Truncate(time.Second)
+	m.apiKeys[id-1].LastUsedAt = &usedAt
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+func (m *MemoryDB) CreateBooking(parkingLotID int, b Booking) (Booking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(parkingLotID) {
+		return Booking{}, ErrLotNotFound
+	}
+
+	pr, err := m.lookupVehicle(b.parkRequest())
+	if err != nil {
+		return Booking{}, err
+	}
+	b.VehicleType = pr.VehicleType
+
+	ps, err := m.allocate(parkingLotID, pr)
+	if err == ErrLotFull {
+		return Booking{}, ErrBookingConflict
+	}
+	if err != nil {
+		return Booking{}, err
+	}
+
+	b.ID = len(m.bookings) + 1
+	b.ParkingLotID = parkingLotID
+	b.ParkingSpaceID = ps.id
+	b.Status = BookingHeld
+	b.ParkingSpaceReservationID = nil
+	b.NoShowFee = 0
+	m.bookings = append(m.bookings, &b)
+
+	return b, nil
+}
+
+func (m *MemoryDB) booking(id int) *Booking {
+	if id <= 0 || id > len(m.bookings) {
+		return nil
+	}
+
+	return m.bookings[id-1]
+}
+
+func (m *MemoryDB) GetBookingByID(id int) (Booking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.booking(id)
+	if b == nil {
+		return Booking{}, sql.ErrNoRows
+	}
+
+	return *b, nil
+}
+
+func (m *MemoryDB) ArriveBooking(id int) (int64, Location, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.booking(id)
+	if b == nil {
+		return 0, Location{}, sql.ErrNoRows
+	}
+
+	now := m.currentTime()
+	if b.Status != BookingHeld {
+		return 0, Location{}, ErrBookingNotHeld
+	}
+	if isNoShow(*b, now, m.bookingGracePeriod(b.ParkingLotID)) {
+		m.releaseNoShowBookings(now)
+		return 0, Location{}, ErrBookingNotHeld
+	}
+	if now.Before(b.StartTime.Add(-bookingLeadTime)) {
+		return 0, Location{}, ErrBookingTooEarly
+	}
+
+	pr, err := m.resolveVehicle(b.parkRequest())
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	if err := m.checkOpen(b.ParkingLotID, now); err != nil {
+		return 0, Location{}, err
+	}
+
+	ps := m.parkingSpace(b.ParkingSpaceID)
+	if ps.status != available {
+		ps, err = m.allocate(b.ParkingLotID, pr)
+		if err != nil {
+			return 0, Location{}, err
+		}
+	}
+
+	psrID, loc := m.occupy(ps, pr)
+	id = int(psrID)
+	b.Status = BookingArrived
+	b.ParkingSpaceReservationID = &id
+
+	return psrID, loc, nil
+}
+
+func (m *MemoryDB) bookingGracePeriod(parkingLotID int) time.Duration {
+	return time.Duration(m.parkingLots[parkingLotID-1].BookingGracePeriod) * time.Minute
+}
+
+func (m *MemoryDB) ReleaseNoShowBookings() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.releaseNoShowBookings(m.currentTime()), nil
+}
+
+func (m *MemoryDB) releaseNoShowBookings(now time.Time) int64 {
+	var n int64
+	for _, b := range m.bookings {
+		if b.Status != BookingHeld || !isNoShow(*b, now, m.bookingGracePeriod(b.ParkingLotID)) {
+			continue
+		}
+
+		b.Status = BookingReleased
+		b.NoShowFee = m.parkingLots[b.ParkingLotID-1].NoShowFee
+		n++
+	}
+
+	return n
+}
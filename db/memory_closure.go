@@ -0,0 +1,72 @@
+package db
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+func (m *MemoryDB) CreateClosure(parkingLotID int, c Closure) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(parkingLotID) {
+		return 0, sql.ErrNoRows
+	}
+
+	c.ID = len(m.closures) + 1
+	c.ParkingLotID = parkingLotID
+	c.StartTime = c.StartTime.UTC().Truncate(time.Second)
+	c.EndTime = c.EndTime.UTC().Truncate(time.Second)
+	m.closures = append(m.closures, &c)
+
+	return int64(c.ID), nil
+}
+
+func (m *MemoryDB) GetClosuresByParkingLot(parkingLotID int, endingAfter time.Time) ([]Closure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.closuresByParkingLot(parkingLotID, endingAfter), nil
+}
+
+func (m *MemoryDB) closuresByParkingLot(parkingLotID int, endingAfter time.Time) []Closure {
+	closures := []Closure{}
+	for _, c := range m.closures {
+		if c != nil && c.ParkingLotID == parkingLotID && c.EndTime.After(endingAfter) {
+			closures = append(closures, *c)
+		}
+	}
+
+	sort.SliceStable(closures, func(i, j int) bool {
+		return closures[i].StartTime.Before(closures[j].StartTime)
+	})
+
+	return closures
+}
+
+func (m *MemoryDB) DeleteClosure(parkingLotID, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.closures) || m.closures[id-1] == nil || m.closures[id-1].ParkingLotID != parkingLotID {
+		return sql.ErrNoRows
+	}
+
+	m.closures[id-1] = nil
+
+	return nil
+}
+
+// checkOpen returns ErrLotClosed when the parking lot is closed at now.
+func (m *MemoryDB) checkOpen(parkingLotID int, now time.Time) error {
+	open, err := m.parkingLots[parkingLotID-1].OpenAt(now, m.closuresByParkingLot(parkingLotID, now))
+	if err != nil {
+		return err
+	}
+	if !open {
+		return ErrLotClosed
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"database/sql"
+)
+
+func (m *MemoryDB) CreateGrant(g Grant) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if g.UserID <= 0 || g.UserID > len(m.users) {
+		return 0, sql.ErrNoRows
+	}
+	if g.ParkingLotID != nil && !m.parkingLotExists(*g.ParkingLotID) {
+		return 0, sql.ErrNoRows
+	}
+	if hasGrant(m.grantsByUser(g.UserID), g) {
+		return 0, ErrDuplicate
+	}
+
+	g.ID = len(m.grants) + 1
+	m.grants = append(m.grants, &g)
+
+	return int64(g.ID), nil
+}
+
+func (m *MemoryDB) GetGrantsByUser(userID int) ([]Grant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.grantsByUser(userID), nil
+}
+
+func (m *MemoryDB) grantsByUser(userID int) []Grant {
+	grants := []Grant{}
+	for _, g := range m.grants {
+		if g != nil && g.UserID == userID {
+			grants = append(grants, *g)
+		}
+	}
+
+	return grants
+}
+
+func (m *MemoryDB) DeleteGrant(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.grants) || m.grants[id-1] == nil {
+		return sql.ErrNoRows
+	}
+
+	m.grants[id-1] = nil
+
+	return nil
+}
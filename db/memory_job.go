@@ -0,0 +1,141 @@
+package db
+
+import (
+	"time"
+)
+
+func (m *MemoryDB) CreateJobRun(jr JobRun) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jr.ID = len(m.jobRuns) + 1
+	jr.StartedAt = jr.StartedAt.UTC().Truncate(time.Second)
+	jr.FinishedAt = jr.FinishedAt.UTC().Truncate(time.Second)
+	m.jobRuns = append(m.jobRuns, &jr)
+
+	return int64(jr.ID), nil
+}
+
+func (m *MemoryDB) GetJobRuns(page int) ([]JobRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobRuns := make([]JobRun, 0, size)
+	offset := getOffset(page)
+	for i := len(m.jobRuns) - 1; i >= 0 && len(jobRuns) < size; i-- {
+		if m.jobRuns[i] == nil {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		jobRuns = append(jobRuns, *m.jobRuns[i])
+	}
+
+	return jobRuns, nil
+}
+
+func (m *MemoryDB) GetTotalCountJobRuns() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int
+	for _, jr := range m.jobRuns {
+		if jr != nil {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (m *MemoryDB) DeleteJobRunsBefore(finishedBefore time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for i, jr := range m.jobRuns {
+		if jr != nil && jr.FinishedAt.Before(finishedBefore) {
+			m.jobRuns[i] = nil
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func (m *MemoryDB) FlagOverstays() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.currentTime()
+	var n int64
+	for _, psr := range m.parkingSpaceReservations {
+		if psr.endTime != nil || psr.overstayedAt != nil {
+			continue
+		}
+
+		maxStay := m.parkingLots[m.parkingSpace(psr.parkingSpaceID).parkingLotID-1].MaxStay
+		if maxStay == nil || !psr.startTime.Before(now.Add(-time.Duration(*maxStay)*time.Minute)) {
+			continue
+		}
+
+		psr.overstayedAt = &now
+		n++
+	}
+
+	return n, nil
+}
+
+func (m *MemoryDB) GetStaleReservationIDs() ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.currentTime()
+	var ids []int
+	for _, psr := range m.parkingSpaceReservations {
+		if psr.endTime != nil {
+			continue
+		}
+
+		staleAfter := m.parkingLots[m.parkingSpace(psr.parkingSpaceID).parkingLotID-1].StaleAfter
+		if staleAfter != nil && psr.startTime.Before(now.Add(-time.Duration(*staleAfter)*time.Minute)) {
+			ids = append(ids, psr.id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (m *MemoryDB) FlagActiveReservationsByParkingLot(parkingLotID int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.currentTime()
+	var n int64
+	for _, psr := range m.parkingSpaceReservations {
+		if psr.endTime != nil || psr.overstayedAt != nil || m.parkingSpace(psr.parkingSpaceID).parkingLotID != parkingLotID {
+			continue
+		}
+
+		psr.overstayedAt = &now
+		n++
+	}
+
+	return n, nil
+}
+
+func (m *MemoryDB) GetActiveReservationIDsByParkingLot(parkingLotID int) ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int
+	for _, psr := range m.parkingSpaceReservations {
+		if psr.endTime == nil && m.parkingSpace(psr.parkingSpaceID).parkingLotID == parkingLotID {
+			ids = append(ids, psr.id)
+		}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"database/sql"
+	"sort"
+)
+
+func (m *MemoryDB) level(id int) *memLevel {
+	if id <= 0 || id > len(m.levels) {
+		return nil
+	}
+
+	return m.levels[id-1]
+}
+
+func (m *MemoryDB) zone(id int) *Zone {
+	if id <= 0 || id > len(m.zones) {
+		return nil
+	}
+
+	return m.zones[id-1]
+}
+
+func (m *MemoryDB) location(ps *memParkingSpace) Location {
+	loc := Location{
+		ParkingSpaceID: ps.id,
+		Label:          ps.label,
+		SlotNumber:     ps.slotNumber,
+	}
+	if ps.zoneID != nil {
+		z := *m.zone(*ps.zoneID)
+		l := m.level(z.LevelID).level
+		loc.Zone, loc.Level = &z, &l
+	}
+
+	return loc
+}
+
+func (m *MemoryDB) CreateLevel(parkingLotID int, l Level) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.parkingLotExists(parkingLotID) {
+		return 0, errForeignKey
+	}
+	for _, ml := range m.levels {
+		if ml != nil && ml.parkingLotID == parkingLotID && ml.level.Code == l.Code {
+			return 0, ErrDuplicate
+		}
+	}
+
+	l.ID = len(m.levels) + 1
+	m.levels = append(m.levels, &memLevel{
+		parkingLotID: parkingLotID,
+		level:        l,
+	})
+
+	return int64(l.ID), nil
+}
+
+func (m *MemoryDB) GetLevelsByParkingLot(parkingLotID int) ([]Level, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	levels := []Level{}
+	for _, ml := range m.levels {
+		if ml == nil || ml.parkingLotID != parkingLotID {
+			continue
+		}
+
+		levels = append(levels, ml.level)
+	}
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Ordinal < levels[j].Ordinal
+	})
+
+	return levels, nil
+}
+
+func (m *MemoryDB) GetLevelByParkingLotIDAndID(parkingLotID, id int) (Level, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ml := m.level(id)
+	if ml == nil || ml.parkingLotID != parkingLotID {
+		return Level{}, sql.ErrNoRows
+	}
+
+	return ml.level, nil
+}
+
+func (m *MemoryDB) UpdateLevel(l Level) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ml := m.level(l.ID); ml != nil {
+		ml.level.Name = l.Name
+		ml.level.Ordinal = l.Ordinal
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) DeleteLevel(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.level(id) == nil {
+		return nil
+	}
+	for _, z := range m.zones {
+		if z != nil && z.LevelID == id {
+			return ErrInUse
+		}
+	}
+
+	m.levels[id-1] = nil
+
+	return nil
+}
+
+func (m *MemoryDB) CreateZone(levelID int, z Zone) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.level(levelID) == nil {
+		return 0, errForeignKey
+	}
+	for _, mz := range m.zones {
+		if mz != nil && mz.LevelID == levelID && mz.Code == z.Code {
+			return 0, ErrDuplicate
+		}
+	}
+
+	z.ID = len(m.zones) + 1
+	z.LevelID = levelID
+	m.zones = append(m.zones, &z)
+
+	return int64(z.ID), nil
+}
+
+func (m *MemoryDB) GetZonesByLevel(levelID int) ([]Zone, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zones := []Zone{}
+	for _, z := range m.zones {
+		if z == nil || z.LevelID != levelID {
+			continue
+		}
+
+		zones = append(zones, *z)
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].Code < zones[j].Code
+	})
+
+	return zones, nil
+}
+
+func (m *MemoryDB) GetZoneByLevelIDAndID(levelID, id int) (Zone, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	z := m.zone(id)
+	if z == nil || z.LevelID != levelID {
+		return Zone{}, sql.ErrNoRows
+	}
+
+	return *z, nil
+}
+
+func (m *MemoryDB) UpdateZone(z Zone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if mz := m.zone(z.ID); mz != nil {
+		mz.Name = z.Name
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) DeleteZone(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.zone(id) == nil {
+		return nil
+	}
+	for _, ps := range m.parkingSpaces {
+		if ps.zoneID != nil && *ps.zoneID == id {
+			return ErrInUse
+		}
+	}
+
+	m.zones[id-1] = nil
+
+	return nil
+}
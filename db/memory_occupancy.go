@@ -0,0 +1,101 @@
+package db
+
+import "time"
+
+func (m *MemoryDB) GetOccupancyByParkingLot(parkingLotID int) (Occupancy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	o := Occupancy{
+		ParkingLotID: parkingLotID,
+	}
+	for _, ps := range m.parkingSpaces {
+		if ps.parkingLotID != parkingLotID || (ps.status != available && ps.status != booked) {
+			continue
+		}
+
+		o.Spaces++
+		switch ps.status {
+		case booked:
+			o.Occupied++
+		case available:
+			o.Available++
+		}
+	}
+
+	var (
+		now          = m.currentTime()
+		since        = now.Add(-occupancyHistory)
+		stays        time.Duration
+		n            int
+		activeStarts []time.Time
+	)
+	for _, psr := range m.parkingSpaceReservations {
+		if m.parkingSpace(psr.parkingSpaceID).parkingLotID != parkingLotID {
+			continue
+		}
+
+		switch {
+		case psr.endTime == nil:
+			activeStarts = append(activeStarts, psr.startTime)
+		case !psr.endTime.Before(since):
+			stays += psr.endTime.Sub(psr.startTime)
+			n++
+		}
+	}
+	if n == 0 {
+		return o, nil
+	}
+
+	stay := (stays / time.Duration(n)).Truncate(time.Second)
+	for _, start := range activeStarts {
+		nextFreeAt := start.Add(stay)
+		if nextFreeAt.After(now) && (o.NextFreeAt == nil || nextFreeAt.Before(*o.NextFreeAt)) {
+			o.NextFreeAt = &nextFreeAt
+		}
+	}
+
+	return o, nil
+}
+
+func (m *MemoryDB) GetParkingLotSuggestions(parkingLotID int, pr ParkRequest, limit int) ([]ParkingLotSuggestion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var origin ParkingLot
+	if parkingLotID > 0 && parkingLotID <= len(m.parkingLots) {
+		origin = m.parkingLots[parkingLotID-1].ParkingLot
+	}
+
+	now := m.currentTime()
+	suggestions := []ParkingLotSuggestion{}
+	for _, pl := range m.activeParkingLots() {
+		if pl.ID == parkingLotID {
+			continue
+		}
+		if m.checkOpen(pl.ID, now) != nil {
+			continue
+		}
+
+		s := ParkingLotSuggestion{
+			ParkingLotID: pl.ID,
+			Name:         pl.Name,
+			Distance:     distance(origin, pl.ParkingLot),
+		}
+		for _, c := range m.candidates(pl.ID) {
+			if c.SizeClass.Fits(pr.VehicleType) && c.Features.Eligible(pr.Features, pl.AllowTaggedOverflow) {
+				s.Available++
+			}
+		}
+		if s.Available > 0 {
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	sortSuggestions(suggestions)
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/arifmahmudrana/parking-lot/payment"
+)
+
+func (m *MemoryDB) CreatePayment(p Payment) (Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p.ParkingSpaceReservationID <= 0 || p.ParkingSpaceReservationID > len(m.parkingSpaceReservations) {
+		return Payment{}, sql.ErrNoRows
+	}
+
+	return m.insertPayment(p), nil
+}
+
+func (m *MemoryDB) insertPayment(p Payment) Payment {
+	now := m.currentTime()
+	p.ID = len(m.payments) + 1
+	p.Status = payment.Pending
+	p.RefundedAmount = 0
+	p.Reference = ""
+	p.FailureReason = ""
+	p.CreatedAt, p.UpdatedAt = now, now
+	m.payments = append(m.payments, &p)
+
+	return p
+}
+
+func (m *MemoryDB) ClaimPayment(parkingSpaceReservationsID int) (Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if parkingSpaceReservationsID <= 0 || parkingSpaceReservationsID > len(m.parkingSpaceReservations) {
+		return Payment{}, sql.ErrNoRows
+	}
+	psr := m.parkingSpaceReservations[parkingSpaceReservationsID-1]
+	if psr.endTime == nil {
+		return Payment{}, ErrReservationActive
+	}
+
+	var latest Payment
+	for _, p := range m.payments {
+		if p.ParkingSpaceReservationID == parkingSpaceReservationsID {
+			latest = *p
+		}
+	}
+
+	now := m.currentTime()
+	due := psr.fee - Waived(m.adjustmentsByReservation(parkingSpaceReservationsID))
+	p, claim, err := claimablePayment(latest, due, now)
+	if err != nil {
+		return Payment{}, err
+	}
+	if !claim {
+		stored := m.payments[p.ID-1]
+		stored.Status, stored.FailureReason, stored.UpdatedAt = payment.Failed, "fee adjusted", now
+		p = Payment{}
+	}
+
+	if p.ID == 0 {
+		p = m.insertPayment(Payment{
+			ParkingSpaceReservationID: parkingSpaceReservationsID,
+			Amount:                    due,
+		})
+	}
+	if p.Status == payment.Pending {
+		p.Status = payment.Processing
+	}
+	p.UpdatedAt = now
+	*m.payments[p.ID-1] = p
+
+	return p, nil
+}
+
+func (m *MemoryDB) UpdatePayment(p Payment) (Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p.ID <= 0 || p.ID > len(m.payments) {
+		return Payment{}, sql.ErrNoRows
+	}
+
+	stored := m.payments[p.ID-1]
+	stored.Status = p.Status
+	stored.Reference = p.Reference
+	stored.FailureReason = p.FailureReason
+	stored.UpdatedAt = m.currentTime()
+
+	return *stored, nil
+}
+
+func (m *MemoryDB) GetPaymentsByReservation(parkingSpaceReservationsID int) ([]Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	payments := []Payment{}
+	for _, p := range m.payments {
+		if p.ParkingSpaceReservationID == parkingSpaceReservationsID {
+			payments = append(payments, *p)
+		}
+	}
+
+	return payments, nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql"
+)
+
+func (m *MemoryDB) CreateUser(u User) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, other := range m.users {
+		if other.Email == u.Email {
+			return 0, ErrDuplicate
+		}
+	}
+
+	u.ID = len(m.users) + 1
+	m.users = append(m.users, u)
+
+	return int64(u.ID), nil
+}
+
+func (m *MemoryDB) GetUserByEmail(email string) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return User{}, sql.ErrNoRows
+}
+
+func (m *MemoryDB) GetUserByID(id int) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id <= 0 || id > len(m.users) {
+		return User{}, sql.ErrNoRows
+	}
+
+	return m.users[id-1], nil
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+func (m *MemoryDB) CreateVehicle(v Vehicle) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createVehicle(v)
+}
+
+func (m *MemoryDB) createVehicle(v Vehicle) (int64, error) {
+	if m.vehicleByPlate(v.Plate) != nil {
+		return 0, ErrDuplicate
+	}
+
+	v.ID = len(m.vehicles) + 1
+	m.vehicles = append(m.vehicles, &v)
+
+	return int64(v.ID), nil
+}
+
+func (m *MemoryDB) vehicleByPlate(plate string) *Vehicle {
+	for _, v := range m.vehicles {
+		if v.Plate == plate {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) GetVehicleByPlate(plate string) (Vehicle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v := m.vehicleByPlate(plate)
+	if v == nil {
+		return Vehicle{}, sql.ErrNoRows
+	}
+
+	return *v, nil
+}
+
+func (m *MemoryDB) GetCurrentParkingByVehicle(vehicleID int) (CurrentParking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	psr := m.activeReservationByVehicle(vehicleID)
+	if psr == nil {
+		return CurrentParking{}, sql.ErrNoRows
+	}
+
+	ps := m.parkingSpace(psr.parkingSpaceID)
+	return CurrentParking{
+		ParkingSpaceReservationID: psr.id,
+		ParkingLotID:              ps.parkingLotID,
+		ParkingLotName:            m.parkingLots[ps.parkingLotID-1].Name,
+		Location:                  m.location(ps),
+		StartTime:                 psr.startTime,
+	}, nil
+}
+
+func (m *MemoryDB) activeReservationByVehicle(vehicleID int) *memParkingSpaceReservation {
+	for _, psr := range m.parkingSpaceReservations {
+		if psr.vehicleID == vehicleID && psr.endTime == nil {
+			return psr
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) resolveVehicle(pr ParkRequest) (ParkRequest, error) {
+	pr, err := m.lookupVehicle(pr)
+	if err != nil || pr.vehicleID == 0 {
+		return pr, err
+	}
+
+	if m.activeReservationByVehicle(pr.vehicleID) != nil {
+		return ParkRequest{}, ErrVehicleParked
+	}
+
+	return pr, nil
+}
+
+func (m *MemoryDB) lookupVehicle(pr ParkRequest) (ParkRequest, error) {
+	if pr.Plate == "" {
+		if pr.VehicleType == "" {
+			pr.VehicleType = vehicle.DefaultType
+		}
+
+		return pr, nil
+	}
+
+	v := m.vehicleByPlate(pr.Plate)
+	if v == nil {
+		return ParkRequest{}, ErrVehicleNotRegistered
+	}
+	if v.UserID != pr.UserID && !pr.AnyVehicle {
+		return ParkRequest{}, ErrVehicleNotOwned
+	}
+
+	if pr.VehicleType == "" {
+		pr.VehicleType = v.VehicleType
+	}
+	if pr.UserID == 0 {
+		pr.UserID = v.UserID
+	}
+	pr.vehicleID = v.ID
+
+	return pr, nil
+}
@@ -0,0 +1,355 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	migrationTimeout = time.Minute
+	migrationLock    = "parking_lot_schema_version"
+)
+
+var (
+	//go:embed migrations/*.sql
+	migrationFiles embed.FS
+
+	ErrMigrationLocked = errors.New("another migration is running")
+	// ErrUnversionedSchema is returned by MigrateUp for a database whose tables
+	// were created before migrations were recorded, see MigrateBaseline.
+	ErrUnversionedSchema = errors.New("the tables exist but no migration is recorded, record the migrations they match with baseline")
+)
+
+// Migration is a versioned schema change embedded from db/migrations. Files are
+// named <version>_<name>.up.sql and <version>_<name>.down.sql.
+type Migration struct {
+	Version   int     `json:"version"`
+	Name      string  `json:"name"`
+	AppliedAt *string `json:"applied_at"`
+
+	up, down string
+}
+
+func loadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		base := path.Base(file)
+		direction := path.Ext(strings.TrimSuffix(base, ".sql"))
+		if direction != ".up" && direction != ".down" {
+			return nil, fmt.Errorf("migration %s: must end with .up.sql or .down.sql", base)
+		}
+
+		v, name, ok := strings.Cut(strings.TrimSuffix(base, direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: must be named <version>_<name>", base)
+		}
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", base, err)
+		}
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == ".up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s: missing up or down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// splitStatements splits a migration file into single statements as the driver
+// does not allow multiple statements per query. Statements must end with a
+// semicolon at the end of a line.
+func splitStatements(content string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+	)
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		statements = append(statements, s)
+	}
+
+	return statements
+}
+
+// withMigrationConn runs fn on a single connection holding the migration lock
+// with the schema_version table created.
+func (d *DB) withMigrationConn(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	conn, err := d.dbConn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 10)`, migrationLock).Scan(&locked); err != nil {
+		return err
+	}
+	if locked.Int64 != 1 {
+		return ErrMigrationLocked
+	}
+	defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, migrationLock)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (
+																		version INT NOT NULL,
+																		name VARCHAR(255) NOT NULL,
+																		applied_at DATETIME NOT NULL,
+																		PRIMARY KEY (version)
+																	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if err != nil {
+		return err
+	}
+
+	return fn(ctx, conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]string{}
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt string
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// MigrationStatus returns every known migration, AppliedAt is nil for the ones
+// that are pending.
+func (d *DB) MigrationStatus() ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.withMigrationConn(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := range migrations {
+			if appliedAt, ok := applied[migrations[i].Version]; ok {
+				migrations[i].AppliedAt = &appliedAt
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+// MigrateUp applies all pending migrations in version order and returns them.
+func (d *DB) MigrateUp() ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = d.withMigrationConn(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			// creating the tables again would fail midway
+			var tables int
+			err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.tables
+																				WHERE table_schema = DATABASE() and table_name = 'parking_lots'`).
+				Scan(&tables)
+			if err != nil {
+				return err
+			}
+			if tables > 0 {
+				return ErrUnversionedSchema
+			}
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+
+			// MySQL commits DDL implicitly, a failed migration has to be
+			// fixed by hand before it can be run again
+			for _, statement := range splitStatements(m.up) {
+				if _, err := conn.ExecContext(ctx, statement); err != nil {
+					return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+				}
+			}
+
+			appliedAt := time.Now().UTC().Format(dateFormat)
+			_, err := conn.ExecContext(ctx,
+				`insert into schema_version (version, name, applied_at) values (?, ?, ?)`,
+				m.Version, m.Name, appliedAt)
+			if err != nil {
+				return err
+			}
+
+			m.AppliedAt = &appliedAt
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// MigrateBaseline records the migrations up to version as applied without
+// running them and returns the ones it recorded. It adopts a database whose
+// tables were created before migrations were recorded, e.g. version 3 for the
+// parking lots, parking spaces and reservations tables.
+func (d *DB) MigrateBaseline(version int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	known := false
+	for _, m := range migrations {
+		known = known || m.Version == version
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	var done []Migration
+	err = d.withMigrationConn(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok || m.Version > version {
+				continue
+			}
+
+			appliedAt := time.Now().UTC().Format(dateFormat)
+			_, err := conn.ExecContext(ctx,
+				`insert into schema_version (version, name, applied_at) values (?, ?, ?)`,
+				m.Version, m.Name, appliedAt)
+			if err != nil {
+				return err
+			}
+
+			m.AppliedAt = &appliedAt
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// MigrateDown reverts the last steps applied migrations and returns them.
+func (d *DB) MigrateDown(steps int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = d.withMigrationConn(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+
+			for _, statement := range splitStatements(m.down) {
+				if _, err := conn.ExecContext(ctx, statement); err != nil {
+					return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+				}
+			}
+
+			_, err := conn.ExecContext(ctx, `DELETE FROM schema_version WHERE version = ?`, m.Version)
+			if err != nil {
+				return err
+			}
+
+			m.AppliedAt = nil
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
@@ -0,0 +1 @@
+DROP TABLE parking_spaces;
@@ -0,0 +1,11 @@
+-- status: 0 IN_MAINTANANCE, 1 AVAILABLE, 2 BOOKED
+CREATE TABLE parking_spaces (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  status TINYINT NOT NULL DEFAULT 1,
+  parking_lots_id INT UNSIGNED NOT NULL,
+  PRIMARY KEY (id),
+  KEY parking_spaces_parking_lots_id_status (parking_lots_id, status),
+  CONSTRAINT fk_parking_spaces_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
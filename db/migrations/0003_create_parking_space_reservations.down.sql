@@ -0,0 +1 @@
+DROP TABLE parking_space_reservations;
@@ -0,0 +1,12 @@
+CREATE TABLE parking_space_reservations (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  user_id INT NOT NULL,
+  start_time DATETIME NOT NULL,
+  end_time DATETIME NULL,
+  fee INT NOT NULL DEFAULT 0,
+  parking_spaces_id INT UNSIGNED NOT NULL,
+  PRIMARY KEY (id),
+  KEY parking_space_reservations_end_time (end_time),
+  CONSTRAINT fk_parking_space_reservations_parking_spaces
+    FOREIGN KEY (parking_spaces_id) REFERENCES parking_spaces (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+ALTER TABLE parking_space_reservations
+  DROP FOREIGN KEY fk_parking_space_reservations_rate_plans,
+  DROP COLUMN rate_plans_id;
+
+DROP TABLE rate_plans;
@@ -0,0 +1,23 @@
+-- rate plans are never updated, a new row replaces the current plan of a lot
+-- so reservations keep pointing to the plan they were charged with
+CREATE TABLE rate_plans (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  parking_lots_id INT UNSIGNED NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  first_hour_rate INT NOT NULL,
+  hourly_rate INT NOT NULL,
+  billing_increment INT NOT NULL DEFAULT 60,
+  grace_period INT NOT NULL DEFAULT 0,
+  daily_max INT NOT NULL DEFAULT 0,
+  free_minutes INT NOT NULL DEFAULT 0,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  KEY rate_plans_parking_lots_id (parking_lots_id),
+  CONSTRAINT fk_rate_plans_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE parking_space_reservations
+  ADD COLUMN rate_plans_id INT UNSIGNED NULL,
+  ADD CONSTRAINT fk_parking_space_reservations_rate_plans
+    FOREIGN KEY (rate_plans_id) REFERENCES rate_plans (id);
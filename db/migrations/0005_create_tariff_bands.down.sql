@@ -0,0 +1,4 @@
+DROP TABLE tariff_bands;
+
+ALTER TABLE parking_lots
+  DROP COLUMN time_zone;
@@ -0,0 +1,20 @@
+-- tariff bands are evaluated in the local time of the parking lot
+ALTER TABLE parking_lots
+  ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- days is a comma separated list of weekdays (mon,tue,...), empty for every day
+-- start_time and end_time are HH:MM
+CREATE TABLE tariff_bands (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  rate_plans_id INT UNSIGNED NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  kind VARCHAR(16) NOT NULL,
+  days VARCHAR(32) NOT NULL DEFAULT '',
+  start_time CHAR(5) NOT NULL,
+  end_time CHAR(5) NOT NULL,
+  amount INT NOT NULL,
+  PRIMARY KEY (id),
+  KEY tariff_bands_rate_plans_id (rate_plans_id),
+  CONSTRAINT fk_tariff_bands_rate_plans
+    FOREIGN KEY (rate_plans_id) REFERENCES rate_plans (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,7 @@
+DROP TABLE rate_plan_vehicle_rates;
+
+ALTER TABLE parking_space_reservations
+  DROP COLUMN vehicle_type;
+
+ALTER TABLE parking_spaces
+  DROP COLUMN size_class;
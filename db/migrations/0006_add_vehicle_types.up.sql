@@ -0,0 +1,15 @@
+-- size_class: 0 motorcycle, 1 compact, 2 regular, 3 large
+ALTER TABLE parking_spaces
+  ADD COLUMN size_class TINYINT NOT NULL DEFAULT 2;
+
+ALTER TABLE parking_space_reservations
+  ADD COLUMN vehicle_type VARCHAR(16) NOT NULL DEFAULT 'car';
+
+CREATE TABLE rate_plan_vehicle_rates (
+  rate_plans_id INT UNSIGNED NOT NULL,
+  vehicle_type VARCHAR(16) NOT NULL,
+  percent INT NOT NULL,
+  PRIMARY KEY (rate_plans_id, vehicle_type),
+  CONSTRAINT fk_rate_plan_vehicle_rates_rate_plans
+    FOREIGN KEY (rate_plans_id) REFERENCES rate_plans (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
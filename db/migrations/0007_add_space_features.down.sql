@@ -0,0 +1,5 @@
+ALTER TABLE parking_lots
+  DROP COLUMN allow_tagged_overflow;
+
+ALTER TABLE parking_spaces
+  DROP COLUMN features;
@@ -0,0 +1,8 @@
+-- features is a bit mask: 1 ev_charger, 2 accessible, 4 family, 8 staff_only
+ALTER TABLE parking_spaces
+  ADD COLUMN features TINYINT UNSIGNED NOT NULL DEFAULT 0;
+
+-- whether requests may get spaces with features they did not ask for when no
+-- other space is available, staff only spaces are never given out this way
+ALTER TABLE parking_lots
+  ADD COLUMN allow_tagged_overflow TINYINT(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+ALTER TABLE parking_spaces
+  DROP FOREIGN KEY fk_parking_spaces_zones,
+  DROP INDEX parking_spaces_parking_lots_id_slot_number,
+  DROP INDEX parking_spaces_parking_lots_id_label,
+  DROP COLUMN zones_id,
+  DROP COLUMN slot_number,
+  DROP COLUMN label;
+
+DROP TABLE zones;
+
+DROP TABLE levels;
@@ -0,0 +1,44 @@
+-- ordinal sorts levels from the lowest to the highest, e.g. B2 -2, B1 -1, G 0
+CREATE TABLE levels (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  parking_lots_id INT UNSIGNED NOT NULL,
+  code VARCHAR(8) NOT NULL,
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  ordinal INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  UNIQUE KEY levels_parking_lots_id_code (parking_lots_id, code),
+  CONSTRAINT fk_levels_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE zones (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  levels_id INT UNSIGNED NOT NULL,
+  code VARCHAR(8) NOT NULL,
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (id),
+  UNIQUE KEY zones_levels_id_code (levels_id, code),
+  CONSTRAINT fk_zones_levels
+    FOREIGN KEY (levels_id) REFERENCES levels (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- slot_number and label are assigned once when a space is created
+ALTER TABLE parking_spaces
+  ADD COLUMN zones_id INT UNSIGNED NULL,
+  ADD COLUMN slot_number INT NOT NULL DEFAULT 0,
+  ADD COLUMN label VARCHAR(32) NOT NULL DEFAULT '',
+  ADD CONSTRAINT fk_parking_spaces_zones
+    FOREIGN KEY (zones_id) REFERENCES zones (id);
+
+-- existing spaces keep the slot numbers they were shown with so far
+UPDATE parking_spaces
+  INNER JOIN (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY parking_lots_id ORDER BY created_at, id) AS slot_number
+    FROM parking_spaces
+  ) numbered ON numbered.id = parking_spaces.id
+  SET parking_spaces.slot_number = numbered.slot_number,
+    parking_spaces.label = LPAD(numbered.slot_number, 3, '0');
+
+ALTER TABLE parking_spaces
+  ADD UNIQUE KEY parking_spaces_parking_lots_id_slot_number (parking_lots_id, slot_number),
+  ADD UNIQUE KEY parking_spaces_parking_lots_id_label (parking_lots_id, label);
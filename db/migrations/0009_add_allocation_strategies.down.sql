@@ -0,0 +1,5 @@
+ALTER TABLE parking_lots
+  DROP COLUMN allocation_strategy;
+
+ALTER TABLE parking_spaces
+  DROP COLUMN distance_to_entrance;
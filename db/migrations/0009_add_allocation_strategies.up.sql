@@ -0,0 +1,6 @@
+-- distance_to_entrance is in meters, NULL when not measured
+ALTER TABLE parking_spaces
+  ADD COLUMN distance_to_entrance INT UNSIGNED NULL;
+
+ALTER TABLE parking_lots
+  ADD COLUMN allocation_strategy VARCHAR(32) NOT NULL DEFAULT 'first_available';
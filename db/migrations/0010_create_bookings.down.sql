@@ -0,0 +1,5 @@
+ALTER TABLE parking_lots
+  DROP COLUMN booking_grace_period,
+  DROP COLUMN no_show_fee;
+
+DROP TABLE bookings;
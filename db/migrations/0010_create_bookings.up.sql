@@ -0,0 +1,30 @@
+-- a booking holds a parking space for a future window, status is held until
+-- the driver arrives (arrived) or the grace period passes (released)
+CREATE TABLE bookings (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  parking_lots_id INT UNSIGNED NOT NULL,
+  parking_spaces_id INT UNSIGNED NOT NULL,
+  user_id INT NOT NULL,
+  vehicle_type VARCHAR(16) NOT NULL DEFAULT 'car',
+  features TINYINT UNSIGNED NOT NULL DEFAULT 0,
+  start_time DATETIME NOT NULL,
+  end_time DATETIME NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'held',
+  parking_space_reservations_id INT UNSIGNED NULL,
+  no_show_fee INT NOT NULL DEFAULT 0,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  KEY bookings_parking_spaces_id_status (parking_spaces_id, status, start_time),
+  KEY bookings_status_start_time (status, start_time),
+  CONSTRAINT fk_bookings_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id),
+  CONSTRAINT fk_bookings_parking_spaces
+    FOREIGN KEY (parking_spaces_id) REFERENCES parking_spaces (id),
+  CONSTRAINT fk_bookings_parking_space_reservations
+    FOREIGN KEY (parking_space_reservations_id) REFERENCES parking_space_reservations (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- booking_grace_period is in minutes
+ALTER TABLE parking_lots
+  ADD COLUMN booking_grace_period INT NOT NULL DEFAULT 15,
+  ADD COLUMN no_show_fee INT NOT NULL DEFAULT 0;
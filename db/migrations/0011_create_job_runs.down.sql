@@ -0,0 +1,7 @@
+ALTER TABLE parking_space_reservations
+  DROP COLUMN overstayed_at;
+
+ALTER TABLE parking_lots
+  DROP COLUMN max_stay;
+
+DROP TABLE job_runs;
@@ -0,0 +1,20 @@
+-- status is succeeded or failed, error holds the message of a failed run
+CREATE TABLE job_runs (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(64) NOT NULL,
+  started_at DATETIME NOT NULL,
+  finished_at DATETIME NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  affected INT NOT NULL DEFAULT 0,
+  error TEXT NULL,
+  PRIMARY KEY (id),
+  KEY job_runs_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- max_stay is in minutes, NULL when vehicles may stay as long as they like
+ALTER TABLE parking_lots
+  ADD COLUMN max_stay INT NULL;
+
+-- overstayed_at is when a reservation was flagged for exceeding max_stay
+ALTER TABLE parking_space_reservations
+  ADD COLUMN overstayed_at DATETIME NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE parking_space_reservations
+  DROP FOREIGN KEY fk_parking_space_reservations_vehicles,
+  DROP INDEX parking_space_reservations_active_vehicles_id,
+  DROP COLUMN active_vehicles_id,
+  DROP COLUMN vehicles_id;
+
+DROP TABLE vehicles;
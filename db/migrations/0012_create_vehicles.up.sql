@@ -0,0 +1,20 @@
+-- plate is normalised to upper case letters and digits
+CREATE TABLE vehicles (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  plate VARCHAR(16) NOT NULL,
+  user_id INT NOT NULL,
+  vehicle_type VARCHAR(16) NOT NULL DEFAULT 'car',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY vehicles_plate (plate),
+  KEY vehicles_user_id (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- active_vehicles_id is only set while the reservation is active so a vehicle
+-- can not have two active reservations
+ALTER TABLE parking_space_reservations
+  ADD COLUMN vehicles_id INT UNSIGNED NULL,
+  ADD COLUMN active_vehicles_id INT UNSIGNED AS (IF(end_time IS NULL, vehicles_id, NULL)) STORED,
+  ADD CONSTRAINT fk_parking_space_reservations_vehicles
+    FOREIGN KEY (vehicles_id) REFERENCES vehicles (id),
+  ADD UNIQUE KEY parking_space_reservations_active_vehicles_id (active_vehicles_id);
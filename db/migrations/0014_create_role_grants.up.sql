@@ -0,0 +1,15 @@
+-- a grant without parking_lots_id applies to every parking lot, admin grants
+-- are always global
+CREATE TABLE role_grants (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  users_id INT UNSIGNED NOT NULL,
+  role VARCHAR(16) NOT NULL,
+  parking_lots_id INT UNSIGNED NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  KEY role_grants_users_id (users_id),
+  CONSTRAINT fk_role_grants_users
+    FOREIGN KEY (users_id) REFERENCES users (id),
+  CONSTRAINT fk_role_grants_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+DROP TABLE api_key_parking_lots;
+DROP TABLE api_keys;
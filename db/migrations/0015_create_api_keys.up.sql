@@ -0,0 +1,25 @@
+-- key_id is the public part of a key it is looked up by, secret_hash is the
+-- SHA-256 of the secret part. A rotated key keeps working until expires_at.
+CREATE TABLE api_keys (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  key_id CHAR(12) NOT NULL,
+  secret_hash CHAR(64) NOT NULL,
+  actions SET('park', 'unpark') NOT NULL,
+  created_at DATETIME NOT NULL,
+  expires_at DATETIME NULL,
+  revoked_at DATETIME NULL,
+  last_used_at DATETIME NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY api_keys_key_id (key_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE api_key_parking_lots (
+  api_keys_id INT UNSIGNED NOT NULL,
+  parking_lots_id INT UNSIGNED NOT NULL,
+  PRIMARY KEY (api_keys_id, parking_lots_id),
+  CONSTRAINT fk_api_key_parking_lots_api_keys
+    FOREIGN KEY (api_keys_id) REFERENCES api_keys (id),
+  CONSTRAINT fk_api_key_parking_lots_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
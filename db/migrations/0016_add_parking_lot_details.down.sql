@@ -0,0 +1,11 @@
+DROP TABLE parking_lot_opening_hours;
+
+ALTER TABLE parking_lots
+  DROP COLUMN address,
+  DROP COLUMN latitude,
+  DROP COLUMN longitude,
+  DROP COLUMN capacity,
+  DROP COLUMN contact_name,
+  DROP COLUMN contact_phone,
+  DROP COLUMN contact_email,
+  DROP COLUMN archived_at;
@@ -0,0 +1,25 @@
+-- capacity is the most spaces a parking lot may have, NULL for no limit. An
+-- archived parking lot is kept for the history of its reservations only.
+ALTER TABLE parking_lots
+  ADD COLUMN address VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN latitude DECIMAL(9,6) NULL,
+  ADD COLUMN longitude DECIMAL(9,6) NULL,
+  ADD COLUMN capacity INT UNSIGNED NULL,
+  ADD COLUMN contact_name VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN contact_phone VARCHAR(32) NOT NULL DEFAULT '',
+  ADD COLUMN contact_email VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN archived_at DATETIME NULL;
+
+-- days is a comma separated list of weekdays (mon,tue,...), empty for every day
+-- open_time and close_time are HH:MM in the time zone of the parking lot
+CREATE TABLE parking_lot_opening_hours (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  parking_lots_id INT UNSIGNED NOT NULL,
+  days VARCHAR(32) NOT NULL DEFAULT '',
+  open_time CHAR(5) NOT NULL,
+  close_time CHAR(5) NOT NULL,
+  PRIMARY KEY (id),
+  KEY parking_lot_opening_hours_parking_lots_id (parking_lots_id),
+  CONSTRAINT fk_parking_lot_opening_hours_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
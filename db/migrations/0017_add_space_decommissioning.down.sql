@@ -0,0 +1,4 @@
+UPDATE parking_spaces SET status = 0 WHERE status = 3;
+
+ALTER TABLE parking_spaces
+  DROP COLUMN decommissioned_at;
@@ -0,0 +1,4 @@
+-- status 3 is DECOMMISSIONED, such spaces are never allocated again but are
+-- kept for the history of their reservations
+ALTER TABLE parking_spaces
+  ADD COLUMN decommissioned_at DATETIME NULL;
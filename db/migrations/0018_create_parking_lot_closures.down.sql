@@ -0,0 +1,4 @@
+DROP TABLE parking_lot_closures;
+
+ALTER TABLE parking_lots
+  DROP COLUMN closing_policy;
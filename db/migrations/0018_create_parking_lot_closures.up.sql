@@ -0,0 +1,18 @@
+-- closing_policy: stay, flag or unpark, see hours.ClosingPolicy
+ALTER TABLE parking_lots
+  ADD COLUMN closing_policy VARCHAR(16) NOT NULL DEFAULT 'stay';
+
+-- kind is holiday or ad_hoc, a holiday lasts whole local days of the parking
+-- lot. The parking lot is closed from start_time until end_time.
+CREATE TABLE parking_lot_closures (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  parking_lots_id INT UNSIGNED NOT NULL,
+  kind VARCHAR(16) NOT NULL,
+  reason VARCHAR(255) NOT NULL DEFAULT '',
+  start_time DATETIME NOT NULL,
+  end_time DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY parking_lot_closures_parking_lots_id_end_time (parking_lots_id, end_time),
+  CONSTRAINT fk_parking_lot_closures_parking_lots
+    FOREIGN KEY (parking_lots_id) REFERENCES parking_lots (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+DROP TABLE payments;
+
+ALTER TABLE parking_lots
+  DROP COLUMN require_payment;
@@ -0,0 +1,21 @@
+-- require_payment: unparked reservations with a fee must be paid before the
+-- vehicle may exit
+ALTER TABLE parking_lots
+  ADD COLUMN require_payment BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- status: pending, processing, authorised, captured, failed or refunded, see
+-- payment.Status. reference identifies the payment at the gateway.
+CREATE TABLE payments (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  parking_space_reservations_id INT UNSIGNED NOT NULL,
+  amount INT UNSIGNED NOT NULL,
+  status VARCHAR(16) NOT NULL,
+  reference VARCHAR(255) NOT NULL DEFAULT '',
+  failure_reason VARCHAR(255) NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL,
+  updated_at DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY payments_parking_space_reservations_id (parking_space_reservations_id),
+  CONSTRAINT fk_payments_parking_space_reservations
+    FOREIGN KEY (parking_space_reservations_id) REFERENCES parking_space_reservations (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
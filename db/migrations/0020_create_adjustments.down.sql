@@ -0,0 +1,4 @@
+DROP TABLE adjustments;
+
+ALTER TABLE payments
+  DROP COLUMN refunded_amount;
@@ -0,0 +1,26 @@
+-- refunded_amount is how much of a captured payment was refunded, the payment
+-- is refunded once all of it was
+ALTER TABLE payments
+  ADD COLUMN refunded_amount INT UNSIGNED NOT NULL DEFAULT 0;
+
+-- kind: refund or waiver, reason: see db.AdjustmentReason. A refund returns
+-- money of payments_id, a waiver lowers what is left to pay of an unpaid fee.
+-- user_id is the manager who made the adjustment.
+CREATE TABLE adjustments (
+  id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+  parking_space_reservations_id INT UNSIGNED NOT NULL,
+  payments_id INT UNSIGNED NULL,
+  user_id INT NOT NULL,
+  kind VARCHAR(16) NOT NULL,
+  reason VARCHAR(32) NOT NULL,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  amount INT UNSIGNED NOT NULL,
+  created_at DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  KEY adjustments_parking_space_reservations_id (parking_space_reservations_id),
+  KEY adjustments_created_at (created_at),
+  CONSTRAINT fk_adjustments_parking_space_reservations
+    FOREIGN KEY (parking_space_reservations_id) REFERENCES parking_space_reservations (id),
+  CONSTRAINT fk_adjustments_payments
+    FOREIGN KEY (payments_id) REFERENCES payments (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+ALTER TABLE adjustments
+  DROP COLUMN status,
+  DROP COLUMN failure_reason;
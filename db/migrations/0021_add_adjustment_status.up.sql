@@ -0,0 +1,6 @@
+-- status: pending, done or failed, see db.AdjustmentStatus. A refund is pending
+-- until the gateway returned the money, its amount is part of the refunded
+-- amount of the payment meanwhile and is given back when it failed.
+ALTER TABLE adjustments
+  ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'done',
+  ADD COLUMN failure_reason VARCHAR(255) NOT NULL DEFAULT '';
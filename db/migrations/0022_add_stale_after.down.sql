@@ -0,0 +1,2 @@
+ALTER TABLE parking_lots
+  DROP COLUMN stale_after;
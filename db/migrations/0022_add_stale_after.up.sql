@@ -0,0 +1,4 @@
+-- stale_after is in minutes, active reservations older than it are unparked as
+-- vehicles that left without unparking. NULL never unparks them.
+ALTER TABLE parking_lots
+  ADD COLUMN stale_after INT NULL;
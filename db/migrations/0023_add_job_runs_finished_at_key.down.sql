@@ -0,0 +1,2 @@
+ALTER TABLE job_runs
+  DROP KEY job_runs_finished_at;
@@ -0,0 +1,3 @@
+-- job runs are pruned by finished_at
+ALTER TABLE job_runs
+  ADD KEY job_runs_finished_at (finished_at);
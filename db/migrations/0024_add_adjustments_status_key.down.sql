@@ -0,0 +1,2 @@
+ALTER TABLE adjustments
+  DROP KEY adjustments_status_created_at;
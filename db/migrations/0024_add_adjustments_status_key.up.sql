@@ -0,0 +1,3 @@
+-- pending refunds are looked up to be reconciled with the payment gateway
+ALTER TABLE adjustments
+  ADD KEY adjustments_status_created_at (status, created_at);
@@ -0,0 +1,4 @@
+-- plate is the registered vehicle a booking is for, empty for any vehicle. The
+-- reservation of the arrival gets its vehicles_id.
+ALTER TABLE bookings
+  ADD COLUMN plate VARCHAR(16) NOT NULL DEFAULT '';
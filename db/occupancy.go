@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/space"
+)
+
+// occupancyHistory is how far back finished stays are averaged to estimate when
+// the next space is left
+const occupancyHistory = 30 * 24 * time.Hour
+
+// Occupancy of a parking lot, spaces in maintenance or decommissioned are not
+// counted. NextFreeAt is when the next parked vehicle is expected to leave after
+// the average stay in the parking lot, it is nil without recent stays or when
+// every parked vehicle is staying longer than average.
+type Occupancy struct {
+	ParkingLotID int        `json:"parking_lot_id"`
+	Spaces       int        `json:"spaces"`
+	Occupied     int        `json:"occupied"`
+	Available    int        `json:"available"`
+	NextFreeAt   *time.Time `json:"next_free_at"`
+}
+
+// ParkingLotSuggestion is another parking lot with spaces available for a park
+// request. Distance is in meters from the parking lot that is full, nil when one
+// of them has no coordinates.
+type ParkingLotSuggestion struct {
+	ParkingLotID int    `json:"parking_lot_id"`
+	Name         string `json:"name"`
+	Available    int    `json:"available"`
+	Distance     *int   `json:"distance"`
+}
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371000
+
+// distance returns the great-circle distance between two parking lots in
+// meters, nil when one of them has no coordinates.
+func distance(a, b ParkingLot) *int {
+	if a.Latitude == nil || a.Longitude == nil || b.Latitude == nil || b.Longitude == nil {
+		return nil
+	}
+
+	radians := func(degrees float64) float64 {
+		return degrees * math.Pi / 180
+	}
+	lat1, lat2 := radians(*a.Latitude), radians(*b.Latitude)
+	dLat, dLng := lat2-lat1, radians(*b.Longitude-*a.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	d := int(math.Round(2 * earthRadius * math.Asin(math.Sqrt(h))))
+
+	return &d
+}
+
+// sortSuggestions orders suggestions by distance, the ones without a distance
+// last, and then by the most available spaces.
+func sortSuggestions(suggestions []ParkingLotSuggestion) {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Distance == nil || b.Distance == nil || *a.Distance == *b.Distance {
+			if (a.Distance == nil) != (b.Distance == nil) {
+				return b.Distance == nil
+			}
+			return a.Available > b.Available
+		}
+
+		return *a.Distance < *b.Distance
+	})
+}
+
+func (d *DB) GetOccupancyByParkingLot(parkingLotID int) (Occupancy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	o := Occupancy{
+		ParkingLotID: parkingLotID,
+	}
+	err := d.dbConn.QueryRowContext(ctx, `SELECT COUNT(id), COALESCE(SUM(status = ?), 0), COALESCE(SUM(status = ?), 0)
+																				FROM parking_spaces
+																				WHERE parking_lots_id = ? and status in (?, ?)`,
+		booked, available, parkingLotID, available, booked).
+		Scan(&o.Spaces, &o.Occupied, &o.Available)
+	if err != nil {
+		return Occupancy{}, err
+	}
+
+	now := time.Now().UTC()
+	var averageStay sql.NullFloat64
+	err = d.dbConn.QueryRowContext(ctx, `SELECT AVG(TIMESTAMPDIFF(SECOND, start_time, end_time))
+																				FROM parking_space_reservations
+																				INNER JOIN parking_spaces
+																				ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																				WHERE parking_spaces.parking_lots_id = ?
+																				and end_time >= ?`,
+		parkingLotID, now.Add(-occupancyHistory).Format(dateFormat)).
+		Scan(&averageStay)
+	if err != nil || !averageStay.Valid {
+		return o, err
+	}
+
+	stay := time.Duration(averageStay.Float64) * time.Second
+	var firstStart sql.NullString
+	err = d.dbConn.QueryRowContext(ctx, `SELECT MIN(start_time)
+																				FROM parking_space_reservations
+																				INNER JOIN parking_spaces
+																				ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																				WHERE parking_spaces.parking_lots_id = ?
+																				and end_time IS NULL
+																				and start_time > ?`,
+		parkingLotID, now.Add(-stay).Format(dateFormat)).
+		Scan(&firstStart)
+	if err != nil {
+		return Occupancy{}, err
+	}
+
+	start, err := parseNullTime(firstStart)
+	if err != nil || start == nil {
+		return o, err
+	}
+
+	nextFreeAt := start.Add(stay)
+	o.NextFreeAt = &nextFreeAt
+
+	return o, nil
+}
+
+// GetParkingLotSuggestions returns up to limit other parking lots that are open
+// and have spaces available for the request, the nearest first, see
+// sortSuggestions. Spaces held by a booking are not counted as they are not
+// given to walk-ins.
+func (d *DB) GetParkingLotSuggestions(parkingLotID int, pr ParkRequest, limit int) ([]ParkingLotSuggestion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var (
+		origin              ParkingLot
+		latitude, longitude sql.NullFloat64
+	)
+	err := d.dbConn.QueryRowContext(ctx, `SELECT latitude, longitude FROM parking_lots WHERE id = ?`, parkingLotID).
+		Scan(&latitude, &longitude)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	origin.Latitude, origin.Longitude = nullFloatPtr(latitude), nullFloatPtr(longitude)
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT parking_lots.id, parking_lots.name, parking_lots.time_zone,
+																						 parking_lots.latitude, parking_lots.longitude,
+																						 COUNT(parking_spaces.id) AS available
+																						 FROM parking_lots
+																						 INNER JOIN parking_spaces ON parking_spaces.parking_lots_id = parking_lots.id
+																						 WHERE parking_lots.id <> ? and archived_at IS NULL
+																						 and parking_spaces.status = ?
+																						 and size_class >= ?
+																						 and (features & ?) = ?
+																						 and (
+																							(features & ~?) = 0
+																							or (parking_lots.allow_tagged_overflow and (features & ~? & ?) = 0)
+																						 )
+																						 and NOT EXISTS (
+																							SELECT bookings.id FROM bookings
+																							WHERE bookings.parking_spaces_id = parking_spaces.id
+																							and bookings.status = ?
+																							and bookings.start_time >= DATE_SUB(?, INTERVAL parking_lots.booking_grace_period MINUTE)
+																						 )
+																						 GROUP BY parking_lots.id, parking_lots.name, parking_lots.time_zone,
+																						 parking_lots.latitude, parking_lots.longitude
+																						 order by parking_lots.id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx,
+		parkingLotID, available, pr.VehicleType.MinSizeClass(),
+		pr.Features, pr.Features,
+		pr.Features,
+		pr.Features, space.StaffOnly,
+		BookingHeld, time.Now().UTC().Format(dateFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		suggestions []ParkingLotSuggestion
+		parkingLots = map[int]ParkingLot{}
+	)
+	for rows.Next() {
+		var (
+			s  ParkingLotSuggestion
+			pl ParkingLot
+		)
+		if err := rows.Scan(&s.ParkingLotID, &s.Name, &pl.TimeZone, &latitude, &longitude, &s.Available); err != nil {
+			return nil, err
+		}
+
+		pl.ID = s.ParkingLotID
+		pl.Latitude, pl.Longitude = nullFloatPtr(latitude), nullFloatPtr(longitude)
+		s.Distance = distance(origin, pl)
+		parkingLots[pl.ID] = pl
+		suggestions = append(suggestions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortSuggestions(suggestions)
+
+	// the nearest parking lots are checked until there are enough open ones
+	now := time.Now().UTC()
+	open := []ParkingLotSuggestion{}
+	for _, s := range suggestions {
+		if len(open) == limit {
+			break
+		}
+
+		ok, err := parkingLotOpen(ctx, d.dbConn, parkingLots[s.ParkingLotID], now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			open = append(open, s)
+		}
+	}
+
+	return open, nil
+}
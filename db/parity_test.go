@@ -0,0 +1,118 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/pricing"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// parityResult is what a scenario observes through a repository, free of the
+// ids and times that differ between them.
+type parityResult struct {
+	Slots    []int // of the parked vehicles, in parking order
+	Held     int   // slot of the booking
+	Full     bool  // the last park found the parking lot full
+	Quote    int   // of a vehicle still parked, 90 minutes on
+	Vehicles int
+	Fee      int
+	Net      int
+}
+
+func paritySlot(t *testing.T, repo Repository, parkingLotID, parkingSpaceID int) int {
+	t.Helper()
+
+	ps, err := repo.GetParkingSpaceByParkingLotIDAndID(parkingLotID, parkingSpaceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ps.SlotNumber
+}
+
+// runParityScenario books a parking space, parks walk-ins until the parking lot
+// is full and unparks two of them within the free minutes.
+func runParityScenario(t *testing.T, repo Repository) parityResult {
+	t.Helper()
+
+	var r parityResult
+	parkingLotID := createTestParkingLot(t, repo, 4)
+	_, err := repo.CreateRatePlan(parkingLotID, pricing.RatePlan{
+		Name:             "parity",
+		FirstHourRate:    300,
+		HourlyRate:       200,
+		BillingIncrement: 60,
+		FreeMinutes:      30,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := repo.CreateBooking(parkingLotID, testBooking(2*time.Hour, 4*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Held = paritySlot(t, repo, parkingLotID, b.ParkingSpaceID)
+
+	var psrIDs []int64
+	for {
+		psrID, loc, err := repo.ParkParkingSpaceByParkingLot(parkingLotID, ParkRequest{VehicleType: vehicle.Car})
+		if err == ErrLotFull {
+			r.Full = true
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(psrIDs) == 4 {
+			t.Fatal("parked more vehicles than the parking lot has parking spaces")
+		}
+		psrIDs = append(psrIDs, psrID)
+		r.Slots = append(r.Slots, loc.SlotNumber)
+	}
+
+	for _, id := range psrIDs[:2] {
+		if _, _, err := repo.UnParkParkingSpaceByID(int(id), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	quote, err := repo.QuoteParkingSpaceReservation(int(psrIDs[2]), time.Now().Add(90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Quote = quote.Total
+
+	report, err := repo.GetDailyReportByParkingLot(parkingLotID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Vehicles, r.Fee, r.Net = report.TotalVehicles, report.TotalFee, report.NetRevenue
+
+	return r
+}
+
+// TestRepositoryParity runs the same scenario on every repository, MemoryDB
+// must behave like the database it stands in for.
+func TestRepositoryParity(t *testing.T) {
+	want := parityResult{
+		Slots:    []int{2, 3, 4},
+		Held:     1,
+		Full:     true,
+		Vehicles: 2,
+		Quote:    300,
+	}
+
+	got := map[string]parityResult{}
+	for name, repo := range testRepositories(t) {
+		got[name] = runParityScenario(t, repo)
+	}
+
+	for name, r := range got {
+		if !reflect.DeepEqual(r, want) {
+			t.Errorf("%s: %+v, want %+v", name, r, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/arifmahmudrana/parking-lot/allocation"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// testRepositories returns the repositories to test, MySQL only when
+// TEST_MYSQL_DSN is set. The MySQL database is migrated up and not cleaned, the
+// tests create their own parking lots.
+func testRepositories(t *testing.T) map[string]Repository {
+	t.Helper()
+
+	repositories := map[string]Repository{
+		"memory": NewMemoryDB(),
+	}
+
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		return repositories
+	}
+
+	d, err := NewDB(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		d.Close()
+	})
+	if _, err := d.MigrateUp(); err != nil {
+		t.Fatal(err)
+	}
+	repositories["mysql"] = d
+
+	return repositories
+}
+
+func TestParkParkingSpaceByParkingLotConcurrently(t *testing.T) {
+	const (
+		spaces = 20
+		parks  = 300
+	)
+
+	for name, repo := range testRepositories(t) {
+		repo := repo
+		t.Run(name, func(t *testing.T) {
+			id, err := repo.CreateParkingLot(ParkingLot{
+				Name:               "concurrency",
+				TimeZone:           "UTC",
+				AllocationStrategy: allocation.FirstAvailable,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			parkingLotID := int(id)
+			for i := 0; i < spaces; i++ {
+				if _, err := repo.CreateParkingSpaceFromParkingLotID(parkingLotID, ParkingSpace{SizeClass: vehicle.SizeRegular}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var (
+				wg     sync.WaitGroup
+				mu     sync.Mutex
+				taken  = map[int]int64{} // parking space to reservation
+				parked int
+			)
+			for i := 0; i < parks; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					psrID, loc, err := repo.ParkParkingSpaceByParkingLot(parkingLotID, ParkRequest{VehicleType: vehicle.Car})
+					if err == ErrLotFull {
+						return
+					}
+					if err != nil {
+						t.Error(err)
+						return
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					if other, ok := taken[loc.ParkingSpaceID]; ok {
+						t.Errorf("parking space %d given to reservations %d and %d", loc.ParkingSpaceID, other, psrID)
+					}
+					taken[loc.ParkingSpaceID] = psrID
+					parked++
+				}()
+			}
+			wg.Wait()
+
+			if parked != spaces {
+				t.Errorf("parked %d vehicles, want %d", parked, spaces)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/payment"
+)
+
+// paymentClaimTimeout is how long a payment may be processing before it is
+// taken as abandoned, it is well above the time a gateway takes to settle.
+const paymentClaimTimeout = 2 * time.Minute
+
+// Payment of the fee of a parking space reservation. A reservation has a
+// payment for every attempt to settle it, the latest one is its settlement.
+type Payment struct {
+	ID                        int            `json:"id"`
+	ParkingSpaceReservationID int            `json:"parking_space_reservation_id"`
+	Amount                    int            `json:"amount"`
+	RefundedAmount            int            `json:"refunded_amount"`
+	Status                    payment.Status `json:"status"`
+	Reference                 string         `json:"reference"`
+	FailureReason             string         `json:"failure_reason"`
+	CreatedAt                 time.Time      `json:"created_at"`
+	UpdatedAt                 time.Time      `json:"updated_at"`
+}
+
+const paymentColumns = `id, parking_space_reservations_id, amount, refunded_amount, status, reference, failure_reason,
+												created_at, updated_at`
+
+func scanPayment(row interface{ Scan(dest ...any) error }) (Payment, error) {
+	var (
+		p                    Payment
+		createdAt, updatedAt string
+	)
+	err := row.Scan(&p.ID, &p.ParkingSpaceReservationID, &p.Amount, &p.RefundedAmount, &p.Status, &p.Reference,
+		&p.FailureReason, &createdAt, &updatedAt)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if p.CreatedAt, err = time.Parse(dateFormat, createdAt); err != nil {
+		return Payment{}, err
+	}
+	if p.UpdatedAt, err = time.Parse(dateFormat, updatedAt); err != nil {
+		return Payment{}, err
+	}
+
+	return p, nil
+}
+
+// CreatePayment creates a pending payment, sql.ErrNoRows is returned when the
+// reservation does not exist.
+func (d *DB) CreatePayment(p Payment) (Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return insertPayment(ctx, d.dbConn, p)
+}
+
+func insertPayment(ctx context.Context, q preparer, p Payment) (Payment, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	p.Status = payment.Pending
+	p.RefundedAmount = 0
+	p.Reference = ""
+	p.FailureReason = ""
+	p.CreatedAt, p.UpdatedAt = now, now
+
+	stmt, err := q.PrepareContext(ctx, `insert into payments (
+																				parking_space_reservations_id, amount, status, reference, failure_reason,
+																				created_at, updated_at
+																			) values (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return Payment{}, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, p.ParkingSpaceReservationID, p.Amount, p.Status, p.Reference,
+		p.FailureReason, now.Format(dateFormat), now.Format(dateFormat))
+	if isMissingReference(err) {
+		return Payment{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return Payment{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Payment{}, err
+	}
+	p.ID = int(id)
+
+	return p, nil
+}
+
+// UpdatePayment saves the status, reference and failure reason of a payment.
+func (d *DB) UpdatePayment(p Payment) (Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE payments SET status = ?, reference = ?, failure_reason = ?, updated_at = ?
+																						 WHERE (id = ?)`)
+	if err != nil {
+		return Payment{}, err
+	}
+	defer stmt.Close()
+
+	p.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	result, err := stmt.ExecContext(ctx, p.Status, p.Reference, p.FailureReason, p.UpdatedAt.Format(dateFormat), p.ID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return Payment{}, err
+	}
+	if affected != 1 {
+		return Payment{}, sql.ErrNoRows
+	}
+
+	return p, nil
+}
+
+// ClaimPayment takes the payment of what is left to pay of an unparked
+// reservation for the caller, it is the pending payment of the reservation or a
+// new one and is returned processing. The reservation is locked meanwhile so
+// only one caller settles a reservation at a time, a payment processing for
+// longer than paymentClaimTimeout was abandoned and may be claimed again. An
+// authorised payment keeps its status, its settlement continues with the
+// capture. ErrPaymentInProgress is returned while another caller settles the
+// reservation and ErrAlreadyPaid when nothing is left to pay.
+func (d *DB) ClaimPayment(parkingSpaceReservationsID int) (Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return Payment{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		endTime sql.NullString
+		fee     int
+	)
+	err = tx.QueryRowContext(ctx, `SELECT end_time, fee FROM parking_space_reservations WHERE id = ? FOR UPDATE`,
+		parkingSpaceReservationsID).
+		Scan(&endTime, &fee)
+	if err != nil {
+		return Payment{}, err
+	}
+	if !endTime.Valid {
+		return Payment{}, ErrReservationActive
+	}
+
+	var waived int
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM adjustments
+																	WHERE parking_space_reservations_id = ? and kind = ?`,
+		parkingSpaceReservationsID, AdjustmentWaiver).
+		Scan(&waived)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	// the latest payment is the settlement of the reservation
+	p, err := scanPayment(tx.QueryRowContext(ctx, `SELECT `+paymentColumns+`
+																									FROM payments
+																									WHERE parking_space_reservations_id = ?
+																									order by id desc
+																									limit 1`,
+		parkingSpaceReservationsID))
+	if err != nil && err != sql.ErrNoRows {
+		return Payment{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	p, claim, err := claimablePayment(p, fee-waived, now)
+	if err != nil {
+		return Payment{}, err
+	}
+	if !claim {
+		// an adjustment changed what is due, the payment is kept as the history
+		_, err = tx.ExecContext(ctx, `UPDATE payments SET status = ?, failure_reason = ?, updated_at = ? WHERE (id = ?)`,
+			payment.Failed, "fee adjusted", now.Format(dateFormat), p.ID)
+		if err != nil {
+			return Payment{}, err
+		}
+		p = Payment{}
+	}
+
+	if p.ID == 0 {
+		p, err = insertPayment(ctx, tx, Payment{
+			ParkingSpaceReservationID: parkingSpaceReservationsID,
+			Amount:                    fee - waived,
+		})
+		if err != nil {
+			return Payment{}, err
+		}
+	}
+	if p.Status == payment.Pending {
+		p.Status = payment.Processing
+	}
+	p.UpdatedAt = now
+
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET status = ?, updated_at = ? WHERE (id = ?)`,
+		p.Status, now.Format(dateFormat), p.ID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Payment{}, err
+	}
+
+	return p, nil
+}
+
+// claimablePayment decides what becomes of the latest payment p of a
+// reservation with due left to pay. It returns p and true when p is claimed,
+// p and false when p must fail as it is for another amount and a zero payment
+// when a new one is needed.
+func claimablePayment(p Payment, due int, now time.Time) (Payment, bool, error) {
+	switch p.Status {
+	case payment.Captured, payment.Refunded:
+		return Payment{}, false, ErrAlreadyPaid
+	case payment.Processing, payment.Authorised:
+		if now.Sub(p.UpdatedAt) < paymentClaimTimeout {
+			return Payment{}, false, ErrPaymentInProgress
+		}
+	case payment.Pending:
+	default:
+		// failed or no payment yet
+		p = Payment{}
+	}
+	if due <= 0 {
+		return Payment{}, false, ErrAlreadyPaid
+	}
+	if p.ID > 0 && p.Amount != due {
+		return p, false, nil
+	}
+
+	return p, true, nil
+}
+
+// GetPaymentsByReservation returns the payments of a reservation, the latest
+// last.
+func (d *DB) GetPaymentsByReservation(parkingSpaceReservationsID int) ([]Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT `+paymentColumns+`
+																						 FROM payments
+																						 WHERE parking_space_reservations_id = ?
+																						 order by id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, parkingSpaceReservationsID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
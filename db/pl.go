@@ -2,24 +2,128 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/hours"
 )
 
 type ParkingLot struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	TimeZone string `json:"time_zone"` // IANA name, tariff bands are evaluated in it
+
+	Address   string   `json:"address"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+
+	// Capacity is the most spaces the parking lot may have, nil means no limit.
+	Capacity *int `json:"capacity"`
+
+	ContactName  string `json:"contact_name"`
+	ContactPhone string `json:"contact_phone"`
+	ContactEmail string `json:"contact_email"`
+
+	// OpeningHours are in the time zone of the parking lot.
+	OpeningHours  hours.Week          `json:"opening_hours"`
+	ClosingPolicy hours.ClosingPolicy `json:"closing_policy"`
+
+	// AllowTaggedOverflow lets park requests use spaces with features they did
+	// not ask for when no other space is available, except staff only spaces.
+	AllowTaggedOverflow bool `json:"allow_tagged_overflow"`
+
+	// AllocationStrategy names the allocation strategy that picks the space
+	// a vehicle is given.
+	AllocationStrategy string `json:"allocation_strategy"`
+
+	// BookingGracePeriod is how many minutes after the start of a booking the
+	// space is held, a booking without arrival by then is released and records
+	// NoShowFee as owed. The fee is not collected through payments.
+	BookingGracePeriod int `json:"booking_grace_period"`
+	NoShowFee          int `json:"no_show_fee"`
+
+	// MaxStay is in minutes, reservations exceeding it are flagged as
+	// overstays. nil means no limit.
+	MaxStay *int `json:"max_stay"`
+
+	// StaleAfter is in minutes, active reservations older than it are unparked
+	// as vehicles that left without unparking. nil never unparks them.
+	StaleAfter *int `json:"stale_after"`
+
+	// RequirePayment keeps the exit closed until the fee of an unparked
+	// reservation is paid.
+	RequirePayment bool `json:"require_payment"`
+}
+
+const parkingLotColumns = `id, name, time_zone, address, latitude, longitude, capacity,
+													contact_name, contact_phone, contact_email, closing_policy, allow_tagged_overflow,
+													allocation_strategy, booking_grace_period, no_show_fee, max_stay, stale_after, require_payment`
+
+func scanParkingLot(row interface{ Scan(dest ...any) error }) (ParkingLot, error) {
+	var (
+		pl                  ParkingLot
+		latitude, longitude sql.NullFloat64
+		capacity, maxStay   sql.NullInt64
+		staleAfter          sql.NullInt64
+	)
+	err := row.Scan(
+		&pl.ID,
+		&pl.Name,
+		&pl.TimeZone,
+		&pl.Address,
+		&latitude,
+		&longitude,
+		&capacity,
+		&pl.ContactName,
+		&pl.ContactPhone,
+		&pl.ContactEmail,
+		&pl.ClosingPolicy,
+		&pl.AllowTaggedOverflow,
+		&pl.AllocationStrategy,
+		&pl.BookingGracePeriod,
+		&pl.NoShowFee,
+		&maxStay,
+		&staleAfter,
+		&pl.RequirePayment,
+	)
+	if err != nil {
+		return ParkingLot{}, err
+	}
+
+	pl.Latitude, pl.Longitude = nullFloatPtr(latitude), nullFloatPtr(longitude)
+	pl.Capacity = nullIntPtr(capacity)
+	pl.MaxStay = nullIntPtr(maxStay)
+	pl.StaleAfter = nullIntPtr(staleAfter)
+
+	return pl, nil
 }
 
 func (d *DB) CreateParkingLot(pl ParkingLot) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	stmt, err := d.dbConn.PrepareContext(ctx, `insert into parking_lots (name) values (?)`)
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `insert into parking_lots (
+																				name, time_zone, address, latitude, longitude, capacity,
+																				contact_name, contact_phone, contact_email, closing_policy, allow_tagged_overflow,
+																				allocation_strategy, booking_grace_period, no_show_fee, max_stay, stale_after,
+																				require_payment
+																			) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(ctx, pl.Name)
+	result, err := stmt.ExecContext(ctx, pl.Name, pl.TimeZone, pl.Address, floatPtrNull(pl.Latitude),
+		floatPtrNull(pl.Longitude), intPtrNull(pl.Capacity), pl.ContactName, pl.ContactPhone, pl.ContactEmail,
+		pl.ClosingPolicy, pl.AllowTaggedOverflow, pl.AllocationStrategy, pl.BookingGracePeriod, pl.NoShowFee,
+		intPtrNull(pl.MaxStay), intPtrNull(pl.StaleAfter), pl.RequirePayment)
 	if err != nil {
 		return 0, err
 	}
@@ -29,15 +133,82 @@ func (d *DB) CreateParkingLot(pl ParkingLot) (int64, error) {
 		return 0, err
 	}
 
+	if err := createOpeningHours(ctx, tx, int(id), pl.OpeningHours); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
+func createOpeningHours(ctx context.Context, tx *sql.Tx, parkingLotID int, w hours.Week) error {
+	stmt, err := tx.PrepareContext(ctx, `insert into parking_lot_opening_hours (
+																				parking_lots_id, days, open_time, close_time
+																			) values (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range w {
+		_, err := stmt.ExecContext(ctx, parkingLotID, strings.Join(p.Days, ","), p.Open, p.Close)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getOpeningHoursByParkingLot returns the opening hours of a parking lot in the
+// order they were configured.
+func getOpeningHoursByParkingLot(ctx context.Context, q preparer, parkingLotID int) (hours.Week, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT days, open_time, close_time
+																						 FROM parking_lot_opening_hours
+																						 WHERE parking_lots_id = ?
+																						 order by id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, parkingLotID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	w := hours.Week{}
+	for rows.Next() {
+		var (
+			p    hours.Period
+			days string
+		)
+		if err := rows.Scan(&days, &p.Open, &p.Close); err != nil {
+			return nil, err
+		}
+
+		if days != "" {
+			p.Days = strings.Split(days, ",")
+		}
+		w = append(w, p)
+	}
+
+	return w, rows.Err()
+}
+
+// GetParkingLots returns a page of the parking lots that are not archived.
 func (d *DB) GetParkingLots(page int) ([]ParkingLot, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	stmt, err := d.dbConn.PrepareContext(ctx, `select id, name
+	stmt, err := d.dbConn.PrepareContext(ctx, `select `+parkingLotColumns+`
 																             from parking_lots
+																						 where archived_at IS NULL
+																						 order by id asc
 																						 LIMIT ?, ?`)
 	if err != nil {
 		return nil, err
@@ -52,27 +223,186 @@ func (d *DB) GetParkingLots(page int) ([]ParkingLot, error) {
 
 	parkingLots := make([]ParkingLot, 0, size)
 	for rows.Next() {
-		var parkingLot ParkingLot
-		err := rows.Scan(
-			&parkingLot.ID,
-			&parkingLot.Name,
-		)
+		parkingLot, err := scanParkingLot(rows)
 		if err != nil {
 			return nil, err
 		}
 
 		parkingLots = append(parkingLots, parkingLot)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range parkingLots {
+		parkingLots[i].OpeningHours, err = getOpeningHoursByParkingLot(ctx, d.dbConn, parkingLots[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return parkingLots, nil
 }
 
+// GetParkingLotByID returns a parking lot that is not archived.
+func (d *DB) GetParkingLotByID(id int) (ParkingLot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `select `+parkingLotColumns+`
+																						 from parking_lots
+																						 where id = ? and archived_at IS NULL`)
+	if err != nil {
+		return ParkingLot{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, id)
+	if row == nil {
+		return ParkingLot{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return ParkingLot{}, err
+	}
+
+	pl, err := scanParkingLot(row)
+	if err != nil {
+		return ParkingLot{}, err
+	}
+
+	pl.OpeningHours, err = getOpeningHoursByParkingLot(ctx, d.dbConn, pl.ID)
+	if err != nil {
+		return ParkingLot{}, err
+	}
+
+	return pl, nil
+}
+
+// UpdateParkingLot replaces the attributes and the opening hours of a parking
+// lot. ErrLotCapacity is returned when the parking lot has more spaces than the
+// new capacity.
+func (d *DB) UpdateParkingLot(pl ParkingLot) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the parking lot so no space is created while the capacity is checked
+	var lockedID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM parking_lots WHERE id = ? and archived_at IS NULL FOR UPDATE`, pl.ID).
+		Scan(&lockedID)
+	if err != nil {
+		return err
+	}
+
+	var spaces int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(id) FROM parking_spaces WHERE parking_lots_id = ? and status <> ?`,
+		pl.ID, decommissioned).Scan(&spaces)
+	if err != nil {
+		return err
+	}
+	if pl.Capacity != nil && spaces > *pl.Capacity {
+		return ErrLotCapacity
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE parking_lots SET
+																				name = ?, time_zone = ?, address = ?, latitude = ?, longitude = ?, capacity = ?,
+																				contact_name = ?, contact_phone = ?, contact_email = ?, closing_policy = ?,
+																				allow_tagged_overflow = ?,
+																				allocation_strategy = ?, booking_grace_period = ?, no_show_fee = ?, max_stay = ?,
+																				stale_after = ?, require_payment = ?
+																			WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, pl.Name, pl.TimeZone, pl.Address, floatPtrNull(pl.Latitude),
+		floatPtrNull(pl.Longitude), intPtrNull(pl.Capacity), pl.ContactName, pl.ContactPhone, pl.ContactEmail,
+		pl.ClosingPolicy, pl.AllowTaggedOverflow, pl.AllocationStrategy, pl.BookingGracePeriod, pl.NoShowFee,
+		intPtrNull(pl.MaxStay), intPtrNull(pl.StaleAfter), pl.RequirePayment, pl.ID)
+	if err != nil {
+		return err
+	}
+
+	stmt, err = tx.PrepareContext(ctx, `DELETE FROM parking_lot_opening_hours WHERE (parking_lots_id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, pl.ID); err != nil {
+		return err
+	}
+
+	if err := createOpeningHours(ctx, tx, pl.ID, pl.OpeningHours); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ArchiveParkingLot hides a parking lot from the API while keeping the history
+// of its reservations. ErrLotActive is returned while a vehicle is parked in it
+// or a booking is held for it.
+func (d *DB) ArchiveParkingLot(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the parking lot so nothing is parked or booked while it is checked
+	if _, err := lockParkingLotAllocation(ctx, tx, id); err != nil {
+		return err
+	}
+
+	var active bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(
+																	SELECT parking_space_reservations.id
+																	FROM parking_space_reservations
+																	INNER JOIN parking_spaces
+																	ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																	WHERE parking_spaces.parking_lots_id = ? and end_time IS NULL
+																) or EXISTS(
+																	SELECT id FROM bookings WHERE parking_lots_id = ? and status = ?
+																)`, id, id, BookingHeld).Scan(&active)
+	if err != nil {
+		return err
+	}
+	if active {
+		return ErrLotActive
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE parking_lots SET archived_at = ? WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, time.Now().UTC().Format(dateFormat), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (d *DB) GetTotalCountParkingLots() (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
 	query := `select count(id)
 					  from parking_lots
+						where archived_at IS NULL
 						limit 1`
 
 	row := d.dbConn.QueryRowContext(ctx, query)
@@ -93,12 +423,14 @@ func (d *DB) GetTotalCountParkingLots() (int, error) {
 	return count, nil
 }
 
+// DoesParkingLotExistByID reports whether a parking lot exists and is not
+// archived.
 func (d *DB) DoesParkingLotExistByID(parkingLotID int) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
 	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT EXISTS(
-							SELECT id FROM parking_lots WHERE parking_lots.id = ?
+							SELECT id FROM parking_lots WHERE parking_lots.id = ? and archived_at IS NULL
 						) limit 1`)
 
 	if err != nil {
@@ -1,79 +1,222 @@
 package db
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/allocation"
+	"github.com/arifmahmudrana/parking-lot/space"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
 
 // SELECT * FROM parking_lot.parking_spaces WHERE EXISTS (SELECT * FROM parking_lots where parking_lots.id = 1) and parking_spaces.parking_lots_id = 1;
 
 type ParkingSpace struct {
-	ID         int    `json:"id"`
-	Status     string `json:"status"`
-	SlotNumber int    `json:"slot_number"`
+	ID         int               `json:"id"`
+	Status     string            `json:"status"`
+	SlotNumber int               `json:"slot_number"`
+	Label      string            `json:"label"`
+	ZoneID     *int              `json:"zone_id"`
+	SizeClass  vehicle.SizeClass `json:"size_class"`
+	Features   space.Features    `json:"features"`
+
+	// DistanceToEntrance is in meters, the nearest_entrance allocation
+	// strategy takes spaces without it last.
+	DistanceToEntrance *int `json:"distance_to_entrance"`
 }
 
 func (d *DB) GetParkingSpacesByParkingLot(parkingLotID int) ([]ParkingSpace, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT *
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, status, slot_number, label, zones_id, size_class, features, distance_to_entrance
 	                               						 FROM parking_spaces
 																 						 WHERE EXISTS (
 																							SELECT * FROM parking_lots where parking_lots.id = ?
 																 						 ) and parking_spaces.parking_lots_id = ?
-																						 order by created_at asc, parking_spaces.id asc`)
+																						 and status <> ?
+																						 order by slot_number asc`)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.QueryContext(ctx, parkingLotID, parkingLotID)
+	rows, err := stmt.QueryContext(ctx, parkingLotID, parkingLotID, decommissioned)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var parkingSpaces []ParkingSpace
-	slotNumber := 1
 	for rows.Next() {
-		var ps struct {
-			id              int
-			createdAt       string
-			status          int8
-			parking_lots_id int
-		}
+		var (
+			ps               ParkingSpace
+			st               status
+			zoneID, distance sql.NullInt64
+		)
 		err := rows.Scan(
-			&ps.id,
-			&ps.createdAt,
-			&ps.status,
-			&ps.parking_lots_id,
+			&ps.ID,
+			&st,
+			&ps.SlotNumber,
+			&ps.Label,
+			&zoneID,
+			&ps.SizeClass,
+			&ps.Features,
+			&distance,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		parkingSpaces = append(parkingSpaces, ParkingSpace{
-			ID:         ps.id,
-			Status:     status(ps.status).value(),
-			SlotNumber: slotNumber,
-		})
-
-		slotNumber++
+		ps.Status = st.value()
+		ps.ZoneID = nullIntPtr(zoneID)
+		ps.DistanceToEntrance = nullIntPtr(distance)
+		parkingSpaces = append(parkingSpaces, ps)
 	}
 
 	return parkingSpaces, nil
 }
 
-func (d *DB) CreateParkingSpaceFromParkingLotID(plID int) (int64, error) {
+// CreateParkingSpaceFromParkingLotID creates a parking space with the next slot
+// number of the parking lot. Without a label one is generated, <level>-<zone>-<n>
+// for a space in a zone where n counts the spaces of the zone, or the slot
+// number otherwise. sql.ErrNoRows is returned when the parking lot does not
+// exist or the zone is not in it, ErrLotCapacity when the parking lot is at its
+// capacity and ErrDuplicate when the label is taken.
+func (d *DB) CreateParkingSpaceFromParkingLotID(plID int, ps ParkingSpace) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	stmt, err := d.dbConn.PrepareContext(ctx,
-		`insert into parking_spaces (parking_lots_id) values (?)`)
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := lockParkingLotSpaces(ctx, tx, plID, 1); err != nil {
+		return 0, err
+	}
+
+	id, err := createParkingSpace(ctx, tx, plID, ps)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// CreateParkingSpacesFromParkingLotID creates parking spaces like
+// CreateParkingSpaceFromParkingLotID in a single transaction, either all of them
+// are created or none.
+func (d *DB) CreateParkingSpacesFromParkingLotID(plID int, spaces []ParkingSpace) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockParkingLotSpaces(ctx, tx, plID, len(spaces)); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(spaces))
+	for _, ps := range spaces {
+		id, err := createParkingSpace(ctx, tx, plID, ps)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// lockParkingLotSpaces locks the parking lot so concurrent creates get
+// different slot numbers and checks that n more spaces fit its capacity,
+// decommissioned spaces do not count.
+// ErrLotCapacity is returned when they do not.
+func lockParkingLotSpaces(ctx context.Context, tx *sql.Tx, plID, n int) error {
+	var capacity sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT capacity FROM parking_lots WHERE id = ? and archived_at IS NULL FOR UPDATE`, plID).
+		Scan(&capacity)
+	if err != nil || !capacity.Valid {
+		return err
+	}
+
+	var spaces int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(id) FROM parking_spaces WHERE parking_lots_id = ? and status <> ?`,
+		plID, decommissioned).Scan(&spaces)
+	if err != nil {
+		return err
+	}
+	if spaces+n > int(capacity.Int64) {
+		return ErrLotCapacity
+	}
+
+	return nil
+}
+
+// createParkingSpace inserts a parking space, the caller must hold the lock of
+// the parking lot.
+func createParkingSpace(ctx context.Context, tx *sql.Tx, plID int, ps ParkingSpace) (int64, error) {
+	var slotNumber int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(slot_number), 0) + 1 FROM parking_spaces WHERE parking_lots_id = ?`, plID).
+		Scan(&slotNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	label := ps.Label
+	if ps.ZoneID != nil {
+		var levelCode, zoneCode string
+		var n int
+		err := tx.QueryRowContext(ctx, `SELECT levels.code, zones.code,
+																		(SELECT count(id) FROM parking_spaces WHERE zones_id = zones.id) + 1
+																		FROM zones
+																		INNER JOIN levels ON levels.id = zones.levels_id
+																		WHERE zones.id = ? and levels.parking_lots_id = ?`, *ps.ZoneID, plID).
+			Scan(&levelCode, &zoneCode, &n)
+		if err != nil {
+			return 0, err
+		}
+
+		if label == "" {
+			label = fmt.Sprintf("%s-%s-%03d", levelCode, zoneCode, n)
+		}
+	}
+	if label == "" {
+		label = fmt.Sprintf("%03d", slotNumber)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `insert into parking_spaces (
+																				parking_lots_id, size_class, features, zones_id, slot_number, label, distance_to_entrance
+																			) values (?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(ctx, plID)
+	result, err := stmt.ExecContext(ctx,
+		plID, ps.SizeClass, ps.Features, intPtrNull(ps.ZoneID), slotNumber, label, intPtrNull(ps.DistanceToEntrance),
+	)
+	if isDuplicateEntry(err) {
+		return 0, ErrDuplicate
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -86,39 +229,324 @@ func (d *DB) CreateParkingSpaceFromParkingLotID(plID int) (int64, error) {
 	return id, nil
 }
 
-func (d *DB) GetNextParkingSpaceByParkingLot(parkingLotID int) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
-	defer cancel()
+// getNextParkingSpaceByParkingLot locks and returns the next available parking
+// space of a parking lot for a park request. Spaces held by a booking are
+// skipped, see getAllocationCandidates. The allocation strategy of the parking lot
+// picks the space, see allocation.Allocate. The locks are held until tx is
+// committed or rolled back so concurrent callers never get the same space.
+// ErrLotNotFound is returned when the parking lot does not exist and ErrLotFull
+// when no space is available for the request.
+func getNextParkingSpaceByParkingLot(ctx context.Context, tx *sql.Tx, parkingLotID int, pr ParkRequest) (int, error) {
+	la, err := lockParkingLotAllocation(ctx, tx, parkingLotID)
+	if err != nil {
+		return 0, err
+	}
+
+	candidates, err := getAllocationCandidates(ctx, tx, parkingLotID, la, pr)
+	if err != nil {
+		return 0, err
+	}
+
+	c, ok := allocation.Allocate(la.strategy, candidates, la.request(pr))
+	if !ok {
+		return 0, ErrLotFull
+	}
+
+	return c.ID, nil
+}
+
+// lotAllocation is what allocating the spaces of a parking lot depends on.
+type lotAllocation struct {
+	strategy            allocation.Strategy
+	allowTaggedOverflow bool
+	bookingGracePeriod  time.Duration
+}
+
+func (la lotAllocation) request(pr ParkRequest) allocation.Request {
+	return allocation.Request{
+		VehicleType:   pr.VehicleType,
+		Features:      pr.Features,
+		AllowOverflow: la.allowTaggedOverflow,
+	}
+}
+
+// lockParkingLotAllocation locks the parking lot so its spaces are allocated to
+// walk-ins and bookings one at a time. ErrLotNotFound is returned when the
+// parking lot does not exist.
+func lockParkingLotAllocation(ctx context.Context, tx *sql.Tx, parkingLotID int) (lotAllocation, error) {
+	var (
+		la                 lotAllocation
+		strategyName       string
+		bookingGracePeriod int
+	)
+	err := tx.QueryRowContext(ctx, `SELECT allow_tagged_overflow, allocation_strategy, booking_grace_period
+																	FROM parking_lots WHERE id = ? and archived_at IS NULL FOR UPDATE`, parkingLotID).
+		Scan(&la.allowTaggedOverflow, &strategyName, &bookingGracePeriod)
+	if err == sql.ErrNoRows {
+		return lotAllocation{}, ErrLotNotFound
+	}
+	if err != nil {
+		return lotAllocation{}, err
+	}
+
+	la.strategy, err = allocation.ByName(strategyName)
+	if err != nil {
+		return lotAllocation{}, err
+	}
+	la.bookingGracePeriod = time.Duration(bookingGracePeriod) * time.Minute
+
+	return la, nil
+}
+
+// getAllocationCandidates locks and returns the available spaces of a parking
+// lot a request may get. Spaces held by a booking are skipped whatever its
+// window, a stay has no known end so a space given to it now could still be
+// occupied when the booking starts.
+func getAllocationCandidates(
+	ctx context.Context, tx *sql.Tx, parkingLotID int, la lotAllocation, pr ParkRequest,
+) ([]allocation.Candidate, error) {
+	zoneOccupancy, err := getZoneOccupancyByParkingLot(ctx, tx, parkingLotID)
+	if err != nil {
+		return nil, err
+	}
 
-	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT parking_spaces.id
+	stmt, err := tx.PrepareContext(ctx, `SELECT parking_spaces.id, slot_number, size_class, features,
+																						 distance_to_entrance, zones_id, levels.ordinal
 	                               						 FROM parking_spaces
-																 						 WHERE EXISTS (
-																							SELECT * FROM parking_lots where parking_lots.id = ?
-																 						 ) and parking_lots_id = ?
+																						 LEFT JOIN zones ON zones.id = parking_spaces.zones_id
+																						 LEFT JOIN levels ON levels.id = zones.levels_id
+																 						 WHERE parking_spaces.parking_lots_id = ?
 																						 and status = ?
-																						 order by created_at asc, parking_spaces.id asc
-																						 limit 1`)
+																						 and size_class >= ?
+																						 and (features & ?) = ?
+																						 and (
+																							(features & ~?) = 0
+																							or (? and (features & ~? & ?) = 0)
+																						 )
+																						 and NOT EXISTS (
+																							SELECT bookings.id FROM bookings
+																							WHERE bookings.parking_spaces_id = parking_spaces.id
+																							and bookings.status = ?
+																							and bookings.start_time >= ?
+																						 )
+																						 order by parking_spaces.id asc
+																						 FOR UPDATE OF parking_spaces`)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	defer stmt.Close()
+
+	// holds whose grace period has passed are released
+	heldSince := time.Now().UTC().Add(-la.bookingGracePeriod)
+	rows, err := stmt.QueryContext(ctx,
+		parkingLotID, available, pr.VehicleType.MinSizeClass(),
+		pr.Features, pr.Features,
+		pr.Features,
+		la.allowTaggedOverflow, pr.Features, space.StaffOnly,
+		BookingHeld, heldSince.Format(dateFormat),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []allocation.Candidate
+	for rows.Next() {
+		var (
+			c                              allocation.Candidate
+			distance, zoneID, levelOrdinal sql.NullInt64
+		)
+		if err := rows.Scan(
+			&c.ID, &c.SlotNumber, &c.SizeClass, &c.Features,
+			&distance, &zoneID, &levelOrdinal,
+		); err != nil {
+			return nil, err
+		}
+
+		c.Distance = nullIntPtr(distance)
+		c.LevelOrdinal = nullIntPtr(levelOrdinal)
+		if zoneID.Valid {
+			c.ZoneID = int(zoneID.Int64)
+			c.ZoneOccupied, c.ZoneSpaces = zoneOccupancy[c.ZoneID][0], zoneOccupancy[c.ZoneID][1]
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// getZoneOccupancyByParkingLot returns the number of booked and of all spaces
+// by zone ID.
+func getZoneOccupancyByParkingLot(ctx context.Context, q preparer, parkingLotID int) (map[int][2]int, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT zones_id, SUM(status = ?), COUNT(id)
+																						 FROM parking_spaces
+																						 WHERE parking_lots_id = ? and zones_id IS NOT NULL and status <> ?
+																						 GROUP BY zones_id`)
+	if err != nil {
+		return nil, err
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, parkingLotID, parkingLotID, available)
+	rows, err := stmt.QueryContext(ctx, booked, parkingLotID, decommissioned)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	occupancy := map[int][2]int{}
+	for rows.Next() {
+		var zoneID, occupied, total int
+		if err := rows.Scan(&zoneID, &occupied, &total); err != nil {
+			return nil, err
+		}
+
+		occupancy[zoneID] = [2]int{occupied, total}
+	}
+
+	return occupancy, rows.Err()
+}
+
+// GetParkingSpaceByParkingLotIDAndID returns a parking space of a parking lot
+// that is not decommissioned.
+func (d *DB) GetParkingSpaceByParkingLotIDAndID(parkingLotID, id int) (ParkingSpace, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT parking_spaces.id, status, slot_number, label, zones_id,
+																						 size_class, features, distance_to_entrance
+																						 FROM parking_spaces
+																						 INNER JOIN parking_lots ON parking_lots.id = parking_spaces.parking_lots_id
+																						 WHERE parking_spaces.id = ? and parking_lots_id = ?
+																						 and archived_at IS NULL and status <> ?`)
+	if err != nil {
+		return ParkingSpace{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, id, parkingLotID, decommissioned)
 	if row == nil {
-		return 0, ErrNilQueryRowContext
+		return ParkingSpace{}, ErrNilQueryRowContext
 	}
 
 	err = row.Err()
 	if err != nil {
-		return 0, err
+		return ParkingSpace{}, err
 	}
 
-	var id int
-	if err := row.Scan(&id); err != nil {
-		return 0, err
+	var (
+		ps               ParkingSpace
+		st               status
+		zoneID, distance sql.NullInt64
+	)
+	if err := row.Scan(
+		&ps.ID, &st, &ps.SlotNumber, &ps.Label, &zoneID,
+		&ps.SizeClass, &ps.Features, &distance,
+	); err != nil {
+		return ParkingSpace{}, err
 	}
 
-	return id, nil
+	ps.Status = st.value()
+	ps.ZoneID = nullIntPtr(zoneID)
+	ps.DistanceToEntrance = nullIntPtr(distance)
+
+	return ps, nil
+}
+
+// UpdateParkingSpace changes the label, zone, size class, features and distance
+// to the entrance of a parking space of a parking lot. sql.ErrNoRows is
+// returned when the zone is not in the parking lot and ErrDuplicate when the
+// label is taken.
+func (d *DB) UpdateParkingSpace(parkingLotID int, ps ParkingSpace) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	if ps.ZoneID != nil {
+		var exists bool
+		err := d.dbConn.QueryRowContext(ctx, `SELECT EXISTS(
+																						SELECT zones.id FROM zones
+																						INNER JOIN levels ON levels.id = zones.levels_id
+																						WHERE zones.id = ? and levels.parking_lots_id = ?
+																					)`, *ps.ZoneID, parkingLotID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+	}
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `UPDATE parking_spaces SET
+																				label = ?, zones_id = ?, size_class = ?, features = ?, distance_to_entrance = ?
+																			WHERE (id = ? and parking_lots_id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx,
+		ps.Label, intPtrNull(ps.ZoneID), ps.SizeClass, ps.Features, intPtrNull(ps.DistanceToEntrance),
+		ps.ID, parkingLotID,
+	)
+	if isDuplicateEntry(err) {
+		return ErrDuplicate
+	}
+	if isMissingReference(err) {
+		// the zone was deleted concurrently
+		return sql.ErrNoRows
+	}
+
+	return err
+}
+
+// DecommissionParkingSpace takes a parking space out of use for good, its
+// reservations are kept. ErrInUse is returned while a vehicle is parked in it
+// or a booking holds it.
+func (d *DB) DecommissionParkingSpace(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// allocation locks the spaces it considers, so this waits for a concurrent
+	// park or booking to finish
+	var st status
+	err = tx.QueryRowContext(ctx, `SELECT status FROM parking_spaces WHERE id = ? FOR UPDATE`, id).Scan(&st)
+	if err != nil {
+		return err
+	}
+	if st == decommissioned {
+		return nil
+	}
+	if st == booked {
+		return ErrInUse
+	}
+
+	var held bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(
+																	SELECT id FROM bookings WHERE parking_spaces_id = ? and status = ?
+																)`, id, BookingHeld).Scan(&held)
+	if err != nil {
+		return err
+	}
+	if held {
+		return ErrInUse
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE parking_spaces SET status = ?, decommissioned_at = ? WHERE (id = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, decommissioned, time.Now().UTC().Format(dateFormat), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (d *DB) DoesParkingSpaceExistForMaintananceByParkingLotIDAndID(id, parkingLotID int) (bool, error) {
@@ -131,10 +559,10 @@ func (d *DB) DoesParkingSpaceExistForMaintananceByParkingLotIDAndID(id, parkingL
 																							WHERE EXISTS (
 																								SELECT parking_lots.id
 																								FROM parking_lots
-																								where parking_lots.id = ?
+																								where parking_lots.id = ? and archived_at IS NULL
 																							) and parking_lots_id = ?
 																							and parking_spaces.id = ?
-																							and status != ?
+																							and status in (?, ?)
 																							limit 1
 																						) limit 1`)
 
@@ -143,7 +571,7 @@ func (d *DB) DoesParkingSpaceExistForMaintananceByParkingLotIDAndID(id, parkingL
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, parkingLotID, parkingLotID, id, booked)
+	row := stmt.QueryRowContext(ctx, parkingLotID, parkingLotID, id, maintanance, available)
 	if row == nil {
 		return false, ErrNilQueryRowContext
 	}
@@ -3,142 +3,518 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/arifmahmudrana/parking-lot/pricing"
+	"github.com/arifmahmudrana/parking-lot/space"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// ParkRequest describes who and what is parking. Plate is optional and
+// normalised, VehicleType defaults to the type of the registered vehicle or
+// vehicle.DefaultType.
+type ParkRequest struct {
+	UserID      int            `json:"-"` // the authenticated user, 0 for API keys
+	AnyVehicle  bool           `json:"-"` // vehicles of other users may be parked
+	Plate       string         `json:"plate"`
+	VehicleType vehicle.Type   `json:"vehicle_type"`
+	Features    space.Features `json:"features"` // required features of the parking space
+
+	vehicleID int // set by resolveVehicle
+}
+
+// ParkingSpaceReservation is the stay of a vehicle in a parking space, EndTime
+// is nil while the vehicle is parked.
+type ParkingSpaceReservation struct {
+	ID             int          `json:"id"`
+	UserID         int          `json:"user_id"`
+	ParkingLotID   int          `json:"parking_lot_id"`
+	ParkingSpaceID int          `json:"parking_space_id"`
+	VehicleType    vehicle.Type `json:"vehicle_type"`
+	StartTime      time.Time    `json:"start_time"`
+	EndTime        *time.Time   `json:"end_time"`
+	Fee            int          `json:"fee"`
+	OverstayedAt   *time.Time   `json:"overstayed_at"`
+}
+
+// Statuses of reservations to filter by.
+const (
+	ReservationActive    = "active"
+	ReservationCompleted = "completed"
 )
 
-func (d *DB) CreateParkingSpaceReservation(parkingspaceID, userID int) (int64, error) {
+// reservationSorts are the columns reservations can be sorted by, a "-" prefix
+// sorts in descending order.
+var reservationSorts = map[string]string{
+	"id":         "parking_space_reservations.id",
+	"start_time": "parking_space_reservations.start_time",
+	"end_time":   "parking_space_reservations.end_time",
+	"fee":        "parking_space_reservations.fee",
+}
+
+// DefaultReservationSort lists the latest reservations first.
+const DefaultReservationSort = "-start_time"
+
+// ValidReservationSort reports whether reservations can be sorted by s.
+func ValidReservationSort(s string) bool {
+	_, ok := reservationSorts[strings.TrimPrefix(s, "-")]
+	return ok
+}
+
+// ReservationFilter selects parking space reservations, zero fields select all
+// of them. From and To select the reservations that started within [From, To).
+type ReservationFilter struct {
+	UserID         int
+	ParkingLotID   int
+	ParkingSpaceID int
+	Status         string // ReservationActive or ReservationCompleted
+	From, To       time.Time
+	Sort           string // DefaultReservationSort when empty
+}
+
+func (f ReservationFilter) where() (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if f.UserID > 0 {
+		conditions = append(conditions, "parking_space_reservations.user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.ParkingLotID > 0 {
+		conditions = append(conditions, "parking_spaces.parking_lots_id = ?")
+		args = append(args, f.ParkingLotID)
+	}
+	if f.ParkingSpaceID > 0 {
+		conditions = append(conditions, "parking_space_reservations.parking_spaces_id = ?")
+		args = append(args, f.ParkingSpaceID)
+	}
+	switch f.Status {
+	case ReservationActive:
+		conditions = append(conditions, "parking_space_reservations.end_time IS NULL")
+	case ReservationCompleted:
+		conditions = append(conditions, "parking_space_reservations.end_time IS NOT NULL")
+	}
+	if !f.From.IsZero() {
+		conditions = append(conditions, "parking_space_reservations.start_time >= ?")
+		args = append(args, f.From.UTC().Format(dateFormat))
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "parking_space_reservations.start_time < ?")
+		args = append(args, f.To.UTC().Format(dateFormat))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "WHERE " + strings.Join(conditions, " and "), args
+}
+
+func (f ReservationFilter) orderBy() string {
+	sort := f.Sort
+	if !ValidReservationSort(sort) {
+		sort = DefaultReservationSort
+	}
+
+	direction := "asc"
+	if strings.HasPrefix(sort, "-") {
+		direction = "desc"
+	}
+	column := reservationSorts[strings.TrimPrefix(sort, "-")]
+
+	return fmt.Sprintf("order by %s %s, parking_space_reservations.id %s", column, direction, direction)
+}
+
+const reservationColumns = `parking_space_reservations.id, user_id, parking_spaces.parking_lots_id,
+														parking_spaces_id, vehicle_type, start_time, end_time, fee, overstayed_at`
+
+func scanReservation(row interface{ Scan(dest ...any) error }) (ParkingSpaceReservation, error) {
+	var (
+		psr                     ParkingSpaceReservation
+		startTime               string
+		endTime, overstayedTime sql.NullString
+	)
+	if err := row.Scan(
+		&psr.ID, &psr.UserID, &psr.ParkingLotID,
+		&psr.ParkingSpaceID, &psr.VehicleType, &startTime, &endTime, &psr.Fee, &overstayedTime,
+	); err != nil {
+		return ParkingSpaceReservation{}, err
+	}
+
+	var err error
+	if psr.StartTime, err = time.Parse(dateFormat, startTime); err != nil {
+		return ParkingSpaceReservation{}, err
+	}
+	if psr.EndTime, err = parseNullTime(endTime); err != nil {
+		return ParkingSpaceReservation{}, err
+	}
+	if psr.OverstayedAt, err = parseNullTime(overstayedTime); err != nil {
+		return ParkingSpaceReservation{}, err
+	}
+
+	return psr, nil
+}
+
+func (d *DB) GetParkingSpaceReservationByID(id int) (ParkingSpaceReservation, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
 
-	tx, err := d.dbConn.BeginTx(ctx, nil)
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT `+reservationColumns+`
+																							 FROM parking_space_reservations
+																							 INNER JOIN parking_spaces
+																							 ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																							 WHERE parking_space_reservations.id = ?`)
+	if err != nil {
+		return ParkingSpaceReservation{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, id)
+	if row == nil {
+		return ParkingSpaceReservation{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return ParkingSpaceReservation{}, err
+	}
+
+	return scanReservation(row)
+}
+
+// GetParkingSpaceReservations returns a page of the reservations f selects.
+func (d *DB) GetParkingSpaceReservations(f ReservationFilter, page int) ([]ParkingSpaceReservation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	where, args := f.where()
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT `+reservationColumns+`
+																						 FROM parking_space_reservations
+																						 INNER JOIN parking_spaces
+																						 ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																						 `+where+`
+																						 `+f.orderBy()+`
+																						 LIMIT ?, ?`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, append(args, getOffset(page), size)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := make([]ParkingSpaceReservation, 0, size)
+	for rows.Next() {
+		psr, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		reservations = append(reservations, psr)
+	}
+
+	return reservations, rows.Err()
+}
+
+func (d *DB) GetTotalCountParkingSpaceReservations(f ReservationFilter) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	where, args := f.where()
+	var count int
+	err := d.dbConn.QueryRowContext(ctx, `SELECT count(parking_space_reservations.id)
+																				FROM parking_space_reservations
+																				INNER JOIN parking_spaces
+																				ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																				`+where, args...).
+		Scan(&count)
 	if err != nil {
 		return 0, err
 	}
+
+	return count, nil
+}
+
+// ParkParkingSpaceByParkingLot books the next available parking space of a
+// parking lot that fits the vehicle and the requested features and creates the
+// reservation for it in a single transaction. It returns the reservation ID and
+// where the space is. ErrLotNotFound is returned when the parking lot does not
+// exist, ErrLotClosed when it is closed, ErrLotFull when it has no such space
+// available and the errors of resolveVehicle for the plate.
+func (d *DB) ParkParkingSpaceByParkingLot(parkingLotID int, pr ParkRequest) (int64, Location, error) {
+	var (
+		id  int64
+		loc Location
+		err error
+	)
+	for i := 0; i < parkRetries; i++ {
+		id, loc, err = d.parkParkingSpaceByParkingLot(parkingLotID, pr)
+		if !isRetryable(err) {
+			break
+		}
+	}
+
+	return id, loc, err
+}
+
+func (d *DB) parkParkingSpaceByParkingLot(parkingLotID int, pr ParkRequest) (int64, Location, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, Location{}, err
+	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `UPDATE parking_spaces SET status = ? WHERE (id = ?)`)
+	pr, err = resolveVehicle(ctx, tx, pr)
 	if err != nil {
-		return 0, err
+		return 0, Location{}, err
+	}
+
+	if err := checkParkingLotOpen(ctx, tx, parkingLotID, time.Now().UTC()); err != nil {
+		return 0, Location{}, err
+	}
+
+	parkingspaceID, err := getNextParkingSpaceByParkingLot(ctx, tx, parkingLotID, pr)
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	id, loc, err := occupyParkingSpace(ctx, tx, parkingspaceID, pr)
+	if err != nil {
+		return 0, Location{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, Location{}, err
+	}
+
+	return id, loc, nil
+}
+
+// occupyParkingSpace books an available parking space and creates the
+// reservation for it, errParkingSpaceTaken is returned when the space is not
+// available.
+func occupyParkingSpace(ctx context.Context, tx *sql.Tx, parkingspaceID int, pr ParkRequest) (int64, Location, error) {
+	stmt, err := tx.PrepareContext(ctx, `UPDATE parking_spaces SET status = ? WHERE (id = ? and status = ?)`)
+	if err != nil {
+		return 0, Location{}, err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, booked, parkingspaceID)
+	result, err := stmt.ExecContext(ctx, booked, parkingspaceID, available)
 	if err != nil {
-		return 0, err
+		return 0, Location{}, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, Location{}, err
+	}
+	if affected != 1 {
+		return 0, Location{}, errParkingSpaceTaken
 	}
 
 	stmt, err = tx.PrepareContext(ctx,
-		`insert into parking_space_reservations (user_id, start_time, parking_spaces_id) values (?, ?, ?)`)
+		`insert into parking_space_reservations (
+			user_id, start_time, parking_spaces_id, vehicle_type, vehicles_id
+		) values (?, ?, ?, ?, ?)`)
 	if err != nil {
-		return 0, err
+		return 0, Location{}, err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(ctx, userID, time.Now().UTC().Format(dateFormat), parkingspaceID)
+	var vehicleID sql.NullInt64
+	if pr.vehicleID > 0 {
+		vehicleID = sql.NullInt64{Int64: int64(pr.vehicleID), Valid: true}
+	}
+	result, err = stmt.ExecContext(ctx, pr.UserID, time.Now().UTC().Format(dateFormat), parkingspaceID, pr.VehicleType, vehicleID)
+	if isDuplicateEntry(err) {
+		// the vehicle was parked concurrently
+		return 0, Location{}, ErrVehicleParked
+	}
 	if err != nil {
-		return 0, err
+		return 0, Location{}, err
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, Location{}, err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return 0, err
+	loc, err := getLocationByParkingSpace(ctx, tx, parkingspaceID)
+	if err != nil {
+		return 0, Location{}, err
 	}
 
-	return id, nil
+	return id, loc, nil
 }
 
-func (d *DB) UnParkParkingSpaceByID(parkingSpaceReservationsID int) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
-	defer cancel()
+// reservationFee is the fee of an active reservation.
+type reservationFee struct {
+	pricing.Fee
+	parkingSpaceID int
+}
 
-	// Get the reservation
-	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT *
-	                               						 FROM parking_space_reservations
-																 						 WHERE id = ?
-																						 limit 1`)
+// getReservationFee calculates the fee of the active reservation id if it ends
+// at end, the reservation is not changed.
+func getReservationFee(ctx context.Context, q preparer, id int, end time.Time) (reservationFee, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT start_time, end_time, parking_spaces_id, parking_spaces.parking_lots_id,
+																			parking_lots.time_zone, vehicle_type
+																			FROM parking_space_reservations
+																			INNER JOIN parking_spaces
+																			ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																			INNER JOIN parking_lots
+																			ON parking_lots.id = parking_spaces.parking_lots_id
+																			WHERE parking_space_reservations.id = ?
+																			limit 1`)
 	if err != nil {
-		return 0, err
+		return reservationFee{}, err
 	}
 	defer stmt.Close()
 
-	row := stmt.QueryRowContext(ctx, parkingSpaceReservationsID)
+	row := stmt.QueryRowContext(ctx, id)
 	if row == nil {
-		return 0, ErrNilQueryRowContext
+		return reservationFee{}, ErrNilQueryRowContext
 	}
 
 	err = row.Err()
 	if err != nil {
-		return 0, err
+		return reservationFee{}, err
 	}
 
-	var psrRow struct {
-		id, userID, fee, parkingSpacesID int
-		startTime                        string
-		endTime                          sql.NullString
-	}
+	var (
+		rf                  reservationFee
+		parkingLotID        int
+		startTime, timeZone string
+		endTime             sql.NullString
+		vehicleType         vehicle.Type
+	)
 	if err := row.Scan(
-		&psrRow.id, &psrRow.userID, &psrRow.startTime,
-		&psrRow.endTime, &psrRow.fee, &psrRow.parkingSpacesID,
+		&startTime, &endTime, &rf.parkingSpaceID,
+		&parkingLotID, &timeZone, &vehicleType,
 	); err != nil {
-		return 0, err
+		return reservationFee{}, err
 	}
 
-	if psrRow.endTime.Valid {
-		return 0, ErrAlreadyUnparked
+	if endTime.Valid {
+		return reservationFee{}, ErrAlreadyUnparked
 	}
 
-	// get end time
-	endTime := time.Now().UTC()
-	startTime, err := time.Parse(dateFormat, psrRow.startTime)
+	start, err := time.Parse(dateFormat, startTime)
 	if err != nil {
-		return 0, err
+		return reservationFee{}, err
+	}
+
+	ratePlan, err := getRatePlanByParkingLot(ctx, q, parkingLotID)
+	if err != nil {
+		return reservationFee{}, err
 	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return reservationFee{}, err
+	}
+	rf.Fee = ratePlan.Calculate(pricing.Stay{
+		Start:       start,
+		End:         end,
+		Location:    loc,
+		VehicleType: vehicleType,
+	})
+
+	return rf, nil
+}
+
+// QuoteParkingSpaceReservation returns the fee the active reservation would be
+// charged if it was unparked at the given time.
+func (d *DB) QuoteParkingSpaceReservation(parkingSpaceReservationsID int, at time.Time) (pricing.Fee, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
 
-	// calculate fee
-	duration := endTime.Sub(startTime)
-	h, rem := int(duration/time.Hour), int(duration%time.Hour)
-	fee := h * 10
-	if rem > 0 {
-		fee += 10
+	rf, err := getReservationFee(ctx, d.dbConn, parkingSpaceReservationsID, at.UTC().Truncate(time.Second))
+	if err != nil {
+		return pricing.Fee{}, err
+	}
+
+	return rf.Fee, nil
+}
+
+// UnParkParkingSpaceByID ends a reservation, charges it with the current rate
+// plan of its parking lot and makes the parking space available again. With pay
+// a pending payment of a fee is recorded with it.
+func (d *DB) UnParkParkingSpaceByID(parkingSpaceReservationsID int, pay bool) (pricing.Fee, *Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	endTime := time.Now().UTC()
+	rf, err := getReservationFee(ctx, d.dbConn, parkingSpaceReservationsID, endTime)
+	if err != nil {
+		return pricing.Fee{}, nil, err
 	}
 
 	tx, err := d.dbConn.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return pricing.Fee{}, nil, err
 	}
 	defer tx.Rollback()
 
-	// update reservation
-	stmt, err = tx.PrepareContext(ctx, `UPDATE parking_space_reservations SET end_time = ?, fee = ? WHERE (id = ?)`)
+	// update reservation, the end_time check makes concurrent unparks fail
+	stmt, err := tx.PrepareContext(ctx, `UPDATE parking_space_reservations SET end_time = ?, fee = ?, rate_plans_id = ?
+																			WHERE (id = ? and end_time IS NULL)`)
 	if err != nil {
-		return 0, err
+		return pricing.Fee{}, nil, err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, endTime.Format(dateFormat), fee, parkingSpaceReservationsID)
+	ratePlanID := sql.NullInt64{Int64: int64(rf.RatePlan.ID), Valid: rf.RatePlan.ID > 0}
+	result, err := stmt.ExecContext(ctx, endTime.Format(dateFormat), rf.Total, ratePlanID, parkingSpaceReservationsID)
 	if err != nil {
-		return 0, err
+		return pricing.Fee{}, nil, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return pricing.Fee{}, nil, err
+	}
+	if affected != 1 {
+		return pricing.Fee{}, nil, ErrAlreadyUnparked
 	}
 
 	// update parking space make it available
 	stmt, err = tx.PrepareContext(ctx, `UPDATE parking_spaces SET status = ? WHERE (id = ?)`)
 	if err != nil {
-		return 0, err
+		return pricing.Fee{}, nil, err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, available, psrRow.parkingSpacesID)
+	_, err = stmt.ExecContext(ctx, available, rf.parkingSpaceID)
 	if err != nil {
-		return 0, err
+		return pricing.Fee{}, nil, err
+	}
+
+	// the pending payment is recorded with the unpark, a payment that fails
+	// after it was committed is retried through it
+	var p *Payment
+	if pay && rf.Total > 0 {
+		created, err := insertPayment(ctx, tx, Payment{
+			ParkingSpaceReservationID: parkingSpaceReservationsID,
+			Amount:                    rf.Total,
+		})
+		if err != nil {
+			return pricing.Fee{}, nil, err
+		}
+		p = &created
 	}
 
 	if err = tx.Commit(); err != nil {
-		return 0, err
+		return pricing.Fee{}, nil, err
 	}
 
-	return fee, nil
+	return rf.Fee, p, nil
 }
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// DailyReport is the parking manager summary of a single parking lot for one day.
+//
+// A reservation is attributed to the day its end_time falls on in the time zone
+// of the parking lot, as that is
+// the moment the vehicle leaves and the fee is collected. Reservations spanning
+// midnight are therefore counted once, in full, on the day they ended, and
+// vehicles that are still parked are not counted until they unpark.
+//
+// TotalFee is the gross of the fees charged. Adjustments are attributed to the
+// day they were made, whenever the reservation they correct ended, and
+// NetRevenue is the gross less the adjustments. Refunds that failed are not
+// counted, nor pending ones until they are reconciled with the payment gateway.
+type DailyReport struct {
+	Date             string `json:"date"`
+	TotalVehicles    int    `json:"total_vehicles"`
+	TotalParkingTime int64  `json:"total_parking_time"` // in seconds
+	TotalFee         int    `json:"total_fee"`
+	TotalRefunds     int    `json:"total_refunds"`
+	TotalWaivers     int    `json:"total_waivers"`
+	TotalAdjustments int    `json:"total_adjustments"`
+	NetRevenue       int    `json:"net_revenue"`
+}
+
+// reportDay returns the start and the end of the date of day in timeZone, days
+// the clocks change on are 23 or 25 hours long.
+func reportDay(timeZone string, day time.Time) (time.Time, time.Time, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	return from, from.AddDate(0, 0, 1), nil
+}
+
+// GetDailyReportByParkingLot reports the date of day, it is taken as is and not
+// converted to the time zone of the parking lot.
+func (d *DB) GetDailyReportByParkingLot(parkingLotID int, day time.Time) (DailyReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var timeZone string
+	err := d.dbConn.QueryRowContext(ctx, `SELECT time_zone FROM parking_lots WHERE id = ?`, parkingLotID).
+		Scan(&timeZone)
+	if err != nil {
+		return DailyReport{}, err
+	}
+	from, to, err := reportDay(timeZone, day)
+	if err != nil {
+		return DailyReport{}, err
+	}
+
+	report := DailyReport{
+		Date: from.Format("2006-01-02"),
+	}
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT count(parking_space_reservations.id),
+																						 COALESCE(SUM(TIMESTAMPDIFF(SECOND, start_time, end_time)), 0),
+																						 COALESCE(SUM(fee), 0)
+																						 FROM parking_space_reservations
+																						 INNER JOIN parking_spaces
+																						 ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																						 WHERE parking_spaces.parking_lots_id = ?
+																						 and end_time >= ?
+																						 and end_time < ?`)
+	if err != nil {
+		return report, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, parkingLotID, from.UTC().Format(dateFormat), to.UTC().Format(dateFormat))
+	if row == nil {
+		return report, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return report, err
+	}
+
+	if err := row.Scan(
+		&report.TotalVehicles,
+		&report.TotalParkingTime,
+		&report.TotalFee,
+	); err != nil {
+		return report, err
+	}
+
+	err = d.dbConn.QueryRowContext(ctx, `SELECT COALESCE(SUM(IF(kind = ?, amount, 0)), 0),
+																				COALESCE(SUM(IF(kind = ?, amount, 0)), 0)
+																				FROM adjustments
+																				INNER JOIN parking_space_reservations
+																				ON parking_space_reservations.id = adjustments.parking_space_reservations_id
+																				INNER JOIN parking_spaces
+																				ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																				WHERE parking_spaces.parking_lots_id = ?
+																				and adjustments.status = ?
+																				and adjustments.created_at >= ?
+																				and adjustments.created_at < ?`,
+		AdjustmentRefund, AdjustmentWaiver, parkingLotID, AdjustmentDone, from.UTC().Format(dateFormat), to.UTC().Format(dateFormat)).
+		Scan(&report.TotalRefunds, &report.TotalWaivers)
+	if err != nil {
+		return report, err
+	}
+
+	report.TotalAdjustments = report.TotalRefunds + report.TotalWaivers
+	report.NetRevenue = report.TotalFee - report.TotalAdjustments
+
+	return report, nil
+}
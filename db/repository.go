@@ -0,0 +1,105 @@
+package db
+
+import (
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/pricing"
+)
+
+// Repository is the storage used by the HTTP handlers. It is implemented by DB
+// for MySQL and by MemoryDB for running without a database.
+type Repository interface {
+	Close() error
+
+	CreateUser(u User) (int64, error)
+	GetUserByEmail(email string) (User, error)
+	GetUserByID(id int) (User, error)
+	CreateGrant(g Grant) (int64, error)
+	GetGrantsByUser(userID int) ([]Grant, error)
+	DeleteGrant(id int) error
+
+	CreateAPIKey(k APIKey) (int64, error)
+	GetAPIKeys() ([]APIKey, error)
+	GetAPIKeyByID(id int) (APIKey, error)
+	GetAPIKeyByKeyID(keyID string) (APIKey, error)
+	RotateAPIKey(id int, k APIKey, overlapUntil time.Time) (int64, error)
+	RevokeAPIKey(id int) error
+	TouchAPIKey(id int, usedAt time.Time) error
+
+	CreateParkingLot(pl ParkingLot) (int64, error)
+	GetParkingLots(page int) ([]ParkingLot, error)
+	GetParkingLotByID(id int) (ParkingLot, error)
+	UpdateParkingLot(pl ParkingLot) error
+	ArchiveParkingLot(id int) error
+	CreateClosure(parkingLotID int, c Closure) (int64, error)
+	GetClosuresByParkingLot(parkingLotID int, endingAfter time.Time) ([]Closure, error)
+	DeleteClosure(parkingLotID, id int) error
+	GetTotalCountParkingLots() (int, error)
+	DoesParkingLotExistByID(parkingLotID int) (bool, error)
+	GetOccupancyByParkingLot(parkingLotID int) (Occupancy, error)
+	GetParkingLotSuggestions(parkingLotID int, pr ParkRequest, limit int) ([]ParkingLotSuggestion, error)
+
+	GetParkingSpacesByParkingLot(parkingLotID int) ([]ParkingSpace, error)
+	CreateParkingSpaceFromParkingLotID(plID int, ps ParkingSpace) (int64, error)
+	CreateParkingSpacesFromParkingLotID(plID int, spaces []ParkingSpace) ([]int64, error)
+	GetParkingSpaceByParkingLotIDAndID(parkingLotID, id int) (ParkingSpace, error)
+	UpdateParkingSpace(parkingLotID int, ps ParkingSpace) error
+	DecommissionParkingSpace(id int) error
+	DoesParkingSpaceExistForMaintananceByParkingLotIDAndID(id, parkingLotID int) (bool, error)
+	SetParkingSpaceMaintanance(id int, m bool) error
+
+	CreateLevel(parkingLotID int, l Level) (int64, error)
+	GetLevelsByParkingLot(parkingLotID int) ([]Level, error)
+	GetLevelByParkingLotIDAndID(parkingLotID, id int) (Level, error)
+	UpdateLevel(l Level) error
+	DeleteLevel(id int) error
+	CreateZone(levelID int, z Zone) (int64, error)
+	GetZonesByLevel(levelID int) ([]Zone, error)
+	GetZoneByLevelIDAndID(levelID, id int) (Zone, error)
+	UpdateZone(z Zone) error
+	DeleteZone(id int) error
+
+	GetParkingSpaceReservationByID(id int) (ParkingSpaceReservation, error)
+	GetParkingSpaceReservations(f ReservationFilter, page int) ([]ParkingSpaceReservation, error)
+	GetTotalCountParkingSpaceReservations(f ReservationFilter) (int, error)
+	ParkParkingSpaceByParkingLot(parkingLotID int, pr ParkRequest) (int64, Location, error)
+	UnParkParkingSpaceByID(parkingSpaceReservationsID int, pay bool) (pricing.Fee, *Payment, error)
+	QuoteParkingSpaceReservation(parkingSpaceReservationsID int, at time.Time) (pricing.Fee, error)
+
+	CreatePayment(p Payment) (Payment, error)
+	UpdatePayment(p Payment) (Payment, error)
+	ClaimPayment(parkingSpaceReservationsID int) (Payment, error)
+	GetPaymentsByReservation(parkingSpaceReservationsID int) ([]Payment, error)
+	CreateAdjustment(a Adjustment) (Adjustment, error)
+	UpdateAdjustment(a Adjustment) (Adjustment, error)
+	GetAdjustmentsByReservation(parkingSpaceReservationsID int) ([]Adjustment, error)
+	GetPendingRefunds(createdBefore time.Time) ([]Adjustment, error)
+
+	CreateVehicle(v Vehicle) (int64, error)
+	GetVehicleByPlate(plate string) (Vehicle, error)
+	GetCurrentParkingByVehicle(vehicleID int) (CurrentParking, error)
+
+	CreateBooking(parkingLotID int, b Booking) (Booking, error)
+	GetBookingByID(id int) (Booking, error)
+	ArriveBooking(id int) (int64, Location, error)
+	ReleaseNoShowBookings() (int64, error)
+
+	CreateRatePlan(parkingLotID int, rp pricing.RatePlan) (int64, error)
+	GetRatePlanByParkingLot(parkingLotID int) (pricing.RatePlan, error)
+
+	GetDailyReportByParkingLot(parkingLotID int, day time.Time) (DailyReport, error)
+
+	CreateJobRun(jr JobRun) (int64, error)
+	GetJobRuns(page int) ([]JobRun, error)
+	GetTotalCountJobRuns() (int, error)
+	DeleteJobRunsBefore(finishedBefore time.Time) (int64, error)
+	FlagOverstays() (int64, error)
+	GetStaleReservationIDs() ([]int, error)
+	FlagActiveReservationsByParkingLot(parkingLotID int) (int64, error)
+	GetActiveReservationIDsByParkingLot(parkingLotID int) ([]int, error)
+}
+
+var (
+	_ Repository = (*DB)(nil)
+	_ Repository = (*MemoryDB)(nil)
+)
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/arifmahmudrana/parking-lot/pricing"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+func (d *DB) CreateRatePlan(parkingLotID int, rp pricing.RatePlan) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	tx, err := d.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `insert into rate_plans (
+																							parking_lots_id, name, first_hour_rate, hourly_rate,
+																							billing_increment, grace_period, daily_max, free_minutes
+																						) values (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx,
+		parkingLotID, rp.Name, rp.FirstHourRate, rp.HourlyRate,
+		rp.BillingIncrement, rp.GracePeriod, rp.DailyMax, rp.FreeMinutes,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err = tx.PrepareContext(ctx, `insert into tariff_bands (
+																				rate_plans_id, name, kind, days, start_time, end_time, amount
+																			) values (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, b := range rp.Bands {
+		_, err := stmt.ExecContext(ctx, id, b.Name, b.Kind, strings.Join(b.Days, ","), b.Start, b.End, b.Amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	stmt, err = tx.PrepareContext(ctx, `insert into rate_plan_vehicle_rates (
+																				rate_plans_id, vehicle_type, percent
+																			) values (?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for vehicleType, percent := range rp.VehicleRates {
+		_, err := stmt.ExecContext(ctx, id, vehicleType, percent)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetRatePlanByParkingLot returns the current rate plan of a parking lot or
+// pricing.DefaultRatePlan when none is configured.
+func (d *DB) GetRatePlanByParkingLot(parkingLotID int) (pricing.RatePlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return getRatePlanByParkingLot(ctx, d.dbConn, parkingLotID)
+}
+
+func getRatePlanByParkingLot(ctx context.Context, q preparer, parkingLotID int) (pricing.RatePlan, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT id, name, first_hour_rate, hourly_rate,
+																						 billing_increment, grace_period, daily_max, free_minutes
+																						 FROM rate_plans
+																						 WHERE parking_lots_id = ?
+																						 order by id desc
+																						 limit 1`)
+	if err != nil {
+		return pricing.RatePlan{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, parkingLotID)
+	if row == nil {
+		return pricing.RatePlan{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return pricing.RatePlan{}, err
+	}
+
+	var rp pricing.RatePlan
+	err = row.Scan(
+		&rp.ID, &rp.Name, &rp.FirstHourRate, &rp.HourlyRate,
+		&rp.BillingIncrement, &rp.GracePeriod, &rp.DailyMax, &rp.FreeMinutes,
+	)
+	if err == sql.ErrNoRows {
+		return pricing.DefaultRatePlan, nil
+	}
+	if err != nil {
+		return pricing.RatePlan{}, err
+	}
+
+	rp.Bands, err = getTariffBandsByRatePlan(ctx, q, rp.ID)
+	if err != nil {
+		return pricing.RatePlan{}, err
+	}
+
+	rp.VehicleRates, err = getVehicleRatesByRatePlan(ctx, q, rp.ID)
+	if err != nil {
+		return pricing.RatePlan{}, err
+	}
+
+	return rp, nil
+}
+
+// getTariffBandsByRatePlan returns the bands of a rate plan in the order they
+// were configured, which is the order they take precedence in.
+func getTariffBandsByRatePlan(ctx context.Context, q preparer, ratePlanID int) ([]pricing.Band, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT name, kind, days, start_time, end_time, amount
+																						 FROM tariff_bands
+																						 WHERE rate_plans_id = ?
+																						 order by id asc`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, ratePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bands := []pricing.Band{}
+	for rows.Next() {
+		var (
+			b    pricing.Band
+			days string
+		)
+		err := rows.Scan(&b.Name, &b.Kind, &days, &b.Start, &b.End, &b.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		if days != "" {
+			b.Days = strings.Split(days, ",")
+		}
+		bands = append(bands, b)
+	}
+
+	return bands, rows.Err()
+}
+
+func getVehicleRatesByRatePlan(ctx context.Context, q preparer, ratePlanID int) (map[vehicle.Type]int, error) {
+	stmt, err := q.PrepareContext(ctx, `SELECT vehicle_type, percent
+																						 FROM rate_plan_vehicle_rates
+																						 WHERE rate_plans_id = ?`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, ratePlanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vehicleRates := map[vehicle.Type]int{}
+	for rows.Next() {
+		var (
+			vehicleType vehicle.Type
+			percent     int
+		)
+		if err := rows.Scan(&vehicleType, &percent); err != nil {
+			return nil, err
+		}
+
+		vehicleRates[vehicleType] = percent
+	}
+
+	return vehicleRates, rows.Err()
+}
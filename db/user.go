@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+)
+
+// User is an account that authenticates to the API, emails are stored lower
+// case.
+type User struct {
+	ID           int    `json:"id"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"-"`
+}
+
+func (d *DB) CreateUser(u User) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `insert into users (email, name, password_hash) values (?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, u.Email, u.Name, u.PasswordHash)
+	if isDuplicateEntry(err) {
+		return 0, ErrDuplicate
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (d *DB) GetUserByEmail(email string) (User, error) {
+	return d.getUser(`SELECT id, email, name, password_hash FROM users WHERE email = ?`, email)
+}
+
+func (d *DB) GetUserByID(id int) (User, error) {
+	return d.getUser(`SELECT id, email, name, password_hash FROM users WHERE id = ?`, id)
+}
+
+func (d *DB) getUser(query string, arg any) (User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, query)
+	if err != nil {
+		return User{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, arg)
+	if row == nil {
+		return User{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return User{}, err
+	}
+
+	var u User
+	if err := row.Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash); err != nil {
+		return User{}, err
+	}
+
+	return u, nil
+}
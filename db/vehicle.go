@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+// Vehicle is a registered license plate, Plate is normalised with
+// vehicle.NormalizePlate.
+type Vehicle struct {
+	ID          int          `json:"id"`
+	Plate       string       `json:"plate"`
+	UserID      int          `json:"user_id"`
+	VehicleType vehicle.Type `json:"vehicle_type"`
+}
+
+// CurrentParking is where a vehicle is parked right now.
+type CurrentParking struct {
+	ParkingSpaceReservationID int       `json:"parking_space_reservation_id"`
+	ParkingLotID              int       `json:"parking_lot_id"`
+	ParkingLotName            string    `json:"parking_lot_name"`
+	Location                  Location  `json:"location"`
+	StartTime                 time.Time `json:"start_time"`
+}
+
+func (d *DB) CreateVehicle(v Vehicle) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return createVehicle(ctx, d.dbConn, v)
+}
+
+func createVehicle(ctx context.Context, q preparer, v Vehicle) (int64, error) {
+	stmt, err := q.PrepareContext(ctx, `insert into vehicles (plate, user_id, vehicle_type) values (?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, v.Plate, v.UserID, v.VehicleType)
+	if isDuplicateEntry(err) {
+		return 0, ErrDuplicate
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (d *DB) GetVehicleByPlate(plate string) (Vehicle, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT id, plate, user_id, vehicle_type FROM vehicles WHERE plate = ?`)
+	if err != nil {
+		return Vehicle{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, plate)
+	if row == nil {
+		return Vehicle{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return Vehicle{}, err
+	}
+
+	var v Vehicle
+	if err := row.Scan(&v.ID, &v.Plate, &v.UserID, &v.VehicleType); err != nil {
+		return Vehicle{}, err
+	}
+
+	return v, nil
+}
+
+// GetCurrentParkingByVehicle returns sql.ErrNoRows when the vehicle is not
+// parked.
+func (d *DB) GetCurrentParkingByVehicle(vehicleID int) (CurrentParking, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	stmt, err := d.dbConn.PrepareContext(ctx, `SELECT parking_space_reservations.id, start_time, parking_spaces_id,
+																						 parking_lots.id, parking_lots.name
+																						 FROM parking_space_reservations
+																						 INNER JOIN parking_spaces
+																						 ON parking_spaces.id = parking_space_reservations.parking_spaces_id
+																						 INNER JOIN parking_lots
+																						 ON parking_lots.id = parking_spaces.parking_lots_id
+																						 WHERE vehicles_id = ? and end_time IS NULL`)
+	if err != nil {
+		return CurrentParking{}, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, vehicleID)
+	if row == nil {
+		return CurrentParking{}, ErrNilQueryRowContext
+	}
+
+	err = row.Err()
+	if err != nil {
+		return CurrentParking{}, err
+	}
+
+	var (
+		cp              CurrentParking
+		startTime       string
+		parkingSpacesID int
+	)
+	if err := row.Scan(
+		&cp.ParkingSpaceReservationID, &startTime, &parkingSpacesID,
+		&cp.ParkingLotID, &cp.ParkingLotName,
+	); err != nil {
+		return CurrentParking{}, err
+	}
+
+	if cp.StartTime, err = time.Parse(dateFormat, startTime); err != nil {
+		return CurrentParking{}, err
+	}
+
+	cp.Location, err = getLocationByParkingSpace(ctx, d.dbConn, parkingSpacesID)
+	if err != nil {
+		return CurrentParking{}, err
+	}
+
+	return cp, nil
+}
+
+// resolveVehicle is lookupVehicle for parking the vehicle, ErrVehicleParked is
+// also returned when the vehicle has an active reservation.
+func resolveVehicle(ctx context.Context, tx *sql.Tx, pr ParkRequest) (ParkRequest, error) {
+	pr, err := lookupVehicle(ctx, tx, pr)
+	if err != nil || pr.vehicleID == 0 {
+		return pr, err
+	}
+
+	var parked bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(
+																	SELECT id FROM parking_space_reservations WHERE vehicles_id = ? and end_time IS NULL
+																)`, pr.vehicleID).
+		Scan(&parked)
+	if err != nil {
+		return ParkRequest{}, err
+	}
+	if parked {
+		return ParkRequest{}, ErrVehicleParked
+	}
+
+	return pr, nil
+}
+
+// lookupVehicle locks the registered vehicle of a park request with a plate
+// and fills in the vehicle type. The vehicle keeps its type unless the request
+// names one, requests without a user are made for its owner.
+// ErrVehicleNotRegistered is returned for an unknown plate and
+// ErrVehicleNotOwned when the vehicle belongs to another user and the request
+// may not park any vehicle.
+func lookupVehicle(ctx context.Context, tx *sql.Tx, pr ParkRequest) (ParkRequest, error) {
+	if pr.Plate == "" {
+		if pr.VehicleType == "" {
+			pr.VehicleType = vehicle.DefaultType
+		}
+
+		return pr, nil
+	}
+
+	var (
+		vehicleType vehicle.Type
+		userID      int
+	)
+	err := tx.QueryRowContext(ctx, `SELECT id, user_id, vehicle_type FROM vehicles WHERE plate = ? FOR UPDATE`, pr.Plate).
+		Scan(&pr.vehicleID, &userID, &vehicleType)
+	if err == sql.ErrNoRows {
+		return ParkRequest{}, ErrVehicleNotRegistered
+	}
+	if err != nil {
+		return ParkRequest{}, err
+	}
+	if userID != pr.UserID && !pr.AnyVehicle {
+		return ParkRequest{}, ErrVehicleNotOwned
+	}
+
+	if pr.VehicleType == "" {
+		pr.VehicleType = vehicleType
+	}
+	if pr.UserID == 0 {
+		pr.UserID = userID
+	}
+
+	return pr, nil
+}
@@ -5,6 +5,7 @@ go 1.20
 require (
 	github.com/go-chi/chi/v5 v5.0.12
 	github.com/go-sql-driver/mysql v1.8.0
+	golang.org/x/crypto v0.33.0
 )
 
 require filippo.io/edwards25519 v1.1.0 // indirect
@@ -0,0 +1,82 @@
+// Package hours defines the weekly opening hours of a parking lot.
+package hours
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/clock"
+)
+
+// ClosingPolicy is what happens to the vehicles still parked when a parking lot
+// closes.
+type ClosingPolicy string
+
+const (
+	// Stay lets vehicles stay until they are unparked.
+	Stay ClosingPolicy = "stay"
+	// Flag flags their reservations as overstays.
+	Flag ClosingPolicy = "flag"
+	// Unpark ends their reservations, they are charged until then.
+	Unpark ClosingPolicy = "unpark"
+)
+
+func (p ClosingPolicy) Valid() bool {
+	return p == Stay || p == Flag || p == Unpark
+}
+
+// Period is a window of local time a parking lot is open in, e.g. 07:00 to
+// 22:00 on weekdays. Open and Close are "HH:MM", a period with Close before
+// Open closes on the next day and one with Close equal to Open lasts the whole
+// day. Days are the weekdays ("mon", "tue", ...) the period opens on, no days
+// means every day.
+type Period struct {
+	Days  []string `json:"days"`
+	Open  string   `json:"open"`
+	Close string   `json:"close"`
+}
+
+func (p Period) Validate() error {
+	for _, day := range p.Days {
+		if !clock.ValidDay(day) {
+			return fmt.Errorf("unknown day %q", day)
+		}
+	}
+	if _, err := clock.Parse(p.Open); err != nil {
+		return fmt.Errorf("open: %v", err)
+	}
+	if _, err := clock.Parse(p.Close); err != nil {
+		return fmt.Errorf("close: %v", err)
+	}
+
+	return nil
+}
+
+// Week is the opening hours of a parking lot, a parking lot without periods is
+// always open.
+type Week []Period
+
+func (w Week) Validate() error {
+	for i, p := range w {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("period %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Open reports whether the local time t is inside one of the periods.
+func (w Week) Open(t time.Time) bool {
+	if len(w) == 0 {
+		return true
+	}
+	for _, p := range w {
+		window, _ := clock.NewWindow(p.Days, p.Open, p.Close)
+		if ok, _ := window.Contains(t); ok {
+			return true
+		}
+	}
+
+	return false
+}
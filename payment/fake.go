@@ -0,0 +1,155 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// DeclinedToken is a token the fake gateway always declines.
+	DeclinedToken = "tok_declined"
+	// CaptureFailsToken is a token the fake gateway authorises but fails to
+	// capture.
+	CaptureFailsToken = "tok_capture_fails"
+)
+
+var (
+	errUnknownReference = errors.New("unknown payment reference")
+	errAmount           = errors.New("amount exceeds the payment")
+	errCapture          = errors.New("capture failed")
+	errVoided           = errors.New("payment is voided")
+)
+
+// Fake is a gateway that keeps payments in memory, it authorises every token
+// except DeclinedToken and returns the references fake_1, fake_2 and so on.
+type Fake struct {
+	mu       sync.Mutex
+	payments []*fakePayment
+	keys     map[string]string // idempotency key to reference
+	refunds  map[string]bool   // idempotency keys of refunds
+}
+
+type fakePayment struct {
+	token                          string
+	authorised, captured, refunded int
+	voided                         bool
+}
+
+func NewFake() *Fake {
+	return &Fake{keys: map[string]string{}, refunds: map[string]bool{}}
+}
+
+func (f *Fake) Authorise(ctx context.Context, token string, amount int, idempotencyKey string) (string, error) {
+	if token == "" || token == DeclinedToken {
+		return "", ErrDeclined
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if reference, ok := f.keys[idempotencyKey]; ok && idempotencyKey != "" {
+		return reference, nil
+	}
+
+	f.payments = append(f.payments, &fakePayment{token: token, authorised: amount})
+	reference := fmt.Sprintf("fake_%d", len(f.payments))
+	f.keys[idempotencyKey] = reference
+
+	return reference, nil
+}
+
+func (f *Fake) payment(reference string) (*fakePayment, error) {
+	var n int
+	if _, err := fmt.Sscanf(reference, "fake_%d", &n); err != nil || n <= 0 || n > len(f.payments) {
+		return nil, errUnknownReference
+	}
+
+	return f.payments[n-1], nil
+}
+
+func (f *Fake) Capture(ctx context.Context, reference string, amount int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.payment(reference)
+	if err != nil {
+		return err
+	}
+	if p.voided {
+		return errVoided
+	}
+	if p.token == CaptureFailsToken {
+		return errCapture
+	}
+	if p.captured+amount > p.authorised {
+		return errAmount
+	}
+
+	p.captured += amount
+
+	return nil
+}
+
+func (f *Fake) Refund(ctx context.Context, reference string, amount int, idempotencyKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.payment(reference)
+	if err != nil {
+		return err
+	}
+	if f.refunds[idempotencyKey] && idempotencyKey != "" {
+		return nil
+	}
+	if p.refunded+amount > p.captured {
+		return errAmount
+	}
+
+	p.refunded += amount
+	f.refunds[idempotencyKey] = true
+
+	return nil
+}
+
+func (f *Fake) Void(ctx context.Context, reference string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.payment(reference)
+	if err != nil {
+		return err
+	}
+
+	p.voided = true
+
+	return nil
+}
+
+// Refunded returns what was refunded of a payment.
+func (f *Fake) Refunded(reference string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.payment(reference)
+	if err != nil {
+		return 0
+	}
+
+	return p.refunded
+}
+
+// Held returns what a payment holds, authorised and neither captured nor
+// voided, tests check with it that no money is left on hold.
+func (f *Fake) Held(reference string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.payment(reference)
+	if err != nil || p.voided {
+		return 0
+	}
+
+	return p.authorised - p.captured
+}
@@ -0,0 +1,105 @@
+package payment
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFakeAuthoriseIdempotent(t *testing.T) {
+	f, ctx := NewFake(), context.Background()
+
+	first, err := f.Authorise(ctx, "tok_visa", 20, "payment-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := f.Authorise(ctx, "tok_visa", 20, "payment-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := f.Authorise(ctx, "tok_visa", 20, "payment-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again != first {
+		t.Errorf("authorising again with the same key = %s, want %s", again, first)
+	}
+	if other == first {
+		t.Errorf("authorising with another key = %s, want a new reference", other)
+	}
+	if held := f.Held(first); held != 20 {
+		t.Errorf("Held(%s) = %d, want 20", first, held)
+	}
+}
+
+func TestFakeDeclined(t *testing.T) {
+	if _, err := NewFake().Authorise(context.Background(), DeclinedToken, 20, "payment-1"); err != ErrDeclined {
+		t.Errorf("Authorise() error = %v, want %v", err, ErrDeclined)
+	}
+}
+
+func TestFakeCaptureAndVoid(t *testing.T) {
+	f, ctx := NewFake(), context.Background()
+
+	reference, err := f.Authorise(ctx, "tok_visa", 20, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Capture(ctx, reference, 30); err == nil {
+		t.Error("captured more than was authorised")
+	}
+	if err := f.Capture(ctx, reference, 15); err != nil {
+		t.Fatal(err)
+	}
+	if held := f.Held(reference); held != 5 {
+		t.Errorf("Held() after capturing 15 of 20 = %d, want 5", held)
+	}
+
+	if err := f.Void(ctx, reference); err != nil {
+		t.Fatal(err)
+	}
+	if held := f.Held(reference); held != 0 {
+		t.Errorf("Held() after the void = %d, want 0", held)
+	}
+	if err := f.Capture(ctx, reference, 5); err == nil {
+		t.Error("captured a voided payment")
+	}
+
+	failing, err := f.Authorise(ctx, CaptureFailsToken, 20, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Capture(ctx, failing, 20); err == nil {
+		t.Errorf("captured a payment authorised with %s", CaptureFailsToken)
+	}
+}
+
+func TestFakeRefundIdempotent(t *testing.T) {
+	f, ctx := NewFake(), context.Background()
+
+	reference, err := f.Authorise(ctx, "tok_visa", 20, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Capture(ctx, reference, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := f.Refund(ctx, reference, 15, "adjustment-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if refunded := f.Refunded(reference); refunded != 15 {
+		t.Errorf("refunded %d with one idempotency key, want 15", refunded)
+	}
+	if err := f.Refund(ctx, reference, 10, "adjustment-2"); err == nil {
+		t.Error("refunded more than was captured")
+	}
+	if err := f.Refund(ctx, reference, 5, "adjustment-2"); err != nil {
+		t.Fatal(err)
+	}
+	if refunded := f.Refunded(reference); refunded != 20 {
+		t.Errorf("refunded %d, want 20", refunded)
+	}
+}
@@ -0,0 +1,42 @@
+// Package payment settles the fees of parking space reservations through a
+// payment gateway.
+package payment
+
+import (
+	"context"
+	"errors"
+)
+
+// Status of a payment, a payment is created pending, is processing while it is
+// sent to the gateway, authorised by the gateway and then captured. A payment
+// the gateway refuses has failed.
+type Status string
+
+const (
+	Pending    Status = "pending"
+	Processing Status = "processing"
+	Authorised Status = "authorised"
+	Captured   Status = "captured"
+	Failed     Status = "failed"
+	Refunded   Status = "refunded"
+)
+
+// ErrDeclined is returned by a gateway that refuses to authorise a payment.
+var ErrDeclined = errors.New("payment declined")
+
+// Gateway moves the money of payments. Amounts are in the same unit as fees,
+// the reference identifies an authorised payment at the gateway.
+type Gateway interface {
+	// Authorise reserves amount on the payment method identified by token.
+	// Authorising again with the same idempotency key returns the reference of
+	// the first authorisation and reserves nothing more.
+	Authorise(ctx context.Context, token string, amount int, idempotencyKey string) (reference string, err error)
+	// Capture charges amount of an authorised payment.
+	Capture(ctx context.Context, reference string, amount int) error
+	// Void releases what an authorised payment holds and was not captured, a
+	// voided payment can no longer be captured.
+	Void(ctx context.Context, reference string) error
+	// Refund returns amount of a captured payment. Refunding again with the
+	// same idempotency key returns nothing more.
+	Refund(ctx context.Context, reference string, amount int, idempotencyKey string) error
+}
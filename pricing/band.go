@@ -0,0 +1,81 @@
+package pricing
+
+import (
+	"fmt"
+
+	"github.com/arifmahmudrana/parking-lot/clock"
+)
+
+// Kinds of tariff bands.
+const (
+	// BandRate replaces the plan's rates with Amount per hour.
+	BandRate = "rate"
+	// BandFlat charges Amount once for every occurrence of the band.
+	BandFlat = "flat"
+	// BandSurcharge adds Amount per hour on top of whatever else applies.
+	BandSurcharge = "surcharge"
+)
+
+// Band is a tariff that applies during a window of local time, e.g. a night
+// rate from 22:00 to 06:00 or a weekend flat rate. Start and End are "HH:MM",
+// a window with End before Start ends on the next day and one with End equal to
+// Start lasts the whole day. Days are the weekdays ("mon", "tue", ...) the
+// window starts on, no days means every day.
+type Band struct {
+	Name   string   `json:"name"`
+	Kind   string   `json:"kind"`
+	Days   []string `json:"days"`
+	Start  string   `json:"start"`
+	End    string   `json:"end"`
+	Amount int      `json:"amount"`
+}
+
+func (b Band) Validate() error {
+	if b.Name == "" {
+		return fmt.Errorf("%w: band name is required", ErrInvalidRatePlan)
+	}
+	if b.Kind != BandRate && b.Kind != BandFlat && b.Kind != BandSurcharge {
+		return fmt.Errorf("%w: band %s: kind must be %s, %s or %s", ErrInvalidRatePlan, b.Name, BandRate, BandFlat, BandSurcharge)
+	}
+	if b.Amount < 0 {
+		return fmt.Errorf("%w: band %s: amount must not be negative", ErrInvalidRatePlan, b.Name)
+	}
+	for _, day := range b.Days {
+		if !clock.ValidDay(day) {
+			return fmt.Errorf("%w: band %s: unknown day %q", ErrInvalidRatePlan, b.Name, day)
+		}
+	}
+	if _, err := clock.Parse(b.Start); err != nil {
+		return fmt.Errorf("%w: band %s: start: %v", ErrInvalidRatePlan, b.Name, err)
+	}
+	if _, err := clock.Parse(b.End); err != nil {
+		return fmt.Errorf("%w: band %s: end: %v", ErrInvalidRatePlan, b.Name, err)
+	}
+
+	return nil
+}
+
+// window returns the window of local time of a valid band.
+func (b Band) window() clock.Window {
+	w, _ := clock.NewWindow(b.Days, b.Start, b.End)
+	return w
+}
+
+// boundaries returns the sorted minutes of the day at which one of windows may
+// start or end, midnight included as the day of the week changes there.
+func boundaries(windows []clock.Window) []int {
+	var at [minutesPerDay]bool
+	at[0] = true
+	for _, w := range windows {
+		at[w.Start], at[w.End] = true, true
+	}
+
+	var result []int
+	for minute, ok := range at {
+		if ok {
+			result = append(result, minute)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,334 @@
+// Package pricing calculates parking fees from a parking lot's rate plan.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arifmahmudrana/parking-lot/clock"
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+const minutesPerDay = 24 * 60
+
+var ErrInvalidRatePlan = errors.New("invalid rate plan")
+
+// RatePlan describes how a parking lot charges for a stay. All durations are in
+// minutes and all amounts in the smallest currency unit.
+type RatePlan struct {
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	FirstHourRate    int    `json:"first_hour_rate"`
+	HourlyRate       int    `json:"hourly_rate"`
+	BillingIncrement int    `json:"billing_increment"`
+	GracePeriod      int    `json:"grace_period"`
+	DailyMax         int    `json:"daily_max"` // 0 means no cap
+	FreeMinutes      int    `json:"free_minutes"`
+	Bands            []Band `json:"bands"`
+
+	// VehicleRates scales the fee of a vehicle type in percent, types that are
+	// not listed pay 100%.
+	VehicleRates map[vehicle.Type]int `json:"vehicle_rates"`
+}
+
+// Stay is what gets priced, Location is the parking lot's time zone.
+type Stay struct {
+	Start, End  time.Time
+	Location    *time.Location
+	VehicleType vehicle.Type
+}
+
+// DefaultRatePlan is applied to parking lots without a configured rate plan, it
+// charges 10 for every started hour.
+var DefaultRatePlan = RatePlan{
+	Name:             "default",
+	FirstHourRate:    10,
+	HourlyRate:       10,
+	BillingIncrement: 60,
+}
+
+// LineItem is a single entry of a fee breakdown, Amount is negative for
+// discounts such as the daily maximum cap.
+type LineItem struct {
+	Description string `json:"description"`
+	Minutes     int    `json:"minutes"`
+	Amount      int    `json:"amount"`
+}
+
+// Fee is the result of pricing a stay.
+type Fee struct {
+	RatePlan RatePlan   `json:"rate_plan"`
+	Items    []LineItem `json:"items"`
+	Total    int        `json:"total"`
+}
+
+func (p RatePlan) Validate() error {
+	switch {
+	case p.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidRatePlan)
+	case p.FirstHourRate < 0 || p.HourlyRate < 0 || p.DailyMax < 0:
+		return fmt.Errorf("%w: rates must not be negative", ErrInvalidRatePlan)
+	case p.BillingIncrement < 1 || p.BillingIncrement > minutesPerDay:
+		return fmt.Errorf("%w: billing increment must be between 1 and %d minutes", ErrInvalidRatePlan, minutesPerDay)
+	case p.GracePeriod < 0 || p.FreeMinutes < 0:
+		return fmt.Errorf("%w: grace period and free minutes must not be negative", ErrInvalidRatePlan)
+	}
+
+	for _, b := range p.Bands {
+		if err := b.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for t, percent := range p.VehicleRates {
+		if !t.Valid() {
+			return fmt.Errorf("%w: unknown vehicle type %q", ErrInvalidRatePlan, t)
+		}
+		if percent < 0 {
+			return fmt.Errorf("%w: vehicle rate of %s must not be negative", ErrInvalidRatePlan, t)
+		}
+	}
+
+	return nil
+}
+
+// Calculate prices a stay, tariff bands are evaluated in the stay's location.
+//
+// A stay no longer than the grace period is free. Otherwise the free minutes are
+// deducted from the start of the stay and the rest is rounded up to the billing
+// increment. Billed time is split into segments by the bands it crosses, time
+// outside of any rate or flat band is charged at the first hour rate for its
+// first hour and the hourly rate after that. The daily maximum caps the charge
+// of every started 24 hours of billed time. Finally the vehicle rate of the
+// stay's vehicle type is applied to the total.
+func (p RatePlan) Calculate(s Stay) Fee {
+	fee := Fee{
+		RatePlan: p,
+		Items:    []LineItem{},
+	}
+	start, loc := s.Start, s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	stay := s.End.Sub(start)
+	if stay <= 0 {
+		return fee
+	}
+
+	stayMinutes := ceilDiv(int(stay/time.Second), 60)
+	if stay <= time.Duration(p.GracePeriod)*time.Minute {
+		fee.Items = append(fee.Items, LineItem{
+			Description: "grace period",
+			Minutes:     stayMinutes,
+		})
+		return fee
+	}
+
+	billable := stay - time.Duration(p.FreeMinutes)*time.Minute
+	if p.FreeMinutes > 0 {
+		free := p.FreeMinutes
+		if billable < 0 {
+			free = stayMinutes
+		}
+		fee.Items = append(fee.Items, LineItem{
+			Description: "free minutes",
+			Minutes:     free,
+		})
+	}
+	if billable <= 0 {
+		return fee
+	}
+
+	increment := time.Duration(p.BillingIncrement) * time.Minute
+	billed := int((billable+increment-1)/increment) * p.BillingIncrement
+	billedFrom := start.Add(time.Duration(p.FreeMinutes) * time.Minute)
+
+	windows := make([]clock.Window, len(p.Bands))
+	for i, b := range p.Bands {
+		windows[i] = b.window()
+	}
+	primaryBand := func(t time.Time) (int, time.Time) {
+		for i, b := range p.Bands {
+			if b.Kind == BandSurcharge {
+				continue
+			}
+			if ok, occurrence := windows[i].Contains(t); ok {
+				return i, occurrence
+			}
+		}
+
+		return -1, time.Time{}
+	}
+
+	var (
+		days      = ceilDiv(billed, minutesPerDay)
+		baseUsed  = 0
+		flatsUsed = map[string]bool{}
+		splits    = boundaries(windows)
+	)
+	for day := 1; day <= days; day++ {
+		minutes := billed - (day-1)*minutesPerDay
+		if minutes > minutesPerDay {
+			minutes = minutesPerDay
+		}
+		from := billedFrom.Add(time.Duration(day-1) * 24 * time.Hour).In(loc)
+
+		suffix := ""
+		if days > 1 {
+			suffix = fmt.Sprintf(" (day %d)", day)
+		}
+
+		var items []LineItem
+		for _, seg := range segments(from, minutes, splits, primaryBand) {
+			if seg.band < 0 {
+				if baseUsed < 60 {
+					first := 60 - baseUsed
+					if first > seg.minutes {
+						first = seg.minutes
+					}
+					items = append(items, LineItem{
+						Description: "first hour" + suffix,
+						Minutes:     first,
+						Amount:      ceilDiv(p.FirstHourRate*first, 60),
+					})
+					baseUsed += first
+					seg.minutes -= first
+				}
+				if seg.minutes > 0 {
+					items = append(items, LineItem{
+						Description: "hourly" + suffix,
+						Minutes:     seg.minutes,
+						Amount:      ceilDiv(p.HourlyRate*seg.minutes, 60),
+					})
+					baseUsed += seg.minutes
+				}
+				continue
+			}
+
+			b := p.Bands[seg.band]
+			item := LineItem{
+				Description: fmt.Sprintf("%s %s-%s", b.Name, b.Start, b.End) + suffix,
+				Minutes:     seg.minutes,
+			}
+			if b.Kind == BandRate {
+				item.Amount = ceilDiv(b.Amount*seg.minutes, 60)
+			} else if key := fmt.Sprintf("%d/%s", seg.band, seg.occurrence.Format("2006-01-02")); !flatsUsed[key] {
+				// a flat band crossing into the next day is charged once
+				item.Amount = b.Amount
+				flatsUsed[key] = true
+			}
+			items = append(items, item)
+		}
+
+		for i, b := range p.Bands {
+			if b.Kind != BandSurcharge {
+				continue
+			}
+
+			i, w := i, windows[i]
+			inBand := func(t time.Time) (int, time.Time) {
+				if ok, occurrence := w.Contains(t); ok {
+					return i, occurrence
+				}
+				return -1, time.Time{}
+			}
+			for _, seg := range segments(from, minutes, splits, inBand) {
+				if seg.band < 0 {
+					continue
+				}
+				items = append(items, LineItem{
+					Description: fmt.Sprintf("%s surcharge %s-%s", b.Name, b.Start, b.End) + suffix,
+					Minutes:     seg.minutes,
+					Amount:      ceilDiv(b.Amount*seg.minutes, 60),
+				})
+			}
+		}
+
+		dayTotal := 0
+		for _, item := range items {
+			dayTotal += item.Amount
+		}
+		if p.DailyMax > 0 && dayTotal > p.DailyMax {
+			items = append(items, LineItem{
+				Description: "daily maximum" + suffix,
+				Amount:      p.DailyMax - dayTotal,
+			})
+			dayTotal = p.DailyMax
+		}
+
+		fee.Items = append(fee.Items, items...)
+		fee.Total += dayTotal
+	}
+
+	if percent, ok := p.VehicleRates[s.VehicleType]; ok && percent != 100 {
+		adjusted := ceilDiv(fee.Total*percent, 100)
+		fee.Items = append(fee.Items, LineItem{
+			Description: fmt.Sprintf("%s rate %d%%", s.VehicleType, percent),
+			Amount:      adjusted - fee.Total,
+		})
+		fee.Total = adjusted
+	}
+
+	return fee
+}
+
+// segment is a run of consecutive billed minutes in the same band occurrence.
+type segment struct {
+	band       int
+	occurrence time.Time
+	minutes    int
+}
+
+// segments groups minutes from from by the band classify puts them in. The
+// band can only change at one of the splits, the minutes of the day bands start
+// or end, so runs of minutes up to the next split are classified at once. A run
+// that is not uniform, as when a daylight saving change moves the clock past a
+// split, is walked one minute at a time.
+func segments(from time.Time, minutes int, splits []int, classify func(t time.Time) (int, time.Time)) []segment {
+	var result []segment
+	for i := 0; i < minutes; {
+		t := from.Add(time.Duration(i) * time.Minute)
+		band, occurrence := classify(t)
+
+		n := int((nextSplit(t, splits).Sub(t) + time.Minute - 1) / time.Minute)
+		if n > minutes-i {
+			n = minutes - i
+		}
+		if n > 1 {
+			if last, lastOccurrence := classify(t.Add(time.Duration(n-1) * time.Minute)); last != band || !lastOccurrence.Equal(occurrence) {
+				n = 1
+			}
+		}
+		if n < 1 {
+			n = 1
+		}
+
+		if k := len(result); k > 0 && result[k-1].band == band && result[k-1].occurrence.Equal(occurrence) {
+			result[k-1].minutes += n
+		} else {
+			result = append(result, segment{band: band, occurrence: occurrence, minutes: n})
+		}
+		i += n
+	}
+
+	return result
+}
+
+// nextSplit returns the first of splits after the local time t, midnight of the
+// next day when there is none left today.
+func nextSplit(t time.Time, splits []int) time.Time {
+	minute := t.Hour()*60 + t.Minute()
+	for _, split := range splits {
+		if split > minute {
+			return time.Date(t.Year(), t.Month(), t.Day(), split/60, split%60, 0, 0, t.Location())
+		}
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
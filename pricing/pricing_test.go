@@ -0,0 +1,311 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata" // the tests must not depend on the time zones of the host
+
+	"github.com/arifmahmudrana/parking-lot/vehicle"
+)
+
+var start = time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC) // a Monday
+
+func stay(d time.Duration) Stay {
+	return Stay{Start: start, End: start.Add(d)}
+}
+
+func TestCalculate(t *testing.T) {
+	plan := RatePlan{
+		Name:             "test",
+		FirstHourRate:    300,
+		HourlyRate:       200,
+		BillingIncrement: 15,
+		GracePeriod:      10,
+		DailyMax:         2000,
+	}
+	free := RatePlan{
+		Name:             "free",
+		FirstHourRate:    100,
+		HourlyRate:       100,
+		BillingIncrement: 60,
+		FreeMinutes:      30,
+	}
+
+	tests := []struct {
+		name string
+		plan RatePlan
+		stay Stay
+		want int
+	}{
+		{"no stay", plan, stay(0), 0},
+		{"ended before it started", plan, stay(-time.Hour), 0},
+		{"within the grace period", plan, stay(10 * time.Minute), 0},
+		// 11 minutes are billed as the increment of 15 minutes of the first hour
+		{"after the grace period", plan, stay(11 * time.Minute), 75},
+		{"a started second is a started minute", plan, stay(15*time.Minute + time.Second), 150},
+		{"first hour", plan, stay(time.Hour), 300},
+		// 1:01 is billed as 1:15
+		{"first hour and an increment", plan, stay(time.Hour + time.Minute), 350},
+		{"below the daily maximum", plan, stay(9 * time.Hour), 1900},
+		{"daily maximum", plan, stay(10 * time.Hour), 2000},
+		// the second day starts with an hour at the hourly rate
+		{"daily maximum of every 24 hours", plan, stay(25 * time.Hour), 2200},
+		{"two daily maximums", plan, stay(48 * time.Hour), 4000},
+		{"default", DefaultRatePlan, stay(90 * time.Minute), 20},
+		{"within the free minutes", free, stay(30 * time.Minute), 0},
+		// the minute after the free minutes is billed as an hour
+		{"after the free minutes", free, stay(31 * time.Minute), 100},
+		{"free minutes are deducted", free, stay(90 * time.Minute), 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := tt.plan.Calculate(tt.stay)
+			if fee.Total != tt.want {
+				t.Errorf("Calculate().Total = %d, want %d, items %+v", fee.Total, tt.want, fee.Items)
+			}
+
+			sum := 0
+			for _, item := range fee.Items {
+				sum += item.Amount
+			}
+			if sum != fee.Total {
+				t.Errorf("items add up to %d, total is %d, items %+v", sum, fee.Total, fee.Items)
+			}
+		})
+	}
+}
+
+func TestCalculateItems(t *testing.T) {
+	plan := RatePlan{
+		Name:             "test",
+		FirstHourRate:    300,
+		HourlyRate:       200,
+		BillingIncrement: 15,
+		GracePeriod:      10,
+		DailyMax:         1000,
+	}
+
+	tests := []struct {
+		name string
+		stay Stay
+		want []LineItem
+	}{
+		{"grace period", stay(5 * time.Minute), []LineItem{
+			{Description: "grace period", Minutes: 5},
+		}},
+		{"rounded up", stay(80 * time.Minute), []LineItem{
+			{Description: "first hour", Minutes: 60, Amount: 300},
+			{Description: "hourly", Minutes: 30, Amount: 100},
+		}},
+		{"capped", stay(5 * time.Hour), []LineItem{
+			{Description: "first hour", Minutes: 60, Amount: 300},
+			{Description: "hourly", Minutes: 240, Amount: 800},
+			{Description: "daily maximum", Amount: -100},
+		}},
+		{"capped days", stay(25 * time.Hour), []LineItem{
+			{Description: "first hour (day 1)", Minutes: 60, Amount: 300},
+			{Description: "hourly (day 1)", Minutes: 1380, Amount: 4600},
+			{Description: "daily maximum (day 1)", Amount: -3900},
+			{Description: "hourly (day 2)", Minutes: 60, Amount: 200},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertItems(t, plan.Calculate(tt.stay).Items, tt.want)
+		})
+	}
+}
+
+func assertItems(t *testing.T, got, want []LineItem) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("items = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// at returns a stay from the local time from to the local time to in loc,
+// times are "2006-01-02 15:04".
+func at(t *testing.T, loc *time.Location, from, to string) Stay {
+	t.Helper()
+
+	start, err := time.ParseInLocation("2006-01-02 15:04", from, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, err := time.ParseInLocation("2006-01-02 15:04", to, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Stay{Start: start, End: end, Location: loc}
+}
+
+func TestCalculateBands(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := RatePlan{
+		Name:             "bands",
+		FirstHourRate:    200,
+		HourlyRate:       200,
+		BillingIncrement: 15,
+	}
+	withBands := func(bands ...Band) RatePlan {
+		p := base
+		p.Bands = bands
+		return p
+	}
+	night := Band{Name: "night", Kind: BandRate, Start: "22:00", End: "06:00", Amount: 100}
+	fridayNight := Band{Name: "night", Kind: BandRate, Days: []string{"fri"}, Start: "22:00", End: "06:00", Amount: 100}
+	weekend := Band{Name: "weekend", Kind: BandFlat, Days: []string{"sat", "sun"}, Start: "00:00", End: "00:00", Amount: 1500}
+	evening := Band{Name: "event", Kind: BandSurcharge, Start: "18:00", End: "20:00", Amount: 50}
+	earlyMorning := Band{Name: "night", Kind: BandRate, Start: "22:00", End: "04:00", Amount: 100}
+
+	capped := withBands(night)
+	capped.DailyMax = 1000
+	motorcycles := base
+	motorcycles.VehicleRates = map[vehicle.Type]int{vehicle.Motorcycle: 50, vehicle.Car: 100}
+
+	tests := []struct {
+		name string
+		plan RatePlan
+		stay Stay
+		want int
+	}{
+		// 2024-03-04 is a Monday, 2024-03-08 a Friday
+		// 20:00-22:00 first hour and hourly 400, 22:00-06:00 night 800,
+		// 06:00-08:00 hourly 400
+		{"night band crossing midnight", withBands(night), at(t, time.UTC, "2024-03-04 20:00", "2024-03-05 08:00"), 1600},
+		{"after midnight in the night band", withBands(night), at(t, time.UTC, "2024-03-05 01:00", "2024-03-05 03:00"), 200},
+		{"within the night band", withBands(night), at(t, time.UTC, "2024-03-04 22:30", "2024-03-04 23:00"), 50},
+		// the band starts on Fridays, Saturday morning is part of it
+		{"night band of a day", withBands(fridayNight), at(t, time.UTC, "2024-03-09 02:00", "2024-03-09 03:00"), 100},
+		{"night band of another day", withBands(fridayNight), at(t, time.UTC, "2024-03-09 23:00", "2024-03-10 00:00"), 200},
+		{"flat band", withBands(weekend), at(t, time.UTC, "2024-03-09 10:00", "2024-03-09 14:00"), 1500},
+		{"flat band of every day", withBands(weekend), at(t, time.UTC, "2024-03-09 20:00", "2024-03-10 02:00"), 3000},
+		// Friday 22:00-24:00 first hour and hourly 400, Saturday flat 1500
+		{"into a flat band", withBands(weekend), at(t, time.UTC, "2024-03-08 22:00", "2024-03-09 02:00"), 1900},
+		// 17:00-21:00 first hour and hourly 800, 18:00-20:00 surcharge 100
+		{"surcharge", withBands(evening), at(t, time.UTC, "2024-03-04 17:00", "2024-03-04 21:00"), 900},
+		{"surcharge on a band", withBands(night, evening), at(t, time.UTC, "2024-03-04 19:00", "2024-03-04 23:00"), 750},
+		{"capped night band", capped, at(t, time.UTC, "2024-03-04 20:00", "2024-03-05 08:00"), 1000},
+		{"vehicle rate", motorcycles, Stay{Start: start, End: start.Add(2 * time.Hour), VehicleType: vehicle.Motorcycle}, 200},
+		{"full vehicle rate", motorcycles, Stay{Start: start, End: start.Add(2 * time.Hour), VehicleType: vehicle.Car}, 400},
+		{"vehicle without a rate", motorcycles, Stay{Start: start, End: start.Add(2 * time.Hour), VehicleType: vehicle.Bus}, 400},
+		// the clocks skip 02:00-03:00 on 2024-03-31 in Berlin, 00:00-04:00 is 3
+		// hours of night band 300 and 04:00-06:00 first hour and hourly 400
+		{"daylight saving starts", withBands(earlyMorning), at(t, berlin, "2024-03-31 00:00", "2024-03-31 06:00"), 700},
+		// 02:00-03:00 is repeated on 2024-10-27, 00:00-04:00 is 5 hours
+		{"daylight saving ends", withBands(earlyMorning), at(t, berlin, "2024-10-27 00:00", "2024-10-27 06:00"), 900},
+		{"daylight saving without bands", base, at(t, berlin, "2024-03-31 00:00", "2024-03-31 06:00"), 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.plan.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			fee := tt.plan.Calculate(tt.stay)
+			if fee.Total != tt.want {
+				t.Errorf("Calculate().Total = %d, want %d, items %+v", fee.Total, tt.want, fee.Items)
+			}
+
+			sum := 0
+			for _, item := range fee.Items {
+				sum += item.Amount
+			}
+			if sum != fee.Total {
+				t.Errorf("items add up to %d, total is %d, items %+v", sum, fee.Total, fee.Items)
+			}
+		})
+	}
+}
+
+func TestCalculateBandItems(t *testing.T) {
+	plan := RatePlan{
+		Name:             "bands",
+		FirstHourRate:    200,
+		HourlyRate:       200,
+		BillingIncrement: 15,
+		Bands: []Band{
+			{Name: "night", Kind: BandRate, Start: "22:00", End: "06:00", Amount: 100},
+			{Name: "weekend", Kind: BandFlat, Days: []string{"sat", "sun"}, Start: "00:00", End: "00:00", Amount: 1500},
+		},
+		VehicleRates: map[vehicle.Type]int{vehicle.Motorcycle: 50},
+	}
+
+	// the night band comes first, Friday night is charged at it into Saturday
+	s := at(t, time.UTC, "2024-03-08 21:00", "2024-03-09 07:00")
+	s.VehicleType = vehicle.Motorcycle
+	assertItems(t, plan.Calculate(s).Items, []LineItem{
+		{Description: "first hour", Minutes: 60, Amount: 200},
+		{Description: "night 22:00-06:00", Minutes: 480, Amount: 800},
+		{Description: "weekend 00:00-00:00", Minutes: 60, Amount: 1500},
+		{Description: "motorcycle rate 50%", Amount: -1250},
+	})
+}
+
+func TestValidateBands(t *testing.T) {
+	tests := []struct {
+		name string
+		band Band
+	}{
+		{"no name", Band{Kind: BandRate, Start: "22:00", End: "06:00"}},
+		{"unknown kind", Band{Name: "night", Kind: "hourly", Start: "22:00", End: "06:00"}},
+		{"negative amount", Band{Name: "night", Kind: BandRate, Start: "22:00", End: "06:00", Amount: -1}},
+		{"unknown day", Band{Name: "night", Kind: BandRate, Days: []string{"monday"}, Start: "22:00", End: "06:00"}},
+		{"invalid start", Band{Name: "night", Kind: BandRate, Start: "24:00", End: "06:00"}},
+		{"invalid end", Band{Name: "night", Kind: BandRate, Start: "22:00", End: "6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := RatePlan{Name: "bands", BillingIncrement: 60, Bands: []Band{tt.band}}
+			if err := p.Validate(); !errors.Is(err, ErrInvalidRatePlan) {
+				t.Errorf("Validate() = %v, want %v", err, ErrInvalidRatePlan)
+			}
+		})
+	}
+
+	p := RatePlan{Name: "rates", BillingIncrement: 60, VehicleRates: map[vehicle.Type]int{"tractor": 50}}
+	if err := p.Validate(); !errors.Is(err, ErrInvalidRatePlan) {
+		t.Errorf("Validate() with an unknown vehicle type = %v, want %v", err, ErrInvalidRatePlan)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := RatePlan{Name: "valid", FirstHourRate: 10, HourlyRate: 10, BillingIncrement: 60}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(p *RatePlan)
+	}{
+		{"no name", func(p *RatePlan) { p.Name = "" }},
+		{"negative rate", func(p *RatePlan) { p.HourlyRate = -1 }},
+		{"negative daily maximum", func(p *RatePlan) { p.DailyMax = -1 }},
+		{"no billing increment", func(p *RatePlan) { p.BillingIncrement = 0 }},
+		{"billing increment above a day", func(p *RatePlan) { p.BillingIncrement = minutesPerDay + 1 }},
+		{"negative grace period", func(p *RatePlan) { p.GracePeriod = -1 }},
+		{"negative free minutes", func(p *RatePlan) { p.FreeMinutes = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.change(&p)
+			if err := p.Validate(); !errors.Is(err, ErrInvalidRatePlan) {
+				t.Errorf("Validate() = %v, want %v", err, ErrInvalidRatePlan)
+			}
+		})
+	}
+}
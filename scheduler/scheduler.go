@@ -0,0 +1,86 @@
+// Package scheduler runs periodic jobs in the background of the server.
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Job is run once when the scheduler starts and then every Interval, runs of
+// the same job never overlap.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Run does the work and returns the number of records it changed.
+	Run func() (int64, error)
+}
+
+// Run is the result of running a job once.
+type Run struct {
+	Job        string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Affected   int64
+	Err        error
+}
+
+type Scheduler struct {
+	jobs   []Job
+	record func(Run)
+	wg     sync.WaitGroup
+}
+
+// New returns a scheduler that passes every run to record.
+func New(record func(Run)) *Scheduler {
+	return &Scheduler{
+		record: record,
+	}
+}
+
+// Add adds a job, it must be called before Start.
+func (s *Scheduler) Add(j Job) {
+	s.jobs = append(s.jobs, j)
+}
+
+// Start runs the jobs in the background until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func(j Job) {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+}
+
+// Wait blocks until the running jobs finished after ctx of Start is done.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j Job) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) run(j Job) {
+	r := Run{
+		Job:       j.Name,
+		StartedAt: time.Now(),
+	}
+	r.Affected, r.Err = j.Run()
+	r.FinishedAt = time.Now()
+
+	s.record(r)
+}
@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder keeps the runs a scheduler records.
+type recorder struct {
+	mu   sync.Mutex
+	runs []Run
+}
+
+func (r *recorder) record(run Run) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs = append(r.runs, run)
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.runs)
+}
+
+// waitFor waits until the recorder has n runs.
+func (r *recorder) waitFor(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.count() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("recorded %d runs, want at least %d", r.count(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	var (
+		r      recorder
+		s      = New(r.record)
+		errJob = errors.New("job failed")
+	)
+	s.Add(Job{
+		Name:     "every hour",
+		Interval: time.Hour,
+		Run:      func() (int64, error) { return 2, nil },
+	})
+	s.Add(Job{
+		Name:     "failing",
+		Interval: time.Hour,
+		Run:      func() (int64, error) { return 0, errJob },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	// both jobs run once when the scheduler starts
+	r.waitFor(t, 2)
+	cancel()
+	s.Wait()
+
+	byJob := map[string]Run{}
+	for _, run := range r.runs {
+		byJob[run.Job] = run
+	}
+	if run := byJob["every hour"]; run.Affected != 2 || run.Err != nil || run.FinishedAt.Before(run.StartedAt) {
+		t.Errorf("run of every hour = %+v, want 2 affected", run)
+	}
+	if run := byJob["failing"]; run.Err != errJob {
+		t.Errorf("run of failing = %+v, want error %v", run, errJob)
+	}
+}
+
+func TestSchedulerInterval(t *testing.T) {
+	var (
+		r recorder
+		s = New(r.record)
+
+		mu                sync.Mutex
+		running, overlaps int
+	)
+	s.Add(Job{
+		Name:     "slow",
+		Interval: time.Millisecond,
+		Run: func() (int64, error) {
+			mu.Lock()
+			running++
+			if running > 1 {
+				overlaps++
+			}
+			mu.Unlock()
+
+			// slower than the interval
+			time.Sleep(3 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return 0, nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	r.waitFor(t, 5)
+	cancel()
+	s.Wait()
+
+	if overlaps > 0 {
+		t.Errorf("runs overlapped %d times", overlaps)
+	}
+
+	// no run starts once Wait returned
+	stopped := r.count()
+	time.Sleep(10 * time.Millisecond)
+	if n := r.count(); n != stopped {
+		t.Errorf("recorded %d runs after the scheduler stopped", n-stopped)
+	}
+}
+
+func TestSchedulerWaitsForRunningJobs(t *testing.T) {
+	var (
+		r        recorder
+		s        = New(r.record)
+		started  = make(chan struct{})
+		finished = make(chan struct{})
+	)
+	s.Add(Job{
+		Name:     "long",
+		Interval: time.Hour,
+		Run: func() (int64, error) {
+			close(started)
+			<-finished
+			return 1, nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	<-started
+	cancel()
+
+	waited := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("Wait returned while a job was running")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(finished)
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after the job finished")
+	}
+	if r.count() != 1 {
+		t.Errorf("recorded %d runs, want the run that was running", r.count())
+	}
+}
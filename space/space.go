@@ -0,0 +1,112 @@
+// Package space defines the features a parking space can be tagged with.
+package space
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+)
+
+// Features is a set of features stored as a bit mask, every constant below is a
+// set of a single feature.
+type Features uint8
+
+const (
+	EVCharger Features = 1 << iota
+	Accessible
+	Family
+	StaffOnly
+)
+
+var featureNames = []struct {
+	feature Features
+	name    string
+}{
+	{EVCharger, "ev_charger"},
+	{Accessible, "accessible"},
+	{Family, "family"},
+	{StaffOnly, "staff_only"},
+}
+
+// ParseFeatures parses a list of feature names such as ["ev_charger"].
+func ParseFeatures(names []string) (Features, error) {
+	var f Features
+	for _, name := range names {
+		found := false
+		for _, fn := range featureNames {
+			if fn.name == name {
+				f |= fn.feature
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown feature %q", name)
+		}
+	}
+
+	return f, nil
+}
+
+// Has reports whether f contains every feature of other.
+func (f Features) Has(other Features) bool {
+	return f&other == other
+}
+
+// Count is the number of features in f.
+func (f Features) Count() int {
+	return bits.OnesCount8(uint8(f))
+}
+
+func (f Features) Names() []string {
+	names := []string{}
+	for _, fn := range featureNames {
+		if f.Has(fn.feature) {
+			names = append(names, fn.name)
+		}
+	}
+
+	return names
+}
+
+func (f Features) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Names())
+}
+
+func (f *Features) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+
+	features, err := ParseFeatures(names)
+	if err != nil {
+		return err
+	}
+
+	*f = features
+	return nil
+}
+
+// Eligible reports whether a space with features f can be given to a request
+// requiring the features required. A request only gets spaces with features it
+// did not ask for when overflow is allowed, and never gets staff only spaces
+// unless it asked for them.
+func (f Features) Eligible(required Features, overflow bool) bool {
+	if !f.Has(required) {
+		return false
+	}
+
+	extra := f &^ required
+	if extra == 0 {
+		return true
+	}
+
+	return overflow && extra&StaffOnly == 0
+}
+
+// Extra is the number of features of f that were not required, allocation
+// prefers spaces with fewer of them.
+func (f Features) Extra(required Features) int {
+	return (f &^ required).Count()
+}
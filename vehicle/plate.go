@@ -0,0 +1,32 @@
+package vehicle
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPlate = errors.New("invalid license plate")
+
+const maxPlateLength = 16
+
+// NormalizePlate returns the plate in the form it is stored and looked up in,
+// upper case letters and digits only, so "abc-123" and "ABC 123" are the same
+// vehicle.
+func NormalizePlate(plate string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(plate) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ', r == '-', r == '.':
+		default:
+			return "", ErrInvalidPlate
+		}
+	}
+
+	if b.Len() < 2 || b.Len() > maxPlateLength {
+		return "", ErrInvalidPlate
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,101 @@
+// Package vehicle defines the vehicle types that can park and the size classes
+// of parking spaces they fit in.
+package vehicle
+
+import "fmt"
+
+type Type string
+
+const (
+	Motorcycle Type = "motorcycle"
+	Compact    Type = "compact"
+	Car        Type = "car"
+	Bus        Type = "bus"
+
+	// DefaultType is assumed when a park request does not name a vehicle type.
+	DefaultType = Car
+)
+
+// Types lists every vehicle type from the smallest to the largest.
+var Types = []Type{Motorcycle, Compact, Car, Bus}
+
+func (t Type) Valid() bool {
+	switch t {
+	case Motorcycle, Compact, Car, Bus:
+		return true
+	}
+
+	return false
+}
+
+// MinSizeClass is the smallest size class of parking space the vehicle fits in.
+func (t Type) MinSizeClass() SizeClass {
+	switch t {
+	case Motorcycle:
+		return SizeMotorcycle
+	case Compact:
+		return SizeCompact
+	case Bus:
+		return SizeLarge
+	}
+
+	return SizeRegular
+}
+
+// SizeClass of a parking space, a space fits every vehicle whose minimum size
+// class is the same or smaller.
+type SizeClass int8
+
+const (
+	SizeMotorcycle SizeClass = iota
+	SizeCompact
+	SizeRegular
+	SizeLarge
+)
+
+var sizeClassNames = []string{"motorcycle", "compact", "regular", "large"}
+
+func ParseSizeClass(s string) (SizeClass, error) {
+	for i, name := range sizeClassNames {
+		if name == s {
+			return SizeClass(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown size class %q", s)
+}
+
+func (s SizeClass) Valid() bool {
+	return s >= SizeMotorcycle && s <= SizeLarge
+}
+
+func (s SizeClass) String() string {
+	if !s.Valid() {
+		return fmt.Sprintf("SizeClass(%d)", int8(s))
+	}
+
+	return sizeClassNames[s]
+}
+
+// Fits reports whether a vehicle of type t can park in a space of size class s.
+func (s SizeClass) Fits(t Type) bool {
+	return s >= t.MinSizeClass()
+}
+
+func (s SizeClass) MarshalText() ([]byte, error) {
+	if !s.Valid() {
+		return nil, fmt.Errorf("invalid size class %d", int8(s))
+	}
+
+	return []byte(s.String()), nil
+}
+
+func (s *SizeClass) UnmarshalText(text []byte) error {
+	sc, err := ParseSizeClass(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = sc
+	return nil
+}